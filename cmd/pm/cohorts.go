@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+)
+
+const cohortMonthFormat = "2006-01"
+
+type CohortsCommand struct{}
+
+func (command *CohortsCommand) Execute(argv []string) error {
+	return PM.Run("cohorts", command.Report)
+}
+
+// Report renders the cohorts report from every issue, comment and review the
+// crawler has. Unlike the other reports it counts closed issues too, so a
+// user whose first issue has been closed since is still first seen when they
+// opened it.
+func (command *CohortsCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	events, err := GatherParticipation(ctx, logger, crawler, PM.GitHub.OrganizationNames)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return CohortReport(ctx, t, events)
+}
+
+// GatherParticipation returns every open and closed issue, every issue and
+// repository comment and every review of the organizations as activity
+// events.
+func GatherParticipation(ctx context.Context, logger lager.Logger, crawler gh.Crawler, orgs []string) ([]activity.Event, error) {
+	logger.Debug("gathering issues")
+	issues, err := crawler.IssuesUpdatedSinceForOrganizations(ctx, orgs, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("gathering comments")
+	issueComments, err := crawler.AllIssueCommentsForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryComments, err := crawler.AllRepositoryCommentsForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("gathering reviews")
+	reviews, err := crawler.AllReviewsForIssues(ctx, issues)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []activity.Event
+
+	for _, i := range issues {
+		events = append(events, gh.IssueOpenedEvent(i))
+	}
+
+	for _, c := range issueComments {
+		events = append(events, gh.IssueCommentEvent(c))
+	}
+
+	for _, c := range repositoryComments {
+		events = append(events, gh.RepositoryCommentEvent(c))
+	}
+
+	for _, r := range reviews {
+		events = append(events, gh.ReviewEvent(r))
+	}
+
+	return gh.Dated(events), nil
+}
+
+// CohortReport assigns every user to the month in which they were first seen
+// and renders a retention matrix: for each cohort, how many of its users were
+// active again N months later.
+func CohortReport(ctx context.Context, t tablewriter.TableWriter, events []activity.Event) error {
+	u := CatalogUsers(events)
+
+	cohorts := NewCohortList(u)
+
+	header := []string{"Cohort", "New Users"}
+	for offset := 0; offset <= cohorts.Span(); offset++ {
+		header = append(header, fmt.Sprintf("Month %d", offset))
+	}
+
+	t.SetHeader(header)
+
+	for _, c := range cohorts.Cohorts {
+		row := []string{c.Month.Format(cohortMonthFormat), fmt.Sprintf("%d", len(c.Users))}
+
+		for offset := 0; offset <= cohorts.Span(); offset++ {
+			if c.Month.AddDate(0, offset, 0).After(cohorts.Last) {
+				row = append(row, "")
+				continue
+			}
+
+			row = append(row, fmt.Sprintf("%d", c.ActiveIn(offset)))
+		}
+
+		t.Append(row)
+	}
+
+	return t.Render()
+}
+
+type CohortList struct {
+	Cohorts []*Cohort
+	First   time.Time
+	Last    time.Time
+}
+
+type Cohort struct {
+	Month time.Time
+	Users []*User
+
+	// active maps a month offset from the cohort month to the number of the
+	// cohort's users with any activity during that month.
+	active map[int]int
+}
+
+func NewCohortList(u UserList) *CohortList {
+	byMonth := map[time.Time]*Cohort{}
+	cohorts := &CohortList{}
+
+	for _, user := range u {
+		times := user.ActivityTimes()
+		if len(times) == 0 {
+			continue
+		}
+
+		months := map[time.Time]bool{}
+		for _, t := range times {
+			months[startOfMonth(t)] = true
+		}
+
+		var first time.Time
+		for m := range months {
+			if first.IsZero() || m.Before(first) {
+				first = m
+			}
+
+			if cohorts.First.IsZero() || m.Before(cohorts.First) {
+				cohorts.First = m
+			}
+
+			if m.After(cohorts.Last) {
+				cohorts.Last = m
+			}
+		}
+
+		cohort, exists := byMonth[first]
+		if !exists {
+			cohort = &Cohort{
+				Month:  first,
+				active: map[int]int{},
+			}
+			byMonth[first] = cohort
+			cohorts.Cohorts = append(cohorts.Cohorts, cohort)
+		}
+
+		cohort.Users = append(cohort.Users, user)
+		for m := range months {
+			cohort.active[monthsBetween(first, m)]++
+		}
+	}
+
+	sort.Slice(cohorts.Cohorts, func(i, j int) bool {
+		return cohorts.Cohorts[i].Month.Before(cohorts.Cohorts[j].Month)
+	})
+
+	return cohorts
+}
+
+// Span is the number of whole months between the earliest and latest activity.
+func (c *CohortList) Span() int {
+	if len(c.Cohorts) == 0 {
+		return 0
+	}
+
+	return monthsBetween(c.First, c.Last)
+}
+
+// ActiveIn returns how many of the cohort's users were active the given number
+// of months after the cohort month.
+func (c *Cohort) ActiveIn(offset int) int {
+	return c.active[offset]
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+}
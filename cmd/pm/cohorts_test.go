@@ -0,0 +1,52 @@
+package main_test
+
+import (
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GatherParticipation", func() {
+	var server *ghtest.Server
+
+	BeforeEach(func() {
+		server = ghtest.NewServer("../../lib/gh/ghtest/testdata/acme")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists each repository's issues once, reading the reviews of the pull requests among them", func() {
+		events, err := GatherParticipation(context.Background(), lagertest.NewTestLogger("test"), server.GitHubClient(), []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		listings := map[string]int{}
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request, "/issues?state=all") {
+				listings[request]++
+			}
+		}
+
+		Expect(listings).To(Equal(map[string]int{
+			"/repos/acme/widget/issues?state=all": 1,
+			"/repos/acme/gadget/issues?state=all": 1,
+		}))
+
+		var reviewers []string
+		for _, e := range events {
+			if e.Kind == activity.Review {
+				reviewers = append(reviewers, e.Actor+" "+e.Target)
+			}
+		}
+
+		Expect(reviewers).To(ConsistOf("bob #3", "alice #6", "bob #6"))
+	})
+})
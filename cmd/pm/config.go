@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultProfile = "default"
+
+// ConfigFile is the YAML file named by --config. Each profile maps option
+// names (without the leading dashes) to values. Nested maps are joined with
+// dashes, so a "github" map holding "token" sets --github-token, and a map
+// named after a command holds that command's options.
+//
+//	profiles:
+//	  monthly-community:
+//	    github:
+//	      organization-name: [cloudfoundry, cloudfoundry-incubator]
+//	      exclude-repository: ["*-ci"]
+//	    exclude-user: [ci-bot]
+//	    format: markdown
+//	    manifest:
+//	      weight: {opened-issues: 3, issue-comments: 1}
+type ConfigFile struct {
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// applyConfig loads the profile selected with --profile (or "default") from
+// the file named with --config and installs its values as option defaults.
+// Because go-flags only falls back to defaults when neither the environment
+// nor the command line sets an option, the precedence is flags, then PM_
+// environment variables, then the profile, then the built-in defaults.
+func applyConfig(parser *flags.Parser, args []string) error {
+	var selection struct {
+		Config  string `long:"config"  env:"PM_CONFIG"`
+		Profile string `long:"profile" env:"PM_PROFILE"`
+	}
+
+	// errors are reported by the real parse later on
+	flags.NewParser(&selection, flags.IgnoreUnknown).ParseArgs(args)
+
+	if selection.Config == "" {
+		if selection.Profile != "" {
+			return fmt.Errorf("--profile %s given without --config", selection.Profile)
+		}
+
+		return nil
+	}
+
+	payload, err := ioutil.ReadFile(selection.Config)
+	if err != nil {
+		return err
+	}
+
+	var config ConfigFile
+	err = yaml.Unmarshal(payload, &config)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %s", selection.Config, err)
+	}
+
+	name := selection.Profile
+	if name == "" {
+		name = defaultProfile
+	}
+
+	profile, found := config.Profiles[name]
+	if !found {
+		if selection.Profile == "" {
+			return nil
+		}
+
+		return fmt.Errorf("profile %s not found in %s", name, selection.Config)
+	}
+
+	commands := map[string]*flags.Command{}
+	for _, command := range parser.Commands() {
+		commands[command.Name] = command
+	}
+
+	for _, key := range sortedKeys(profile) {
+		value := profile[key]
+
+		if command, isCommand := commands[key]; isCommand {
+			values, ok := value.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("profile %s: %s must be a map of options", name, key)
+			}
+
+			err := setDefaults(command.Group, "", stringKeys(values))
+			if err != nil {
+				return fmt.Errorf("profile %s: %s: %s", name, key, err)
+			}
+
+			continue
+		}
+
+		err := setDefaults(parser.Command.Group, "", map[string]interface{}{key: value})
+		if err != nil {
+			return fmt.Errorf("profile %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+func setDefaults(group *flags.Group, prefix string, values map[string]interface{}) error {
+	options := map[string]*flags.Option{}
+	eachOption(group, func(opt *flags.Option) {
+		options[opt.LongNameWithNamespace()] = opt
+	})
+
+	for _, key := range sortedKeys(values) {
+		name := key
+		if prefix != "" {
+			name = prefix + "-" + key
+		}
+
+		value := values[key]
+		opt, found := options[name]
+
+		if nested, isMap := value.(map[interface{}]interface{}); isMap {
+			if !found || reflect.TypeOf(opt.Value()).Kind() != reflect.Map {
+				err := setDefaults(group, name, stringKeys(nested))
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("unknown option %s", name)
+		}
+
+		opt.Default = defaultValues(value)
+	}
+
+	return nil
+}
+
+// defaultValues converts a YAML value into the strings go-flags parses:
+// lists become one value per element and maps become "key:value" pairs.
+func defaultValues(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		var values []string
+		for _, e := range v {
+			values = append(values, fmt.Sprint(e))
+		}
+
+		return values
+	case map[interface{}]interface{}:
+		pairs := stringKeys(v)
+
+		var values []string
+		for _, key := range sortedKeys(pairs) {
+			values = append(values, fmt.Sprintf("%s:%v", key, pairs[key]))
+		}
+
+		return values
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func stringKeys(m map[interface{}]interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for k, v := range m {
+		converted[fmt.Sprint(k)] = v
+	}
+
+	return converted
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,149 @@
+package main_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+const profiles = `
+profiles:
+  default:
+    format: markdown
+    github:
+      organization-name: [acme]
+    triage:
+      stale-days: 7
+  weekly:
+    format: html
+    exclude-user: [ci-bot, dependabot]
+    github:
+      organization-name: [acme, acme-labs]
+      exclude-repository: ["*-ci"]
+    manifest:
+      weight: {opened-issues: 3, stars: 0.5}
+  misspelled:
+    colour: blue
+  misspelled-github:
+    github:
+      colour: blue
+  unnested-command:
+    triage: 14
+`
+
+var _ = Describe("Config profiles", func() {
+	var (
+		dir    string
+		config string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pm-config")
+		Expect(err).NotTo(HaveOccurred())
+
+		config = filepath.Join(dir, "pm.yml")
+		Expect(ioutil.WriteFile(config, []byte(profiles), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	// parse reads args the way pm does, with env set for the duration, but
+	// does not run the command it selects.
+	parse := func(env map[string]string, args ...string) (*PMCommand, error) {
+		for name, value := range env {
+			Expect(os.Setenv(name, value)).To(Succeed())
+		}
+
+		defer func() {
+			for name := range env {
+				os.Unsetenv(name)
+			}
+		}()
+
+		pm := &PMCommand{}
+
+		parser, err := NewParser(pm, args)
+		if err != nil {
+			return nil, err
+		}
+
+		parser.Options &^= flags.PrintErrors
+		parser.CommandHandler = func(flags.Commander, []string) error { return nil }
+
+		_, err = parser.ParseArgs(args)
+		return pm, err
+	}
+
+	DescribeTable("choosing each option from the flags, then the environment, then the profile",
+		func(env map[string]string, args []string, format string, organizations []string, staleDays int) {
+			pm, err := parse(env, append([]string{"--config", config, "triage"}, args...)...)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(pm.Format).To(Equal(format))
+			Expect(pm.GitHub.OrganizationNames).To(Equal(organizations))
+			Expect(pm.Triage.Options.StaleDays).To(Equal(staleDays))
+		},
+		Entry("the default profile", nil, nil, "markdown", []string{"acme"}, 7),
+		Entry("a profile given with --profile", nil, []string{"--profile", "weekly"}, "html", []string{"acme", "acme-labs"}, 14),
+		Entry("a profile given with PM_PROFILE", map[string]string{"PM_PROFILE": "weekly"}, nil, "html", []string{"acme", "acme-labs"}, 14),
+		Entry("--profile over PM_PROFILE", map[string]string{"PM_PROFILE": "weekly"}, []string{"--profile", "default"}, "markdown", []string{"acme"}, 7),
+		Entry("the environment over the profile", map[string]string{"PM_FORMAT": "json", "PM_GITHUB_ORGANIZATION_NAME": "acme-labs"}, nil, "json", []string{"acme-labs"}, 7),
+		Entry("a flag over the environment", map[string]string{"PM_FORMAT": "json"}, []string{"--format", "text"}, "text", []string{"acme"}, 7),
+		Entry("a command's option from the environment", map[string]string{"PM_TRIAGE_STALE_DAYS": "30"}, nil, "markdown", []string{"acme"}, 30),
+		Entry("a command's option from a flag", map[string]string{"PM_TRIAGE_STALE_DAYS": "30"}, []string{"--stale-days", "3"}, "markdown", []string{"acme"}, 3),
+	)
+
+	It("reads lists, maps and nested options from the profile", func() {
+		pm, err := parse(nil, "--config", config, "--profile", "weekly", "manifest")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pm.ExcludeUsers).To(Equal([]string{"ci-bot", "dependabot"}))
+		Expect(pm.GitHub.ExcludeRepositories).To(Equal([]string{"*-ci"}))
+		Expect(pm.Manifest.Weights).To(Equal(ScoringWeights{"opened-issues": 3, "stars": 0.5}))
+	})
+
+	It("keeps the built-in defaults without a config file", func() {
+		pm, err := parse(nil, "triage")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pm.Format).To(Equal("csv"))
+		Expect(pm.GitHub.OrganizationNames).To(BeEmpty())
+		Expect(pm.Triage.Options.StaleDays).To(Equal(14))
+	})
+
+	It("keeps the built-in defaults when the config file has no default profile", func() {
+		Expect(ioutil.WriteFile(config, []byte("profiles:\n  weekly:\n    format: html\n"), 0644)).To(Succeed())
+
+		pm, err := parse(nil, "--config", config, "triage")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pm.Format).To(Equal("csv"))
+	})
+
+	DescribeTable("rejecting profiles that cannot be applied",
+		func(env map[string]string, args []string, message string) {
+			_, err := parse(env, append([]string{"--config", config, "triage"}, args...)...)
+			Expect(err).To(MatchError(strings.Replace(message, "CONFIG", config, 1)))
+		},
+		Entry("an unknown profile given with --profile", nil, []string{"--profile", "nightly"}, "profile nightly not found in CONFIG"),
+		Entry("an unknown profile given with PM_PROFILE", map[string]string{"PM_PROFILE": "nightly"}, nil, "profile nightly not found in CONFIG"),
+		Entry("an unknown option", nil, []string{"--profile", "misspelled"}, "profile misspelled: unknown option colour"),
+		Entry("an unknown nested option", nil, []string{"--profile", "misspelled-github"}, "profile misspelled-github: unknown option github-colour"),
+		Entry("a command's options not given as a map", nil, []string{"--profile", "unnested-command"}, "profile unnested-command: triage must be a map of options"),
+	)
+
+	It("rejects a profile given without a config file", func() {
+		_, err := parse(nil, "--profile", "weekly", "triage")
+		Expect(err).To(MatchError("--profile weekly given without --config"))
+	})
+})
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"cloud.google.com/go/bigquery"
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/warehouse"
+	"google.golang.org/api/option"
+)
+
+type ExportCommand struct {
+	Directory string `long:"directory" default:"export" description:"Directory to write the tables and their schema files to"`
+
+	BigQuery BigQueryConfig `group:"BigQuery Loading" namespace:"bigquery"`
+}
+
+type BigQueryConfig struct {
+	Project     string `long:"project"     description:"Load the exported tables into BigQuery under this project"`
+	Dataset     string `long:"dataset"     description:"BigQuery dataset to load the tables into (replacing their contents)"`
+	Credentials string `long:"credentials" description:"Service account JSON key file (defaults to the application default credentials)"`
+	Endpoint    string `long:"endpoint"    description:"BigQuery API endpoint to use instead of Google's, e.g. a local fake; requests are not authenticated"`
+}
+
+func (command *ExportCommand) Execute(argv []string) error {
+	ctx := context.Background()
+
+	if command.BigQuery.Dataset != "" && command.BigQuery.Project == "" {
+		return errors.New("--bigquery-dataset needs a --bigquery-project")
+	}
+
+	logger, closeLog, err := PM.Logger("export")
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	exporter, err := warehouse.NewExporter(command.Directory)
+	if err != nil {
+		return err
+	}
+
+	exporter.ExcludedUsers = PM.excludedUsers()
+
+	err = PM.Record(ctx, logger, exporter)
+	if err != nil {
+		exporter.Close()
+		logger.Error("failed", err)
+		return err
+	}
+
+	err = exporter.Close()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("exported", lager.Data{"directory": command.Directory})
+
+	if command.BigQuery.Dataset == "" {
+		return nil
+	}
+
+	client, err := command.BigQuery.Client(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = warehouse.Load(ctx, logger, client, command.BigQuery.Dataset, command.Directory)
+	if err != nil {
+		logger.Error("failed", err)
+		return err
+	}
+
+	return nil
+}
+
+func (config BigQueryConfig) Client(ctx context.Context) (*bigquery.Client, error) {
+	var opts []option.ClientOption
+
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint), option.WithHTTPClient(http.DefaultClient))
+	} else if config.Credentials != "" {
+		opts = append(opts, option.WithServiceAccountFile(config.Credentials))
+	}
+
+	return bigquery.NewClient(ctx, config.Project, opts...)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/store"
+)
+
+type FetchCommand struct {
+	Snapshot string        `long:"snapshot" default:"snapshot.json.gz" description:"Path to write the snapshot to (ignored with --store)"`
+	Poll     time.Duration `long:"poll"                                description:"After fetching, keep reading the organizations' event streams into the store this often until interrupted (requires --store)"`
+}
+
+func (command *FetchCommand) Execute(argv []string) error {
+	if command.Poll != 0 && PM.Store == "" {
+		return errors.New("--poll requires --store")
+	}
+
+	ctx := context.Background()
+
+	logger, closeLog, err := PM.Logger("fetch")
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	backend, done, err := PM.GitHubBackend(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if PM.Store != "" {
+		s, err := store.Open(PM.Store)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		logger.Info("recording into store", lager.Data{"path": PM.Store})
+
+		err = snapshot.Fetch(ctx, logger, backend, PM.GitHub.OrganizationNames, s)
+		if err != nil {
+			logger.Error("failed", err)
+			return err
+		}
+
+		if command.Poll == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		defer signal.Stop(interrupts)
+
+		go func() {
+			select {
+			case <-interrupts:
+				logger.Info("interrupted")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		return PollEvents(ctx, logger, backend, PM.GitHub.OrganizationNames, s, command.Poll)
+	}
+
+	s := snapshot.New(PM.GitHub.OrganizationNames, time.Now())
+
+	err = snapshot.Fetch(ctx, logger, backend, PM.GitHub.OrganizationNames, s)
+	if err != nil {
+		logger.Error("failed", err)
+		return err
+	}
+
+	logger.Info("writing snapshot", lager.Data{
+		"path":         command.Snapshot,
+		"repositories": len(s.Repositories),
+		"issues":       len(s.Issues),
+	})
+
+	return s.WriteFile(command.Snapshot)
+}
+
+// PollEvents reads the organizations' event streams into the store every
+// interval until ctx is cancelled, which also abandons a read in progress.
+// GitHub only keeps the latest 300 events of each stream, so polling keeps
+// busy organizations' pushes and stars complete.
+func PollEvents(ctx context.Context, logger lager.Logger, fetcher gh.Fetcher, orgs []string, s *store.Store, interval time.Duration) error {
+	logger = logger.Session("poll", lager.Data{"interval": interval.String()})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			events, err := fetcher.StreamEventsForOrganizations(ctx, orgs)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if err != nil {
+				logger.Error("failed", err)
+				return err
+			}
+
+			err = s.RecordEvents(events)
+			if err != nil {
+				logger.Error("failed", err)
+				return err
+			}
+
+			logger.Info("recorded events", lager.Data{"events": len(events)})
+		}
+	}
+}
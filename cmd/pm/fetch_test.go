@@ -0,0 +1,97 @@
+package main_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/chendrix/pm/lib/store"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PollEvents", func() {
+	var (
+		server *ghtest.Server
+		dir    string
+		s      *store.Store
+	)
+
+	BeforeEach(func() {
+		server = ghtest.NewServer("../../lib/gh/ghtest/testdata/acme")
+
+		var err error
+		dir, err = ioutil.TempDir("", "pm-poll")
+		Expect(err).NotTo(HaveOccurred())
+
+		s, err = store.Open(filepath.Join(dir, "pm.db"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		s.Close()
+		os.RemoveAll(dir)
+		server.Close()
+	})
+
+	storedEvents := func() int {
+		count := 0
+		err := s.Each(store.Query{Kind: store.KindEvent}, func(store.Entry, []byte) error {
+			count++
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		return count
+	}
+
+	It("reads the event streams into the store every interval until cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- PollEvents(ctx, lagertest.NewTestLogger("test"), server.GitHubClient(), []string{"acme"}, s, 10*time.Millisecond)
+		}()
+
+		polls := func() int {
+			count := 0
+			for _, request := range server.Requests() {
+				if request == "/orgs/acme/events" {
+					count++
+				}
+			}
+
+			return count
+		}
+
+		Eventually(polls).Should(BeNumerically(">=", 2))
+		Expect(storedEvents()).NotTo(BeZero())
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+
+		stored := storedEvents()
+		Consistently(polls, 50*time.Millisecond).Should(Equal(polls()))
+		Expect(storedEvents()).To(Equal(stored))
+	})
+
+	It("stops on its own context rather than waiting for the next tick", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- PollEvents(ctx, lagertest.NewTestLogger("test"), server.GitHubClient(), []string{"acme"}, s, time.Hour)
+		}()
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(server.Requests()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+type ForksCommand struct {
+	Options ForksOptions `group:"Forks Report"`
+}
+
+func (command *ForksCommand) Execute(argv []string) error {
+	return PM.Run("forks", command.Report)
+}
+
+// Report renders the forks report from the crawler's forks and pull
+// requests.
+func (command *ForksCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	orgs := PM.GitHub.OrganizationNames
+
+	logger.Debug("gathering forks")
+	forks, err := crawler.ForksForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering issues")
+	issues, err := crawler.IssuesUpdatedSinceForOrganizations(ctx, orgs, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return ForksReport(ctx, t, now, command.Options, forks, issues)
+}
+
+type ForksOptions struct {
+	Active  time.Duration `long:"active"  default:"2160h" description:"Count a fork as active if it was pushed to within this long before now and has commits its upstream lacks"`
+	Flagged bool          `long:"flagged"                 description:"Only list active forks whose owners have never opened a pull request upstream"`
+}
+
+// ForkActivity is a fork and what its owner has done upstream.
+type ForkActivity struct {
+	*gh.Fork
+
+	Upstream string
+
+	// PullRequests is the number of pull requests the fork's owner has
+	// opened in the upstream repository.
+	PullRequests int
+
+	Active  bool
+	Flagged bool
+}
+
+// ForksReport renders one row per fork, grouped by upstream repository and
+// most recently pushed first. Active forks whose owners have never opened a
+// pull request upstream are flagged.
+func ForksReport(ctx context.Context, t tablewriter.TableWriter, now time.Time, options ForksOptions, forks map[string][]*gh.Fork, issues []*github.Issue) error {
+	t.SetHeader([]string{"Repository", "Fork", "Owner", "Status", "Ahead", "Behind", "Last Push", "Upstream Pull Requests", "Active", "Flagged"})
+
+	for _, f := range MeasureForks(now, options, forks, issues) {
+		if options.Flagged && !f.Flagged {
+			continue
+		}
+
+		status := f.Status
+		if status == "" {
+			status = "unknown"
+		}
+
+		lastPush := ""
+		if f.Repository.PushedAt != nil {
+			lastPush = f.Repository.GetPushedAt().Format("2006-01-02")
+		}
+
+		t.Append([]string{
+			f.Upstream,
+			f.Repository.GetFullName(),
+			f.Repository.Owner.GetLogin(),
+			status,
+			fmt.Sprintf("%d", f.AheadBy),
+			fmt.Sprintf("%d", f.BehindBy),
+			lastPush,
+			fmt.Sprintf("%d", f.PullRequests),
+			yesNo(f.Active),
+			yesNo(f.Flagged),
+		})
+	}
+
+	return t.Render()
+}
+
+// MeasureForks works out which forks are active and whose owners have
+// opened pull requests upstream, ordered by upstream repository and then
+// most recently pushed first.
+func MeasureForks(now time.Time, options ForksOptions, forks map[string][]*gh.Fork, issues []*github.Issue) []ForkActivity {
+	pullRequests := map[string]map[string]int{}
+	for _, i := range issues {
+		if i.PullRequestLinks == nil || i.User == nil {
+			continue
+		}
+
+		repo := gh.IssueRepository(i)
+		if pullRequests[repo] == nil {
+			pullRequests[repo] = map[string]int{}
+		}
+
+		pullRequests[repo][i.User.GetLogin()]++
+	}
+
+	activeSince := now.Add(-options.Active)
+
+	var measured []ForkActivity
+	for upstream, repoForks := range forks {
+		for _, fork := range repoForks {
+			f := ForkActivity{
+				Fork:         fork,
+				Upstream:     upstream,
+				PullRequests: pullRequests[upstream][fork.Repository.Owner.GetLogin()],
+			}
+
+			f.Active = fork.AheadBy > 0 && !fork.Repository.GetPushedAt().Before(activeSince)
+			f.Flagged = f.Active && f.PullRequests == 0
+
+			measured = append(measured, f)
+		}
+	}
+
+	sort.Slice(measured, func(i, j int) bool {
+		a, b := measured[i], measured[j]
+		if a.Upstream != b.Upstream {
+			return a.Upstream < b.Upstream
+		}
+
+		pa, pb := a.Repository.GetPushedAt().Time, b.Repository.GetPushedAt().Time
+		if !pa.Equal(pb) {
+			return pa.After(pb)
+		}
+
+		return a.Repository.GetFullName() < b.Repository.GetFullName()
+	})
+
+	return measured
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
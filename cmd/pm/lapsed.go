@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+)
+
+type LapsedCommand struct {
+	Criteria LapsedCriteria `group:"Lapsed Report"`
+}
+
+func (command *LapsedCommand) Execute(argv []string) error {
+	return PM.Run("lapsed", command.Report)
+}
+
+// Report renders the lapsed report from the crawler's activity. Issues are
+// counted whether or not they have been closed since, so a user whose recent
+// issues were all closed is not taken for lapsed.
+func (command *LapsedCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	baselineStart := now.Add(-command.Criteria.Recent).Add(-command.Criteria.Baseline)
+
+	logger.Debug("gathering activity")
+	events, err := gh.CrawlEventsSince(ctx, crawler, PM.GitHub.OrganizationNames, baselineStart)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return LapsedReport(ctx, t, now, command.Criteria, events)
+}
+
+type LapsedCriteria struct {
+	Baseline  time.Duration `long:"baseline"  default:"2160h" description:"Length of the window, ending where the recent window starts, in which users must have been active"`
+	Recent    time.Duration `long:"recent"    default:"720h"  description:"Length of the trailing window in which lapsed users have had no activity"`
+	Threshold int           `long:"threshold" default:"5"     description:"Number of activities a user must exceed during the baseline window"`
+}
+
+type LapsedUser struct {
+	User             *User
+	BaselineActivity int
+	LastActivity     activity.Event
+	LastComment      activity.Event
+}
+
+// LapsedReport lists users who were active during the baseline window but have
+// gone quiet during the recent window, most active first.
+func LapsedReport(ctx context.Context, t tablewriter.TableWriter, now time.Time, criteria LapsedCriteria, events []activity.Event) error {
+	u := CatalogUsers(events)
+
+	lapsed := FindLapsedUsers(u, now, criteria)
+
+	t.SetHeader([]string{"Github User", "Baseline Activity", "Last Activity", "Last Repository", "Last Comment"})
+
+	for _, l := range lapsed {
+		t.Append([]string{
+			l.User.Login,
+			fmt.Sprintf("%d", l.BaselineActivity),
+			l.LastActivity.CreatedAt.Format("2006-01-02"),
+			l.LastActivity.Repository,
+			l.LastComment.URL,
+		})
+	}
+
+	return t.Render()
+}
+
+func FindLapsedUsers(u UserList, now time.Time, criteria LapsedCriteria) []LapsedUser {
+	recentStart := now.Add(-criteria.Recent)
+	baselineStart := recentStart.Add(-criteria.Baseline)
+
+	var lapsed []LapsedUser
+
+	for _, user := range u {
+		l := LapsedUser{User: user}
+		recent := false
+
+		for _, a := range user.Events {
+			if !a.CreatedAt.Before(recentStart) {
+				recent = true
+				break
+			}
+
+			if !a.CreatedAt.Before(baselineStart) {
+				l.BaselineActivity++
+			}
+
+			if a.CreatedAt.After(l.LastActivity.CreatedAt) {
+				l.LastActivity = a
+			}
+
+			if a.Kind.IsComment() && a.CreatedAt.After(l.LastComment.CreatedAt) {
+				l.LastComment = a
+			}
+		}
+
+		if recent || l.BaselineActivity <= criteria.Threshold {
+			continue
+		}
+
+		lapsed = append(lapsed, l)
+	}
+
+	sort.Slice(lapsed, func(i, j int) bool {
+		if lapsed[i].BaselineActivity != lapsed[j].BaselineActivity {
+			return lapsed[i].BaselineActivity > lapsed[j].BaselineActivity
+		}
+
+		return lapsed[i].User.Login < lapsed[j].User.Login
+	})
+
+	return lapsed
+}
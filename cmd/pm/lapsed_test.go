@@ -0,0 +1,75 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LapsedCommand", func() {
+	var s *snapshot.Snapshot
+
+	BeforeEach(func() {
+		PM.GitHub.OrganizationNames = []string{"acme"}
+
+		s = snapshot.New([]string{"acme"}, now)
+		s.Repositories = []*github.Repository{{
+			FullName: github.String("acme/widget"),
+			Owner:    &github.User{Login: github.String("acme")},
+		}}
+	})
+
+	comments := func(login string, count int, from time.Time) {
+		for n := 0; n < count; n++ {
+			at := from.AddDate(0, 0, n)
+			s.IssueComments = append(s.IssueComments, &github.IssueComment{
+				ID:        github.Int(len(s.IssueComments) + 1),
+				User:      &github.User{Login: github.String(login)},
+				CreatedAt: &at,
+				URL:       github.String(fmt.Sprintf("https://api.github.com/repos/acme/widget/issues/comments/%d", len(s.IssueComments)+1)),
+				HTMLURL:   github.String(fmt.Sprintf("https://github.com/acme/widget/issues/1#issuecomment-%d", len(s.IssueComments)+1)),
+			})
+		}
+	}
+
+	It("counts recent issues that have since been closed as activity", func() {
+		baseline := time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC)
+		comments("frank", 6, baseline)
+		comments("grace", 6, baseline)
+
+		opened := time.Date(2017, time.June, 15, 0, 0, 0, 0, time.UTC)
+		closed := opened.Add(24 * time.Hour)
+		s.Issues = append(s.Issues, &github.Issue{
+			ID:        github.Int(9001),
+			Number:    github.Int(9),
+			User:      &github.User{Login: github.String("frank")},
+			State:     github.String("closed"),
+			CreatedAt: &opened,
+			UpdatedAt: &closed,
+			ClosedAt:  &closed,
+			URL:       github.String("https://api.github.com/repos/acme/widget/issues/9"),
+		})
+
+		var buf bytes.Buffer
+		t, err := tablewriter.New("csv", &buf)
+		Expect(err).NotTo(HaveOccurred())
+
+		command := &LapsedCommand{Criteria: LapsedCriteria{Baseline: 90 * 24 * time.Hour, Recent: 30 * 24 * time.Hour, Threshold: 5}}
+		err = command.Report(context.Background(), lagertest.NewTestLogger("test"), snapshot.NewCrawler(s), t, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(buf.String()).To(Equal(`Github User,Baseline Activity,Last Activity,Last Repository,Last Comment
+grace,6,2017-04-06,acme/widget,https://github.com/acme/widget/issues/1#issuecomment-12
+`))
+	})
+})
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gcs"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/graphql"
+	"github.com/chendrix/pm/lib/progress"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+	"github.com/jessevdk/go-flags"
+	"github.com/vito/twentythousandtonnesofcrudeoil"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+const (
+	envPrefix       = "PM_"
+	legacyEnvPrefix = "PASSENGERMANIFEST_"
+)
+
+var PM PMCommand
+
+type PMCommand struct {
+	GitHub GitHubConfig `group:"GitHub Configuration" namespace:"github"`
+
+	Config  string `long:"config"  description:"YAML file of named option profiles"`
+	Profile string `long:"profile" description:"Profile from the config file to use (default: default)"`
+
+	Format       string   `long:"format"       default:"csv" choice:"csv" choice:"text" choice:"markdown" choice:"json" choice:"html" description:"Output table format"`
+	Outputs      []Output `long:"output"       description:"Write the report as format:path instead of to stdout, e.g. html:reports/index.html (may be given more than once; a path of - is stdout)"`
+	ExcludeUsers []string `long:"exclude-user" description:"Ignore all activity by this user, e.g. a bot (may be given more than once)"`
+
+	FromSnapshot string `long:"from-snapshot" description:"Build the report from a snapshot written by pm fetch instead of crawling GitHub"`
+	Store        string `long:"store"         description:"Local database of crawled activity: pm fetch records into it and reports are built from it instead of crawling GitHub"`
+	Backend      string `long:"backend"       default:"rest" choice:"rest" choice:"graphql" description:"GitHub API to crawl: rest pages through each kind of activity separately, graphql fetches a repository's issues with their comments, events and reviews in nested queries"`
+
+	Upload            string `long:"upload"             description:"Also upload every rendered report to gs://bucket/prefix, under the date and as latest"`
+	UploadCredentials string `long:"upload-credentials" description:"Service account JSON key file for uploading (defaults to the application default credentials)"`
+	UploadEndpoint    string `long:"upload-endpoint"    description:"Cloud Storage API endpoint to upload to instead of Google's, e.g. a local stand-in; requests are not authenticated"`
+
+	Debug            bool          `long:"debug"             description:"Run in debug mode"`
+	LogFile          string        `long:"log-file"          description:"Write logs to this file instead of stderr"`
+	ProgressInterval time.Duration `long:"progress-interval" default:"30s" description:"How often to log crawl progress when stderr is not a terminal"`
+
+	Fetch  FetchCommand  `command:"fetch" description:"Crawl GitHub into a snapshot file (or --store) for offline reports"`
+	Query  QueryCommand  `command:"query"  description:"List or count stored activity matching a filter expression"`
+	Export ExportCommand `command:"export" description:"Write users, repositories, issues, comments and reviews as BigQuery-ready tables"`
+
+	Manifest       ManifestCommand       `command:"manifest"       description:"Issue and comment counts per user (or per repository)"`
+	Matrix         MatrixCommand         `command:"matrix"         description:"Contribution counts for every user in every repository"`
+	Cohorts        CohortsCommand        `command:"cohorts"        description:"Retention of users grouped by the month they were first seen"`
+	Lapsed         LapsedCommand         `command:"lapsed"         description:"Previously active users who have gone quiet"`
+	Responsiveness ResponsivenessCommand `command:"responsiveness" description:"Time to first response, first label and close per repository"`
+	Triage         TriageCommand         `command:"triage"         description:"Open issues awaiting a maintainer"`
+	Stars          StarsCommand          `command:"stars"          description:"Stars and watchers per repository, star history and new stargazers"`
+	Forks          ForksCommand          `command:"forks"          description:"Forks compared to their upstream, flagging active ones whose owners never opened a pull request"`
+	Releases       ReleasesCommand       `command:"releases"       description:"Downloads and time between releases, crediting the users whose pull requests and issues landed in each"`
+	Traffic        TrafficCommand        `command:"traffic"        description:"Views, clones, referrers and popular paths per repository, kept in --store beyond GitHub's 14 days"`
+}
+
+type GitHubConfig struct {
+	Tokens              []string `long:"token"              default-mask:"-" description:"GitHub access token (may be given more than once to spread requests across several tokens; required unless authenticating as a GitHub App or reading a snapshot or store)"`
+	OrganizationNames   []string `long:"organization-name"                   description:"GitHub organization name (may be given more than once; required unless reading a snapshot or store)"`
+	IncludeRepositories []string `long:"include-repository"                  description:"Only crawl repositories whose name or owner/name matches this glob (may be given more than once)"`
+	ExcludeRepositories []string `long:"exclude-repository"                  description:"Skip repositories whose name or owner/name matches this glob (may be given more than once)"`
+
+	AppID         int    `long:"app-id"          description:"Authenticate as this GitHub App, using its installation on each organization, instead of with --github-token"`
+	AppPrivateKey string `long:"app-private-key" description:"PEM private key of the GitHub App given by --github-app-id"`
+
+	APIURL        string `long:"api-url"         description:"GitHub Enterprise Server API URL, e.g. https://github.example.com/api/v3/ (default: api.github.com)"`
+	UploadURL     string `long:"upload-url"      description:"GitHub Enterprise Server upload URL (default: derived from --github-api-url)"`
+	CABundle      string `long:"ca-bundle"       description:"PEM file of certificate authorities to trust in addition to the system's"`
+	SkipTLSVerify bool   `long:"skip-tls-verify" description:"Do not verify the GitHub server's certificate"`
+}
+
+// ReportFunc gathers whatever a report needs from the crawler and renders it
+// to t.
+type ReportFunc func(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error
+
+func main() {
+	parser, err := NewParser(&PM, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	_, err = parser.Parse()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// NewParser builds the parser for pm's options and commands, reading them
+// from PM_ environment variables and from the config profile that args
+// select.
+func NewParser(pm *PMCommand, args []string) (*flags.Parser, error) {
+	parser := flags.NewParser(pm, flags.Default)
+	parser.NamespaceDelimiter = "-"
+
+	installEnvironment(parser)
+
+	err := applyConfig(parser, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return parser, nil
+}
+
+// installEnvironment lets every option be set through a PM_ environment
+// variable. Options belonging to a subcommand are namespaced by the command's
+// name, e.g. PM_TRIAGE_STALE_DAYS, and the PASSENGERMANIFEST_ variables from
+// before the subcommands existed are still honored.
+func installEnvironment(parser *flags.Parser) {
+	for _, command := range parser.Commands() {
+		prefix := envPrefix + envName(command.Name) + "_"
+
+		eachOption(command.Group, func(opt *flags.Option) {
+			if len(opt.EnvDefaultKey) == 0 {
+				opt.EnvDefaultKey = prefix + envName(opt.LongNameWithNamespace())
+
+				kind := reflect.TypeOf(opt.Value()).Kind()
+				if kind == reflect.Map || kind == reflect.Slice {
+					opt.EnvDefaultDelim = ","
+				}
+			}
+		})
+	}
+
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, legacyEnvPrefix) {
+			continue
+		}
+
+		kv := strings.SplitN(env, "=", 2)
+		name := envPrefix + strings.TrimPrefix(kv[0], legacyEnvPrefix)
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, kv[1])
+		}
+
+		os.Unsetenv(kv[0])
+	}
+
+	twentythousandtonnesofcrudeoil.TheEnvironmentIsPerfectlySafe(parser, envPrefix)
+}
+
+func eachOption(group *flags.Group, cb func(*flags.Option)) {
+	for _, opt := range group.Options() {
+		cb(opt)
+	}
+
+	for _, g := range group.Groups() {
+		eachOption(g, cb)
+	}
+}
+
+func envName(flag string) string {
+	return strings.Replace(strings.ToUpper(flag), "-", "_", -1)
+}
+
+// Run sets up logging, the source of GitHub activity and the output table
+// shared by every report command, then runs the report.
+func (pm *PMCommand) Run(name string, report ReportFunc) error {
+	ctx := context.Background()
+
+	logger, closeLog, err := pm.Logger(name)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	crawler, release, err := pm.Crawler(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var uploader *gcs.Uploader
+	if pm.Upload != "" {
+		uploader, err = pm.Uploader(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	outputs := pm.Outputs
+	if len(outputs) == 0 {
+		outputs = []Output{{Format: pm.Format, Path: "-"}}
+	}
+
+	t, err := OpenOutputs(outputs, uploader != nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = report(ctx, logger, crawler, t, now)
+	if err != nil {
+		t.Abort()
+		logger.Error("failed", err)
+		return err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return err
+	}
+
+	if uploader == nil {
+		return nil
+	}
+
+	for _, r := range t.Rendered {
+		objectName := name + tablewriter.Extensions[r.Format]
+		if r.Path != "-" {
+			objectName = filepath.Base(r.Path)
+		}
+
+		object, err := uploader.Upload(ctx, objectName, tablewriter.ContentTypes[r.Format], r.Content, now)
+		if err != nil {
+			logger.Error("failed-to-upload", err, lager.Data{"name": objectName})
+			return err
+		}
+
+		logger.Info("uploaded", lager.Data{"bucket": uploader.Destination.Bucket, "object": object})
+	}
+
+	return nil
+}
+
+// Uploader returns an uploader for the destination given with --upload.
+func (pm *PMCommand) Uploader(ctx context.Context) (*gcs.Uploader, error) {
+	destination, err := gcs.ParseURL(pm.Upload)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []option.ClientOption
+
+	if pm.UploadEndpoint != "" {
+		opts = append(opts, option.WithEndpoint(pm.UploadEndpoint), option.WithHTTPClient(http.DefaultClient))
+	} else if pm.UploadCredentials != "" {
+		opts = append(opts, option.WithServiceAccountFile(pm.UploadCredentials))
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcs.NewUploader(client, destination), nil
+}
+
+// Logger returns a logger for the named command writing to stderr or
+// --log-file, and a function that closes the log file.
+func (pm *PMCommand) Logger(name string) (lager.Logger, func(), error) {
+	var logWriter io.Writer = os.Stderr
+	closeLog := func() {}
+
+	if pm.LogFile != "" {
+		logFile, err := os.OpenFile(pm.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		logWriter = logFile
+		closeLog = func() { logFile.Close() }
+	}
+
+	logLevel := lager.INFO
+	if pm.Debug {
+		logLevel = lager.DEBUG
+	}
+
+	l := lager.NewLogger("pm")
+	l.RegisterSink(lager.NewWriterSink(logWriter, logLevel))
+
+	return l.Session(name), closeLog, nil
+}
+
+// Crawler returns the snapshot given with --from-snapshot, the store given
+// with --store or, without either, a client for the GitHub API, and a
+// function that releases it.
+func (pm *PMCommand) Crawler(ctx context.Context, logger lager.Logger) (gh.Crawler, func(), error) {
+	switch {
+	case pm.FromSnapshot != "":
+		logger.Debug("reading snapshot", lager.Data{"path": pm.FromSnapshot})
+		s, err := snapshot.ReadFile(pm.FromSnapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(pm.GitHub.OrganizationNames) == 0 {
+			pm.GitHub.OrganizationNames = s.Organizations
+		}
+
+		crawler := snapshot.NewCrawler(s)
+		crawler.RepositoryFilter = pm.repositoryFilter()
+		crawler.ExcludedUsers = pm.excludedUsers()
+
+		return crawler, func() {}, nil
+
+	case pm.Store != "":
+		logger.Debug("opening store", lager.Data{"path": pm.Store})
+		s, err := store.Open(pm.Store)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(pm.GitHub.OrganizationNames) == 0 {
+			pm.GitHub.OrganizationNames, err = storedOrganizations(s)
+			if err != nil {
+				s.Close()
+				return nil, nil, err
+			}
+		}
+
+		crawler := store.NewCrawler(s)
+		crawler.RepositoryFilter = pm.repositoryFilter()
+		crawler.ExcludedUsers = pm.excludedUsers()
+
+		return crawler, func() { s.Close() }, nil
+
+	default:
+		return pm.GitHubBackend(ctx, logger)
+	}
+}
+
+// Record hands r everything crawled from the organizations, reading it from
+// --from-snapshot or --store if given and crawling GitHub otherwise.
+func (pm *PMCommand) Record(ctx context.Context, logger lager.Logger, r snapshot.Recorder) error {
+	switch {
+	case pm.FromSnapshot != "":
+		logger.Debug("reading snapshot", lager.Data{"path": pm.FromSnapshot})
+		s, err := snapshot.ReadFile(pm.FromSnapshot)
+		if err != nil {
+			return err
+		}
+
+		if len(pm.GitHub.OrganizationNames) == 0 {
+			pm.GitHub.OrganizationNames = s.Organizations
+		}
+
+		return s.Replay(pm.GitHub.OrganizationNames, pm.repositoryFilter(), r)
+
+	case pm.Store != "":
+		logger.Debug("opening store", lager.Data{"path": pm.Store})
+		s, err := store.Open(pm.Store)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		if len(pm.GitHub.OrganizationNames) == 0 {
+			pm.GitHub.OrganizationNames, err = storedOrganizations(s)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.Replay(pm.GitHub.OrganizationNames, pm.repositoryFilter(), r)
+
+	default:
+		backend, done, err := pm.GitHubBackend(ctx, logger)
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		return snapshot.Fetch(ctx, logger, backend, pm.GitHub.OrganizationNames, r)
+	}
+}
+
+// storedOrganizations returns the owners of every repository in the store.
+func storedOrganizations(s *store.Store) ([]string, error) {
+	repos, err := s.Repositories()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+
+	var orgs []string
+	for _, repo := range repos {
+		owner := repo.Owner.GetLogin()
+		if !seen[owner] {
+			seen[owner] = true
+			orgs = append(orgs, owner)
+		}
+	}
+
+	return orgs, nil
+}
+
+// GitHubBackend returns a client for the REST API of GitHub, or for the
+// GitHub Enterprise Server at --github-api-url, or for the GraphQL API of the
+// same server with --backend graphql. It is authenticated with
+// --github-token (pooled when several are given) or as the GitHub App given
+// by --github-app-id. The returned func logs how much each pooled token was
+// used.
+func (pm *PMCommand) GitHubBackend(ctx context.Context, logger lager.Logger) (gh.Backend, func(), error) {
+	ghClient, httpClient, done, err := pm.gitHubClient(ctx, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pm.Backend != "graphql" {
+		return ghClient, done, nil
+	}
+
+	client := graphql.NewClient(httpClient, ghClient.GithubClient.BaseURL)
+	client.Progress = ghClient.Progress
+	client.RepositoryFilter = ghClient.RepositoryFilter
+	client.ExcludedUsers = ghClient.ExcludedUsers
+
+	logger.Debug("using-graphql-backend", lager.Data{"endpoint": client.Endpoint})
+
+	return client, done, nil
+}
+
+// gitHubClient returns a REST API client and the authenticated HTTP client
+// it makes requests with.
+func (pm *PMCommand) gitHubClient(ctx context.Context, logger lager.Logger) (*gh.Client, *http.Client, func(), error) {
+	if len(pm.GitHub.Tokens) == 0 && pm.GitHub.AppID == 0 {
+		return nil, nil, nil, errors.New("the required flag `--github-token' (or `--github-app-id') was not specified")
+	}
+
+	if len(pm.GitHub.Tokens) > 0 && pm.GitHub.AppID != 0 {
+		return nil, nil, nil, errors.New("only one of `--github-token' and `--github-app-id' may be given")
+	}
+
+	if pm.GitHub.AppID != 0 && pm.GitHub.AppPrivateKey == "" {
+		return nil, nil, nil, errors.New("the flag `--github-app-private-key' is required with `--github-app-id'")
+	}
+
+	if len(pm.GitHub.OrganizationNames) == 0 {
+		return nil, nil, nil, errors.New("the required flag `--github-organization-name' was not specified")
+	}
+
+	transport, err := gh.NewTransport(pm.GitHub.CABundle, pm.GitHub.SkipTLSVerify)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	httpClient := &http.Client{Transport: transport}
+
+	ghClient := gh.NewClient(github.NewClient(httpClient))
+	if pm.GitHub.APIURL != "" {
+		ghClient, err = gh.NewEnterpriseClient(httpClient, pm.GitHub.APIURL, pm.GitHub.UploadURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Authentication wraps the transport once the client knows which server
+	// it talks to, since a GitHub App exchanges its credentials there.
+	httpClient.Transport, err = pm.authenticatingTransport(ctx, logger, ghClient.GithubClient.BaseURL, transport)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	done := func() {}
+	if pool, ok := httpClient.Transport.(*gh.TokenPool); ok {
+		done = pool.LogUsage
+	}
+
+	if pm.GitHub.APIURL != "" {
+		ghClient.Version, err = ghClient.DetectServerVersion(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("detecting GitHub server version: %s", err)
+		}
+
+		logger.Info("detected-server-version", lager.Data{"version": ghClient.Version.String()})
+
+		for _, feature := range []gh.Feature{gh.FeatureReviews} {
+			if !ghClient.Version.Supports(feature) {
+				logger.Info("feature-unsupported", lager.Data{"feature": feature, "version": ghClient.Version.Version})
+			}
+		}
+	}
+
+	ghClient.Progress = pm.progressReporter(logger)
+	ghClient.RepositoryFilter = pm.repositoryFilter()
+	ghClient.ExcludedUsers = pm.excludedUsers()
+
+	return ghClient, httpClient, done, nil
+}
+
+// authenticatingTransport adds --github-token to every request, or the
+// --github-token with the most quota left when several are given, or an
+// installation token of the GitHub App given by --github-app-id.
+func (pm *PMCommand) authenticatingTransport(ctx context.Context, logger lager.Logger, baseURL *url.URL, base http.RoundTripper) (http.RoundTripper, error) {
+	if len(pm.GitHub.Tokens) > 1 {
+		logger.Info("pooling-tokens", lager.Data{"tokens": len(pm.GitHub.Tokens)})
+		return gh.NewTokenPool(pm.GitHub.Tokens, base, logger), nil
+	}
+
+	if pm.GitHub.AppID == 0 {
+		return &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: pm.GitHub.Tokens[0]}),
+			Base:   base,
+		}, nil
+	}
+
+	key, err := gh.ReadPrivateKey(pm.GitHub.AppPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("authenticating-as-github-app", lager.Data{"app-id": pm.GitHub.AppID})
+
+	app := gh.NewApp(pm.GitHub.AppID, key, baseURL, base)
+
+	return gh.NewAppTransport(ctx, app, base, pm.GitHub.OrganizationNames)
+}
+
+func (pm *PMCommand) repositoryFilter() gh.RepositoryFilter {
+	return gh.RepositoryFilter{
+		Include: pm.GitHub.IncludeRepositories,
+		Exclude: pm.GitHub.ExcludeRepositories,
+	}
+}
+
+func (pm *PMCommand) excludedUsers() map[string]bool {
+	excluded := map[string]bool{}
+	for _, login := range pm.ExcludeUsers {
+		excluded[login] = true
+	}
+
+	return excluded
+}
+
+// progressReporter draws a live progress line when stderr is a terminal and
+// logs periodic progress events otherwise.
+func (pm *PMCommand) progressReporter(logger lager.Logger) gh.Progress {
+	if terminal.IsTerminal(int(os.Stderr.Fd())) {
+		return progress.NewTerminalReporter(os.Stderr)
+	}
+
+	return progress.NewLoggingReporter(logger, pm.ProgressInterval)
+}
+
+// GatherActivity fetches the events most reports are built from.
+func (pm *PMCommand) GatherActivity(ctx context.Context, logger lager.Logger, source activity.Source) ([]activity.Event, error) {
+	logger.Debug("gathering activity")
+	return source.Events(ctx, pm.GitHub.OrganizationNames)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+)
+
+type ManifestCommand struct {
+	GroupBy string         `long:"group-by" default:"user" choice:"user" choice:"repository" description:"Group rows by user or by repository"`
+	Weights ScoringWeights `long:"weight"                                                     description:"Weight of opened-issues, issue-comments, repository-comments, pushes, closed-issues, wiki-edits, releases, forks or stars in a user's score, as kind:weight (unset issue and comment kinds weigh 1, the others 0)"`
+}
+
+func (command *ManifestCommand) Execute(argv []string) error {
+	err := command.Weights.Validate()
+	if err != nil {
+		return err
+	}
+
+	return PM.Run("manifest", command.Report)
+}
+
+// Report renders the manifest report from the crawler's activity.
+func (command *ManifestCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	events, err := PM.GatherActivity(ctx, logger, crawler)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	if command.GroupBy == "repository" {
+		return RepositoryReport(ctx, t, events)
+	}
+
+	return Report(ctx, t, command.Weights, events)
+}
+
+// Report renders one row per user, highest score first.
+func Report(ctx context.Context, t tablewriter.TableWriter, weights ScoringWeights, events []activity.Event) error {
+	u := CatalogUsers(events)
+
+	var names []string
+	for name := range u {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		si, sj := weights.Score(u[names[i]]), weights.Score(u[names[j]])
+		if si != sj {
+			return si > sj
+		}
+
+		return names[i] < names[j]
+	})
+
+	header := []string{"Github User"}
+	for _, c := range manifestColumns {
+		header = append(header, c.header)
+	}
+
+	t.SetHeader(append(header, "Score"))
+
+	for _, name := range names {
+		user := u[name]
+
+		row := []string{name}
+		for _, c := range manifestColumns {
+			row = append(row, fmt.Sprintf("%d", user.Count(c.kind)))
+		}
+
+		t.Append(append(row, strconv.FormatFloat(weights.Score(user), 'f', -1, 64)))
+	}
+
+	return t.Render()
+}
+
+// manifestColumns are the kinds of activity the manifest counts, with the
+// name a kind's weight is given by and its weight when none is given. Event
+// stream activity such as stars does not count towards the score unless
+// asked for.
+var manifestColumns = []struct {
+	kind          activity.Kind
+	header        string
+	weight        string
+	defaultWeight float64
+}{
+	{activity.IssueOpened, "Opened Issues", "opened-issues", 1},
+	{activity.IssueComment, "Issue Comments", "issue-comments", 1},
+	{activity.RepositoryComment, "Repository Comments", "repository-comments", 1},
+	{activity.Push, "Pushes", "pushes", 0},
+	{activity.IssueClosed, "Closed Issues", "closed-issues", 0},
+	{activity.WikiEdit, "Wiki Edits", "wiki-edits", 0},
+	{activity.Release, "Releases", "releases", 0},
+	{activity.Fork, "Forks", "forks", 0},
+	{activity.Star, "Stars", "stars", 0},
+}
+
+// ScoringWeights maps a kind of activity to how much each one counts towards
+// a user's score.
+type ScoringWeights map[string]float64
+
+func (w ScoringWeights) Validate() error {
+	var kinds []string
+	for _, c := range manifestColumns {
+		kinds = append(kinds, c.weight)
+	}
+
+	for kind := range w {
+		known := false
+		for _, k := range kinds {
+			if kind == k {
+				known = true
+			}
+		}
+
+		if !known {
+			return fmt.Errorf("unknown weight %q (expected one of %s)", kind, strings.Join(kinds, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (w ScoringWeights) Score(u *User) float64 {
+	score := 0.0
+	for _, c := range manifestColumns {
+		weight, set := w[c.weight]
+		if !set {
+			weight = c.defaultWeight
+		}
+
+		score += weight * float64(u.Count(c.kind))
+	}
+
+	return score
+}
+
+type UserList map[string]*User
+
+func NewUserList() UserList {
+	return make(map[string]*User)
+}
+
+func CatalogUsers(events []activity.Event) UserList {
+	u := NewUserList()
+
+	for _, e := range events {
+		u.Catalog(e)
+	}
+
+	return u
+}
+
+// Catalog credits an event to its actor, unless the source did not say who
+// it was.
+func (u UserList) Catalog(e activity.Event) {
+	if e.Actor == "" {
+		return
+	}
+
+	user, exists := u[e.Actor]
+	if !exists {
+		user = &User{
+			Login: e.Actor,
+		}
+		u[e.Actor] = user
+	}
+
+	user.Add(e)
+}
+
+type User struct {
+	Login string
+
+	// Events are everything the user has done, in no particular order.
+	Events []activity.Event
+}
+
+func (u *User) Add(e activity.Event) {
+	u.Events = append(u.Events, e)
+}
+
+// Count returns how many of the user's events are of the given kind.
+func (u *User) Count(kind activity.Kind) int {
+	count := 0
+	for _, e := range u.Events {
+		if e.Kind == kind {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Contributions returns how many of the user's events added to a repository.
+func (u *User) Contributions() int {
+	count := 0
+	for _, e := range u.Events {
+		if e.Kind.IsContribution() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ActivityTimes returns the time of everything the user has done, in no
+// particular order.
+func (u *User) ActivityTimes() []time.Time {
+	var times []time.Time
+
+	for _, e := range u.Events {
+		times = append(times, e.CreatedAt)
+	}
+
+	return times
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chendrix/pm/lib/tablewriter"
+)
+
+// Output is a report destination given as format:path. A path of "-" means
+// stdout.
+type Output struct {
+	Format string
+	Path   string
+}
+
+func (o *Output) UnmarshalFlag(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("output %q must be given as format:path", value)
+	}
+
+	for _, format := range tablewriter.Formats {
+		if parts[0] == format {
+			o.Format = parts[0]
+			o.Path = parts[1]
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown output format %q (expected one of %s)", parts[0], strings.Join(tablewriter.Formats, ", "))
+}
+
+// Outputs renders a report to every output at once. Files are written to a
+// temporary file next to their destination and only moved into place by
+// Commit, so a failed run never leaves a partial report behind.
+type Outputs struct {
+	tablewriter.TableWriter
+
+	// Rendered holds a copy of every output when opened to capture them.
+	Rendered []Rendered
+
+	pending []pendingFile
+}
+
+// Rendered is a copy of what was rendered to an output.
+type Rendered struct {
+	Output
+	Content *bytes.Buffer
+}
+
+type pendingFile struct {
+	tmp  *os.File
+	path string
+}
+
+// OpenOutputs opens every output, also keeping a copy of each in Rendered if
+// capture is set.
+func OpenOutputs(outputs []Output, capture bool) (*Outputs, error) {
+	o := &Outputs{}
+
+	dest := func(output Output, w io.Writer) io.Writer {
+		if !capture {
+			return w
+		}
+
+		r := Rendered{Output: output, Content: &bytes.Buffer{}}
+		o.Rendered = append(o.Rendered, r)
+
+		return io.MultiWriter(w, r.Content)
+	}
+
+	var writers []tablewriter.TableWriter
+	for _, output := range outputs {
+		if output.Path == "-" {
+			t, err := tablewriter.New(output.Format, dest(output, os.Stdout))
+			if err != nil {
+				o.Abort()
+				return nil, err
+			}
+
+			writers = append(writers, t)
+			continue
+		}
+
+		dir := filepath.Dir(output.Path)
+
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			o.Abort()
+			return nil, err
+		}
+
+		tmp, err := ioutil.TempFile(dir, "."+filepath.Base(output.Path)+".")
+		if err != nil {
+			o.Abort()
+			return nil, err
+		}
+
+		o.pending = append(o.pending, pendingFile{tmp: tmp, path: output.Path})
+
+		t, err := tablewriter.New(output.Format, dest(output, tmp))
+		if err != nil {
+			o.Abort()
+			return nil, err
+		}
+
+		writers = append(writers, t)
+	}
+
+	o.TableWriter = tablewriter.NewMultiTableWriter(writers...)
+
+	return o, nil
+}
+
+// Commit moves every rendered file into place.
+func (o *Outputs) Commit() error {
+	for i, p := range o.pending {
+		err := p.tmp.Close()
+		if err == nil {
+			err = os.Chmod(p.tmp.Name(), 0644)
+		}
+
+		if err == nil {
+			err = os.Rename(p.tmp.Name(), p.path)
+		}
+
+		if err != nil {
+			o.pending = o.pending[i:]
+			o.Abort()
+			return err
+		}
+	}
+
+	o.pending = nil
+
+	return nil
+}
+
+// Abort discards every file that has not been committed.
+func (o *Outputs) Abort() {
+	for _, p := range o.pending {
+		p.tmp.Close()
+		os.Remove(p.tmp.Name())
+	}
+
+	o.pending = nil
+}
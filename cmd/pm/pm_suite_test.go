@@ -0,0 +1,13 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPm(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pm Suite")
+}
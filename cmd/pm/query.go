@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/query"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+type QueryCommand struct {
+	GroupBy string `long:"group-by" choice:"user" choice:"repository" choice:"type" choice:"month" description:"Count matching activity per user, repository, type or month instead of listing it"`
+
+	Args struct {
+		Expression []string `positional-arg-name:"expression" description:"Filter expression, e.g. type:issue-comments repo:cli date>=2017-05-01"`
+	} `positional-args:"yes"`
+}
+
+func (command *QueryCommand) Execute(argv []string) error {
+	if PM.Store == "" {
+		return errors.New("pm query reads from a local store; give one with --store")
+	}
+
+	expr, err := query.Parse(strings.Join(command.Args.Expression, " "))
+	if err != nil {
+		return fmt.Errorf("invalid expression: %s", err)
+	}
+
+	return PM.Run("query", func(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+		c, ok := crawler.(*store.Crawler)
+		if !ok {
+			return errors.New("pm query reads from a local store, not --from-snapshot")
+		}
+
+		logger.Debug("running query")
+		matches, err := RunQuery(ctx, c, PM.GitHub.OrganizationNames, expr)
+		if err != nil {
+			return err
+		}
+
+		if command.GroupBy != "" {
+			return QueryAggregateReport(ctx, t, command.GroupBy, matches)
+		}
+
+		return QueryReport(ctx, t, matches)
+	})
+}
+
+// QueryMatch is one stored activity matching a query.
+type QueryMatch struct {
+	Event   query.Event
+	HTMLURL string
+}
+
+// queryPayload holds the fields of any stored resource that a query needs.
+type queryPayload struct {
+	HTMLURL        string         `json:"html_url"`
+	Labels         []github.Label `json:"labels"`
+	IssueURL       string         `json:"issue_url"`
+	PullRequestURL string         `json:"pull_request_url"`
+	Issue          *github.Issue  `json:"issue"`
+}
+
+// RunQuery returns the stored activity of the organizations matching expr,
+// oldest first.
+func RunQuery(ctx context.Context, c *store.Crawler, orgs []string, expr query.Expr) ([]QueryMatch, error) {
+	issueLabels := map[string][]string{}
+	if query.References(expr, "label") {
+		err := c.Each(orgs, store.Query{Kind: store.KindIssue}, func(e store.Entry, payload []byte) error {
+			var issue github.Issue
+			err := json.Unmarshal(payload, &issue)
+			if err != nil {
+				return err
+			}
+
+			issueLabels[issue.GetURL()] = labelNames(issue.Labels)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	members := map[string]bool{}
+	if query.References(expr, "affiliation") {
+		for _, org := range orgs {
+			users, err := c.Store.Members(org)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, u := range users {
+				members[u.GetLogin()] = true
+			}
+		}
+	}
+
+	var matches []QueryMatch
+
+	err := c.Each(orgs, query.Narrow(expr), func(e store.Entry, payload []byte) error {
+		var p queryPayload
+		err := json.Unmarshal(payload, &p)
+		if err != nil {
+			return err
+		}
+
+		m := QueryMatch{
+			Event: query.Event{
+				Kind:       e.Kind,
+				Repository: e.Repository,
+				User:       e.User,
+				CreatedAt:  e.CreatedAt,
+				Member:     members[e.User],
+			},
+			HTMLURL: p.HTMLURL,
+		}
+
+		switch e.Kind {
+		case store.KindIssue:
+			m.Event.Labels = labelNames(p.Labels)
+		case store.KindIssueComment:
+			m.Event.Labels = issueLabels[p.IssueURL]
+		case store.KindIssueEvent:
+			if p.Issue != nil {
+				m.Event.Labels = labelNames(p.Issue.Labels)
+				m.HTMLURL = p.Issue.GetHTMLURL()
+			}
+		case store.KindReview:
+			m.Event.Labels = issueLabels[strings.Replace(p.PullRequestURL, "/pulls/", "/issues/", 1)]
+		}
+
+		if expr.Match(m.Event) {
+			matches = append(matches, m)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Event.CreatedAt.Before(matches[j].Event.CreatedAt)
+	})
+
+	return matches, nil
+}
+
+func labelNames(labels []github.Label) []string {
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.GetName())
+	}
+
+	return names
+}
+
+// QueryReport lists every match, oldest first.
+func QueryReport(ctx context.Context, t tablewriter.TableWriter, matches []QueryMatch) error {
+	t.SetHeader([]string{"Type", "Repository", "Github User", "Created At", "URL"})
+
+	for _, m := range matches {
+		t.Append([]string{
+			m.Event.Kind,
+			m.Event.Repository,
+			m.Event.User,
+			m.Event.CreatedAt.Format("2006-01-02"),
+			m.HTMLURL,
+		})
+	}
+
+	return t.Render()
+}
+
+// QueryGroup counts the matches sharing a user, repository, type or month.
+type QueryGroup struct {
+	Key          string
+	Count        int
+	Users        map[string]bool
+	Repositories map[string]bool
+	First        time.Time
+	Last         time.Time
+}
+
+// QueryAggregateReport counts matches per group, largest first.
+func QueryAggregateReport(ctx context.Context, t tablewriter.TableWriter, groupBy string, matches []QueryMatch) error {
+	groups := map[string]*QueryGroup{}
+
+	for _, m := range matches {
+		var key string
+		switch groupBy {
+		case "user":
+			key = m.Event.User
+		case "repository":
+			key = m.Event.Repository
+		case "type":
+			key = m.Event.Kind
+		case "month":
+			key = m.Event.CreatedAt.Format(cohortMonthFormat)
+		}
+
+		g, found := groups[key]
+		if !found {
+			g = &QueryGroup{
+				Key:          key,
+				Users:        map[string]bool{},
+				Repositories: map[string]bool{},
+				First:        m.Event.CreatedAt,
+			}
+
+			groups[key] = g
+		}
+
+		g.Count++
+		g.Users[m.Event.User] = true
+		g.Repositories[m.Event.Repository] = true
+		g.Last = m.Event.CreatedAt
+	}
+
+	var sorted []*QueryGroup
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if groupBy == "month" {
+			return sorted[i].Key < sorted[j].Key
+		}
+
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+
+		return sorted[i].Key < sorted[j].Key
+	})
+
+	keyHeaders := map[string]string{
+		"user":       "Github User",
+		"repository": "Repository",
+		"type":       "Type",
+		"month":      "Month",
+	}
+
+	t.SetHeader([]string{keyHeaders[groupBy], "Matches", "Users", "Repositories", "First", "Last"})
+
+	for _, g := range sorted {
+		t.Append([]string{
+			g.Key,
+			fmt.Sprintf("%d", g.Count),
+			fmt.Sprintf("%d", len(g.Users)),
+			fmt.Sprintf("%d", len(g.Repositories)),
+			g.First.Format("2006-01-02"),
+			g.Last.Format("2006-01-02"),
+		})
+	}
+
+	return t.Render()
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+type ReleasesCommand struct {
+	Options ReleasesOptions `group:"Releases Report"`
+}
+
+func (command *ReleasesCommand) Execute(argv []string) error {
+	return PM.Run("releases", command.Report)
+}
+
+// Report renders the releases report from the crawler's releases, issues and
+// issue events.
+func (command *ReleasesCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	orgs := PM.GitHub.OrganizationNames
+
+	logger.Debug("gathering releases")
+	releases, err := crawler.ReleasesForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering issues")
+	issues, err := crawler.IssuesUpdatedSinceForOrganizations(ctx, orgs, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering issue events")
+	events, err := crawler.AllIssueEventsForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return ReleasesReport(ctx, t, command.Options, releases, issues, events)
+}
+
+type ReleasesOptions struct {
+	View        string `long:"view"        default:"releases" choice:"releases" choice:"assets" choice:"credits" description:"Show downloads and contributors per release, downloads per release asset, or the users credited in each release"`
+	Prereleases bool   `long:"prereleases"                                                                           description:"Include prereleases, which are otherwise neither listed nor credited"`
+}
+
+// unreleased is the release work done since a repository's latest release
+// is credited to.
+const unreleased = "unreleased"
+
+// ReleaseActivity is a release and the users credited in it.
+type ReleaseActivity struct {
+	Repository string
+
+	// Release is nil for the work done since the latest release.
+	Release *gh.Release
+
+	// SincePrevious is the time since the repository's previous release, or
+	// zero for its first one.
+	SincePrevious time.Duration
+
+	Credits []*ReleaseCredit
+}
+
+// Name returns the release's tag, or "unreleased".
+func (r ReleaseActivity) Name() string {
+	if r.Release == nil {
+		return unreleased
+	}
+
+	return r.Release.Release.GetTagName()
+}
+
+// ReleaseCredit is a user whose merged pull requests or closed issues landed
+// in a release.
+type ReleaseCredit struct {
+	Login        string
+	PullRequests int
+	Issues       int
+}
+
+// ReleasesReport renders the view of the releases (keyed by repository)
+// chosen in the options, newest release first.
+func ReleasesReport(ctx context.Context, t tablewriter.TableWriter, options ReleasesOptions, releases map[string][]*gh.Release, issues []*github.Issue, events []*github.IssueEvent) error {
+	measured := MeasureReleases(options, releases, issues, events)
+
+	switch options.View {
+	case "assets":
+		return renderReleaseAssets(t, measured)
+	case "credits":
+		return renderReleaseCredits(t, measured)
+	default:
+		return renderReleases(t, measured)
+	}
+}
+
+func renderReleases(t tablewriter.TableWriter, measured []ReleaseActivity) error {
+	t.SetHeader([]string{"Repository", "Release", "Published", "Prerelease", "Days Since Previous", "Assets", "Downloads", "New Downloads", "Contributors"})
+
+	var downloads, fresh int
+	freshKnown := false
+
+	for _, r := range measured {
+		if r.Release == nil {
+			continue
+		}
+
+		sincePrevious := ""
+		if r.SincePrevious != 0 {
+			sincePrevious = fmt.Sprintf("%d", int(r.SincePrevious.Hours()/24))
+		}
+
+		newDownloads := ""
+		if r.Release.Previous != nil {
+			n := r.Release.Downloads() - r.Release.Previous.Downloads
+			newDownloads = fmt.Sprintf("%d", n)
+			fresh += n
+			freshKnown = true
+		}
+
+		t.Append([]string{
+			r.Repository,
+			r.Name(),
+			r.Release.Release.GetPublishedAt().Format("2006-01-02"),
+			yesNo(r.Release.Release.GetPrerelease()),
+			sincePrevious,
+			fmt.Sprintf("%d", len(r.Release.Release.Assets)),
+			fmt.Sprintf("%d", r.Release.Downloads()),
+			newDownloads,
+			fmt.Sprintf("%d", len(r.Credits)),
+		})
+
+		downloads += r.Release.Downloads()
+	}
+
+	totalFresh := ""
+	if freshKnown {
+		totalFresh = fmt.Sprintf("%d", fresh)
+	}
+
+	t.SetFooter([]string{"Total", "", "", "", "", "", fmt.Sprintf("%d", downloads), totalFresh, ""})
+
+	return t.Render()
+}
+
+func renderReleaseAssets(t tablewriter.TableWriter, measured []ReleaseActivity) error {
+	t.SetHeader([]string{"Repository", "Release", "Asset", "Uploaded", "Downloads"})
+
+	for _, r := range measured {
+		if r.Release == nil {
+			continue
+		}
+
+		for _, asset := range r.Release.Release.Assets {
+			t.Append([]string{
+				r.Repository,
+				r.Name(),
+				asset.GetName(),
+				asset.GetCreatedAt().Format("2006-01-02"),
+				fmt.Sprintf("%d", asset.GetDownloadCount()),
+			})
+		}
+	}
+
+	return t.Render()
+}
+
+func renderReleaseCredits(t tablewriter.TableWriter, measured []ReleaseActivity) error {
+	t.SetHeader([]string{"Repository", "Release", "Github User", "Merged Pull Requests", "Closed Issues"})
+
+	for _, r := range measured {
+		for _, credit := range r.Credits {
+			t.Append([]string{
+				r.Repository,
+				r.Name(),
+				credit.Login,
+				fmt.Sprintf("%d", credit.PullRequests),
+				fmt.Sprintf("%d", credit.Issues),
+			})
+		}
+	}
+
+	return t.Render()
+}
+
+// MeasureReleases credits every merged pull request and closed issue to the
+// first release published after it was merged or closed, or to the
+// repository's unreleased work if there is none, and works out the time
+// between releases. The result is ordered by repository, newest release
+// first, with each repository's unreleased work (if any) ahead of its
+// releases.
+func MeasureReleases(options ReleasesOptions, releases map[string][]*gh.Release, issues []*github.Issue, events []*github.IssueEvent) []ReleaseActivity {
+	merged := map[string]time.Time{}
+	for _, e := range events {
+		if e.GetEvent() == "merged" && e.Issue != nil {
+			merged[e.Issue.GetURL()] = e.GetCreatedAt()
+		}
+	}
+
+	var repos []string
+	measured := map[string][]*ReleaseActivity{}
+	for name, repoReleases := range releases {
+		repos = append(repos, name)
+
+		var previous *gh.Release
+		for _, release := range repoReleases {
+			if release.Release.GetPrerelease() && !options.Prereleases {
+				continue
+			}
+
+			r := &ReleaseActivity{Repository: name, Release: release}
+			if previous != nil {
+				r.SincePrevious = release.Release.GetPublishedAt().Sub(previous.Release.GetPublishedAt().Time)
+			}
+
+			measured[name] = append(measured[name], r)
+			previous = release
+		}
+
+		measured[name] = append(measured[name], &ReleaseActivity{Repository: name})
+	}
+
+	credits := map[*ReleaseActivity]map[string]*ReleaseCredit{}
+	credit := func(repo string, at time.Time, user *github.User) *ReleaseCredit {
+		candidates, found := measured[repo]
+		if !found || user == nil {
+			return nil
+		}
+
+		r := candidates[len(candidates)-1]
+		for _, c := range candidates[:len(candidates)-1] {
+			if !c.Release.Release.GetPublishedAt().Before(at) {
+				r = c
+				break
+			}
+		}
+
+		if credits[r] == nil {
+			credits[r] = map[string]*ReleaseCredit{}
+		}
+
+		login := user.GetLogin()
+		if credits[r][login] == nil {
+			credits[r][login] = &ReleaseCredit{Login: login}
+		}
+
+		return credits[r][login]
+	}
+
+	for _, i := range issues {
+		repo := gh.IssueRepository(i)
+
+		if i.PullRequestLinks != nil {
+			if at, found := merged[i.GetURL()]; found {
+				if c := credit(repo, at, i.User); c != nil {
+					c.PullRequests++
+				}
+			}
+		} else if i.ClosedAt != nil {
+			if c := credit(repo, i.GetClosedAt(), i.User); c != nil {
+				c.Issues++
+			}
+		}
+	}
+
+	sort.Strings(repos)
+
+	var all []ReleaseActivity
+	for _, name := range repos {
+		candidates := measured[name]
+		for i := len(candidates) - 1; i >= 0; i-- {
+			r := candidates[i]
+
+			for _, c := range credits[r] {
+				r.Credits = append(r.Credits, c)
+			}
+
+			sort.Slice(r.Credits, func(a, b int) bool {
+				ca, cb := r.Credits[a], r.Credits[b]
+				if ca.PullRequests+ca.Issues != cb.PullRequests+cb.Issues {
+					return ca.PullRequests+ca.Issues > cb.PullRequests+cb.Issues
+				}
+
+				return ca.Login < cb.Login
+			})
+
+			if r.Release == nil && len(r.Credits) == 0 {
+				continue
+			}
+
+			all = append(all, *r)
+		}
+	}
+
+	return all
+}
@@ -0,0 +1,277 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden with the reports' current output")
+
+// now is when the reports are run, just after the last activity in the
+// fixtures.
+var now = time.Date(2017, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+type reporter interface {
+	Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error
+}
+
+var _ = Describe("Reports", func() {
+	var server *ghtest.Server
+
+	BeforeEach(func() {
+		server = ghtest.NewServer("../../lib/gh/ghtest/testdata/acme")
+		PM.GitHub.OrganizationNames = []string{"acme"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	renderFrom := func(crawler gh.Crawler, command reporter, format string) ([]byte, error) {
+		var buf bytes.Buffer
+
+		t, err := tablewriter.New(format, &buf)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = command.Report(context.Background(), lagertest.NewTestLogger("test"), crawler, t, now)
+		return buf.Bytes(), err
+	}
+
+	render := func(command reporter, format string) ([]byte, error) {
+		return renderFrom(server.GitHubClient(), command, format)
+	}
+
+	golden := func(name string, format string, actual []byte) {
+		path := filepath.Join("testdata", "golden", name+tablewriter.Extensions[format])
+
+		if *update {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(path, actual, 0644)).To(Succeed())
+		}
+
+		expected, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred(), "run go test with -update to create %s", path)
+		Expect(string(actual)).To(Equal(string(expected)), "run go test with -update to accept changes to %s", path)
+	}
+
+	reports := map[string]func() reporter{
+		"manifest": func() reporter {
+			return &ManifestCommand{GroupBy: "user"}
+		},
+		"manifest-by-repository": func() reporter {
+			return &ManifestCommand{GroupBy: "repository"}
+		},
+		"cohorts": func() reporter {
+			return &CohortsCommand{}
+		},
+		"matrix": func() reporter {
+			return &MatrixCommand{}
+		},
+		"lapsed": func() reporter {
+			return &LapsedCommand{Criteria: LapsedCriteria{Baseline: 2160 * time.Hour, Recent: 720 * time.Hour, Threshold: 5}}
+		},
+		"responsiveness": func() reporter {
+			return &ResponsivenessCommand{Options: ResponsivenessOptions{Window: 720 * time.Hour, SLA: 48 * time.Hour}}
+		},
+		"responsiveness-breaches": func() reporter {
+			return &ResponsivenessCommand{Options: ResponsivenessOptions{Window: 720 * time.Hour, SLA: 48 * time.Hour, Breaches: true, MembersOnly: true}}
+		},
+		"triage": func() reporter {
+			return &TriageCommand{Options: TriageOptions{StaleDays: 14, GroupBy: "repository"}}
+		},
+		"triage-by-label": func() reporter {
+			return &TriageCommand{Options: TriageOptions{StaleDays: 14, GroupBy: "label"}}
+		},
+		"stars": func() reporter {
+			return &StarsCommand{Options: StarsOptions{Window: 720 * time.Hour, Bucket: "week", View: "repository"}}
+		},
+		"stars-timeline": func() reporter {
+			return &StarsCommand{Options: StarsOptions{Window: 720 * time.Hour, Bucket: "week", View: "timeline"}}
+		},
+		"stars-new": func() reporter {
+			return &StarsCommand{Options: StarsOptions{Window: 720 * time.Hour, Bucket: "day", View: "new"}}
+		},
+		"stars-overlap": func() reporter {
+			return &StarsCommand{Options: StarsOptions{View: "overlap"}}
+		},
+		"forks": func() reporter {
+			return &ForksCommand{Options: ForksOptions{Active: 2160 * time.Hour}}
+		},
+		"forks-flagged": func() reporter {
+			return &ForksCommand{Options: ForksOptions{Active: 2160 * time.Hour, Flagged: true}}
+		},
+		"releases": func() reporter {
+			return &ReleasesCommand{Options: ReleasesOptions{View: "releases"}}
+		},
+		"releases-prereleases": func() reporter {
+			return &ReleasesCommand{Options: ReleasesOptions{View: "releases", Prereleases: true}}
+		},
+		"releases-assets": func() reporter {
+			return &ReleasesCommand{Options: ReleasesOptions{View: "assets"}}
+		},
+		"releases-credits": func() reporter {
+			return &ReleasesCommand{Options: ReleasesOptions{View: "credits"}}
+		},
+		"traffic": func() reporter {
+			return &TrafficCommand{Options: TrafficOptions{Window: 8760 * time.Hour, Bucket: "week", View: "repository", Top: 10}}
+		},
+		"traffic-timeline": func() reporter {
+			return &TrafficCommand{Options: TrafficOptions{Window: 8760 * time.Hour, Bucket: "day", View: "timeline", Top: 10}}
+		},
+		"traffic-referrers": func() reporter {
+			return &TrafficCommand{Options: TrafficOptions{Window: 8760 * time.Hour, Bucket: "week", View: "referrers", Top: 2}}
+		},
+		"traffic-paths": func() reporter {
+			return &TrafficCommand{Options: TrafficOptions{Window: 8760 * time.Hour, Bucket: "week", View: "paths", Top: 10}}
+		},
+	}
+
+	for name, command := range reports {
+		name, command := name, command
+
+		Describe(name, func() {
+			for _, format := range tablewriter.Formats {
+				format := format
+
+				It("renders "+format+" matching the golden file", func() {
+					actual, err := render(command(), format)
+					Expect(err).NotTo(HaveOccurred())
+
+					golden(name, format, actual)
+				})
+			}
+		})
+	}
+
+	DescribeTable("rendering the same report when every page holds a single item",
+		func(name string) {
+			server.PageSize = 1
+
+			actual, err := render(reports[name](), "csv")
+			Expect(err).NotTo(HaveOccurred())
+
+			golden(name, "csv", actual)
+		},
+		Entry("manifest", "manifest"),
+		Entry("triage", "triage"),
+		Entry("responsiveness", "responsiveness"),
+	)
+
+	Describe("reading the same activity from a snapshot or a store", func() {
+		var (
+			fromSnapshot gh.Crawler
+			fromStore    gh.Crawler
+
+			dir string
+			db  *store.Store
+		)
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			logger := lagertest.NewTestLogger("test")
+
+			s := snapshot.New(PM.GitHub.OrganizationNames, now)
+			Expect(snapshot.Fetch(ctx, logger, server.GitHubClient(), PM.GitHub.OrganizationNames, s)).To(Succeed())
+			fromSnapshot = snapshot.NewCrawler(s)
+
+			var err error
+			dir, err = ioutil.TempDir("", "pm-store")
+			Expect(err).NotTo(HaveOccurred())
+
+			db, err = store.Open(filepath.Join(dir, "pm.db"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(snapshot.Fetch(ctx, logger, server.GitHubClient(), PM.GitHub.OrganizationNames, db)).To(Succeed())
+			fromStore = store.NewCrawler(db)
+		})
+
+		AfterEach(func() {
+			db.Close()
+			os.RemoveAll(dir)
+		})
+
+		for name, command := range reports {
+			name, command := name, command
+
+			It("renders "+name+" as the live crawl does", func() {
+				live, err := render(command(), "csv")
+				Expect(err).NotTo(HaveOccurred())
+
+				offline, err := renderFrom(fromSnapshot, command(), "csv")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(offline)).To(Equal(string(live)), "from the snapshot")
+
+				stored, err := renderFrom(fromStore, command(), "csv")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(stored)).To(Equal(string(live)), "from the store")
+			})
+		}
+	})
+
+	It("credits activity by deleted users to ghost and skips activity without a user", func() {
+		events, err := server.GitHubClient().Events(context.Background(), PM.GitHub.OrganizationNames)
+		Expect(err).NotTo(HaveOccurred())
+
+		var comments, anonymous int
+		for _, e := range events {
+			if e.Kind == activity.IssueComment {
+				comments++
+				if e.Actor == "" {
+					anonymous++
+				}
+			}
+		}
+		Expect(anonymous).To(Equal(1), "the fixtures hold one comment without a user")
+
+		actual, err := render(reports["manifest"](), "csv")
+		Expect(err).NotTo(HaveOccurred())
+
+		rows, err := csv.NewReader(bytes.NewReader(actual)).ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rows[0][2]).To(Equal("Issue Comments"))
+
+		credited := 0
+		byUser := map[string][]string{}
+		for _, row := range rows[1:] {
+			byUser[row[0]] = row
+
+			count, err := strconv.Atoi(row[2])
+			Expect(err).NotTo(HaveOccurred())
+			credited += count
+		}
+
+		Expect(byUser).To(HaveLen(6))
+		Expect(byUser).NotTo(HaveKey(""))
+		Expect(byUser["ghost"]).To(Equal([]string{"ghost", "0", "1", "0", "0", "0", "0", "0", "0", "0", "1"}))
+		Expect(credited).To(Equal(comments - anonymous))
+	})
+
+	It("fails with the rate limit error once the quota runs out mid-crawl", func() {
+		server.RateLimit = 3
+
+		_, err := render(reports["manifest"](), "csv")
+		Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+	})
+})
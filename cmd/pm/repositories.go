@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+)
+
+// RepositoryReport is the manifest grouped by repository rather than by user.
+func RepositoryReport(ctx context.Context, t tablewriter.TableWriter, events []activity.Event) error {
+	r := CatalogRepositories(events)
+
+	header := []string{"Repository"}
+	for _, c := range manifestColumns {
+		header = append(header, c.header)
+	}
+
+	t.SetHeader(append(header, "Unique Contributors"))
+
+	for _, name := range r.Names() {
+		repo := r[name]
+
+		row := []string{name}
+		for _, c := range manifestColumns {
+			row = append(row, fmt.Sprintf("%d", repo.Count(c.kind)))
+		}
+
+		t.Append(append(row, fmt.Sprintf("%d", len(repo.Contributors))))
+	}
+
+	return t.Render()
+}
+
+type MatrixCommand struct{}
+
+func (command *MatrixCommand) Execute(argv []string) error {
+	return PM.Run("matrix", command.Report)
+}
+
+// Report renders the matrix report from the crawler's activity.
+func (command *MatrixCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	events, err := PM.GatherActivity(ctx, logger, crawler)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return MatrixReport(ctx, t, events)
+}
+
+// MatrixReport renders one row per contributing user and one column per
+// repository, each cell counting the user's contributions to that repository:
+// the issues and pull requests they opened, their comments and everything
+// they did in the organizations' event streams except forking and starring,
+// such as pushes, closed issues, wiki edits and releases. The footer holds
+// each repository's unique contributor count.
+func MatrixReport(ctx context.Context, t tablewriter.TableWriter, events []activity.Event) error {
+	r := CatalogRepositories(events)
+	u := CatalogUsers(events)
+
+	names := r.Names()
+
+	t.SetHeader(append([]string{"Github User"}, names...))
+
+	var logins []string
+	for login, user := range u {
+		if user.Contributions() > 0 {
+			logins = append(logins, login)
+		}
+	}
+	sort.Strings(logins)
+
+	for _, login := range logins {
+		row := []string{login}
+		for _, name := range names {
+			row = append(row, fmt.Sprintf("%d", r[name].Contributors[login]))
+		}
+
+		t.Append(row)
+	}
+
+	footer := []string{"Unique Contributors"}
+	for _, name := range names {
+		footer = append(footer, fmt.Sprintf("%d", len(r[name].Contributors)))
+	}
+
+	t.SetFooter(footer)
+
+	return t.Render()
+}
+
+type RepositoryList map[string]*RepositoryActivity
+
+func NewRepositoryList() RepositoryList {
+	return make(map[string]*RepositoryActivity)
+}
+
+func CatalogRepositories(events []activity.Event) RepositoryList {
+	r := NewRepositoryList()
+
+	for _, e := range events {
+		r.repository(e.Repository).Add(e)
+	}
+
+	return r
+}
+
+// Names returns the repository names in alphabetical order.
+func (r RepositoryList) Names() []string {
+	var names []string
+	for name := range r {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (r RepositoryList) repository(name string) *RepositoryActivity {
+	repo, exists := r[name]
+	if !exists {
+		repo = &RepositoryActivity{
+			Name:         name,
+			Contributors: map[string]int{},
+		}
+		r[name] = repo
+	}
+
+	return repo
+}
+
+type RepositoryActivity struct {
+	Name   string
+	Events []activity.Event
+
+	// Contributors maps each user's login to the number of contributions,
+	// such as issues, comments and pushes, they made to the repository.
+	Contributors map[string]int
+}
+
+// Add records an event in the repository and credits contributions to their
+// actor, unless the source did not say who it was.
+func (r *RepositoryActivity) Add(e activity.Event) {
+	r.Events = append(r.Events, e)
+
+	if e.Actor != "" && e.Kind.IsContribution() {
+		r.Contributors[e.Actor]++
+	}
+}
+
+// Count returns how many of the repository's events are of the given kind.
+func (r *RepositoryActivity) Count(kind activity.Kind) int {
+	count := 0
+	for _, e := range r.Events {
+		if e.Kind == kind {
+			count++
+		}
+	}
+
+	return count
+}
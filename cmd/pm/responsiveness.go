@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+type ResponsivenessCommand struct {
+	Options ResponsivenessOptions `group:"Responsiveness Report"`
+}
+
+func (command *ResponsivenessCommand) Execute(argv []string) error {
+	return PM.Run("responsiveness", command.Report)
+}
+
+// Report renders the responsiveness report from the crawler's activity.
+func (command *ResponsivenessCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	orgs := PM.GitHub.OrganizationNames
+
+	logger.Debug("gathering issues")
+	issues, err := crawler.IssuesUpdatedSinceForOrganizations(ctx, orgs, now.Add(-command.Options.Window))
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering issue comments")
+	issueComments, err := crawler.AllIssueCommentsForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering issue events")
+	issueEvents, err := crawler.AllIssueEventsForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	var members []*github.User
+	if command.Options.MembersOnly {
+		logger.Debug("gathering organization members")
+		members, err = crawler.AllMembersForOrganizations(ctx, orgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("calculating report")
+	return ResponsivenessReport(ctx, t, now, command.Options, issues, issueComments, issueEvents, members)
+}
+
+type ResponsivenessOptions struct {
+	Window      time.Duration `long:"window"       default:"720h" description:"Only consider issues opened within this long before now"`
+	MembersOnly bool          `long:"members-only"                description:"Only count comments by organization members as responses"`
+	SLA         time.Duration `long:"sla"          default:"48h"  description:"Longest acceptable time to first response"`
+	Breaches    bool          `long:"breaches"                    description:"List the issues that breached the SLA instead of per-repository aggregates"`
+}
+
+// IssueResponsiveness records how long it took for an issue to receive its
+// first response, its first label and to be closed. A nil duration means the
+// issue has not (yet) reached that point; a zero one means it did so as it
+// was opened, e.g. when it was labeled on creation.
+type IssueResponsiveness struct {
+	Issue         *github.Issue
+	Repository    string
+	FirstResponse *time.Duration
+	FirstLabel    *time.Duration
+	Close         *time.Duration
+
+	// Breached is set when the first response took longer than the SLA, or,
+	// without one, when the issue waited longer than the SLA before it was
+	// closed or, while still open, until now.
+	Breached bool
+}
+
+// ResponsivenessReport renders, per repository, the median and 90th percentile
+// time to first response, first label and close for issues opened within the
+// window. With Breaches set it lists the individual issues that breached the
+// SLA instead.
+func ResponsivenessReport(ctx context.Context, t tablewriter.TableWriter, now time.Time, options ResponsivenessOptions, issues []*github.Issue, issueComments []*github.IssueComment, issueEvents []*github.IssueEvent, members []*github.User) error {
+	measured := MeasureResponsiveness(now, options, issues, issueComments, issueEvents, members)
+
+	if options.Breaches {
+		return renderSLABreaches(t, measured)
+	}
+
+	byRepository := map[string][]IssueResponsiveness{}
+	var names []string
+	for _, m := range measured {
+		if _, exists := byRepository[m.Repository]; !exists {
+			names = append(names, m.Repository)
+		}
+
+		byRepository[m.Repository] = append(byRepository[m.Repository], m)
+	}
+
+	sort.Strings(names)
+
+	t.SetHeader([]string{
+		"Repository",
+		"Issues",
+		"Responded",
+		"Median Hours To First Response",
+		"P90 Hours To First Response",
+		"Labeled",
+		"Median Hours To First Label",
+		"P90 Hours To First Label",
+		"Closed",
+		"Median Hours To Close",
+		"P90 Hours To Close",
+		"SLA Breaches",
+	})
+
+	for _, name := range names {
+		var responses, labels, closes []time.Duration
+		breaches := 0
+
+		for _, m := range byRepository[name] {
+			if m.FirstResponse != nil {
+				responses = append(responses, *m.FirstResponse)
+			}
+
+			if m.FirstLabel != nil {
+				labels = append(labels, *m.FirstLabel)
+			}
+
+			if m.Close != nil {
+				closes = append(closes, *m.Close)
+			}
+
+			if m.Breached {
+				breaches++
+			}
+		}
+
+		t.Append([]string{
+			name,
+			fmt.Sprintf("%d", len(byRepository[name])),
+			fmt.Sprintf("%d", len(responses)),
+			formatHours(percentile(responses, 0.5)),
+			formatHours(percentile(responses, 0.9)),
+			fmt.Sprintf("%d", len(labels)),
+			formatHours(percentile(labels, 0.5)),
+			formatHours(percentile(labels, 0.9)),
+			fmt.Sprintf("%d", len(closes)),
+			formatHours(percentile(closes, 0.5)),
+			formatHours(percentile(closes, 0.9)),
+			fmt.Sprintf("%d", breaches),
+		})
+	}
+
+	return t.Render()
+}
+
+func renderSLABreaches(t tablewriter.TableWriter, measured []IssueResponsiveness) error {
+	t.SetHeader([]string{"Repository", "Issue", "Title", "Opened", "Hours To First Response", "URL"})
+
+	for _, m := range measured {
+		if !m.Breached {
+			continue
+		}
+
+		t.Append([]string{
+			m.Repository,
+			fmt.Sprintf("#%d", m.Issue.GetNumber()),
+			m.Issue.GetTitle(),
+			m.Issue.GetCreatedAt().Format("2006-01-02"),
+			formatHours(m.FirstResponse),
+			m.Issue.GetHTMLURL(),
+		})
+	}
+
+	return t.Render()
+}
+
+// MeasureResponsiveness computes the responsiveness of every issue (excluding
+// pull requests) opened within the window, ordered by repository and number.
+func MeasureResponsiveness(now time.Time, options ResponsivenessOptions, issues []*github.Issue, issueComments []*github.IssueComment, issueEvents []*github.IssueEvent, members []*github.User) []IssueResponsiveness {
+	memberLogins := map[string]bool{}
+	for _, m := range members {
+		memberLogins[m.GetLogin()] = true
+	}
+
+	commentsByIssue := map[string][]*github.IssueComment{}
+	for _, c := range issueComments {
+		commentsByIssue[c.GetIssueURL()] = append(commentsByIssue[c.GetIssueURL()], c)
+	}
+
+	firstLabeled := map[string]time.Time{}
+	for _, e := range issueEvents {
+		if e.GetEvent() != "labeled" || e.Issue == nil {
+			continue
+		}
+
+		url := e.Issue.GetURL()
+		if first, exists := firstLabeled[url]; !exists || e.GetCreatedAt().Before(first) {
+			firstLabeled[url] = e.GetCreatedAt()
+		}
+	}
+
+	windowStart := now.Add(-options.Window)
+
+	var measured []IssueResponsiveness
+
+	for _, i := range issues {
+		if i.PullRequestLinks != nil || i.GetCreatedAt().Before(windowStart) {
+			continue
+		}
+
+		created := i.GetCreatedAt()
+		m := IssueResponsiveness{
+			Issue:      i,
+			Repository: gh.IssueRepository(i),
+		}
+
+		for _, c := range commentsByIssue[i.GetURL()] {
+			login := c.User.GetLogin()
+			if login == i.User.GetLogin() {
+				continue
+			}
+
+			if options.MembersOnly && !memberLogins[login] {
+				continue
+			}
+
+			response := c.GetCreatedAt().Sub(created)
+			if m.FirstResponse == nil || response < *m.FirstResponse {
+				m.FirstResponse = &response
+			}
+		}
+
+		if labeled, exists := firstLabeled[i.GetURL()]; exists {
+			label := labeled.Sub(created)
+			m.FirstLabel = &label
+		}
+
+		if i.ClosedAt != nil {
+			closed := i.ClosedAt.Sub(created)
+			m.Close = &closed
+		}
+
+		switch {
+		case m.FirstResponse != nil:
+			m.Breached = *m.FirstResponse > options.SLA
+		case m.Close != nil:
+			m.Breached = *m.Close > options.SLA
+		default:
+			m.Breached = now.Sub(created) > options.SLA
+		}
+
+		measured = append(measured, m)
+	}
+
+	sort.Slice(measured, func(i, j int) bool {
+		if measured[i].Repository != measured[j].Repository {
+			return measured[i].Repository < measured[j].Repository
+		}
+
+		return measured[i].Issue.GetNumber() < measured[j].Issue.GetNumber()
+	})
+
+	return measured
+}
+
+// percentile returns the nearest-rank percentile of the given durations, or
+// nil if there are none.
+func percentile(durations []time.Duration, p float64) *time.Duration {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return &sorted[rank]
+}
+
+func formatHours(d *time.Duration) string {
+	if d == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%.1f", d.Hours())
+}
@@ -0,0 +1,84 @@
+package main_test
+
+import (
+	"time"
+
+	"github.com/google/go-github/github"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MeasureResponsiveness", func() {
+	var (
+		created time.Time
+		issue   *github.Issue
+		options ResponsivenessOptions
+	)
+
+	BeforeEach(func() {
+		created = time.Date(2017, time.June, 20, 9, 0, 0, 0, time.UTC)
+		issue = &github.Issue{
+			Number:    github.Int(1),
+			User:      &github.User{Login: github.String("carol")},
+			CreatedAt: &created,
+			URL:       github.String("https://api.github.com/repos/acme/widget/issues/1"),
+		}
+		options = ResponsivenessOptions{Window: 720 * time.Hour, SLA: 48 * time.Hour}
+	})
+
+	comment := func(login string, at time.Time) *github.IssueComment {
+		return &github.IssueComment{
+			User:      &github.User{Login: github.String(login)},
+			CreatedAt: &at,
+			IssueURL:  issue.URL,
+		}
+	}
+
+	It("counts a label and a response given as the issue was opened", func() {
+		labeled := &github.IssueEvent{
+			Event:     github.String("labeled"),
+			CreatedAt: &created,
+			Issue:     issue,
+		}
+
+		measured := MeasureResponsiveness(now, options, []*github.Issue{issue},
+			[]*github.IssueComment{comment("alice", created), comment("bob", created.Add(time.Hour))},
+			[]*github.IssueEvent{labeled}, nil)
+
+		Expect(measured).To(HaveLen(1))
+		Expect(measured[0].FirstLabel).NotTo(BeNil())
+		Expect(*measured[0].FirstLabel).To(BeZero())
+		Expect(measured[0].FirstResponse).NotTo(BeNil())
+		Expect(*measured[0].FirstResponse).To(BeZero())
+		Expect(measured[0].Breached).To(BeFalse())
+	})
+
+	It("leaves what has not happened yet unset, breaching the SLA once it has passed", func() {
+		measured := MeasureResponsiveness(now, options, []*github.Issue{issue}, nil, nil, nil)
+
+		Expect(measured).To(HaveLen(1))
+		Expect(measured[0].FirstResponse).To(BeNil())
+		Expect(measured[0].FirstLabel).To(BeNil())
+		Expect(measured[0].Close).To(BeNil())
+		Expect(measured[0].Breached).To(BeTrue())
+	})
+
+	DescribeTable("judging an issue closed without a response on how long it waited to be closed",
+		func(open time.Duration, breached bool) {
+			closed := created.Add(open)
+			issue.ClosedAt = &closed
+			issue.State = github.String("closed")
+
+			measured := MeasureResponsiveness(now, options, []*github.Issue{issue}, nil, nil, nil)
+
+			Expect(measured).To(HaveLen(1))
+			Expect(measured[0].FirstResponse).To(BeNil())
+			Expect(measured[0].Breached).To(Equal(breached))
+		},
+		Entry("closed within the SLA", time.Hour, false),
+		Entry("closed after the SLA", 72*time.Hour, true),
+	)
+})
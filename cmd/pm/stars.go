@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+type StarsCommand struct {
+	Options StarsOptions `group:"Stars Report"`
+}
+
+func (command *StarsCommand) Execute(argv []string) error {
+	return PM.Run("stars", command.Report)
+}
+
+// Report renders the stars report from the crawler's stargazers and
+// watchers.
+func (command *StarsCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	orgs := PM.GitHub.OrganizationNames
+
+	logger.Debug("gathering stargazers")
+	stargazers, err := crawler.StargazersForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	var watchers map[string][]*github.User
+	if command.Options.View == "repository" {
+		logger.Debug("gathering watchers")
+		watchers, err = crawler.WatchersForOrganizations(ctx, orgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("calculating report")
+	return StarsReport(ctx, t, now, command.Options, stargazers, watchers)
+}
+
+type StarsOptions struct {
+	Window time.Duration `long:"window" default:"720h"       description:"Length of the trailing window whose star history is charted and whose stars count as new"`
+	Bucket string        `long:"bucket" default:"week"       choice:"day" choice:"week" description:"Period each point of the star history covers; weeks start on Monday"`
+	View   string        `long:"view"   default:"repository" choice:"repository" choice:"timeline" choice:"new" choice:"overlap" description:"Show stars and watchers per repository, stars per repository and period, the window's new stargazers, or users who starred several repositories"`
+}
+
+var bucketHeaders = map[string]string{
+	"day":  "Day",
+	"week": "Week",
+}
+
+// StarsReport renders the view of the stargazers (keyed by repository) chosen
+// in the options. Only the repository view uses the watchers.
+func StarsReport(ctx context.Context, t tablewriter.TableWriter, now time.Time, options StarsOptions, stargazers map[string][]*github.Stargazer, watchers map[string][]*github.User) error {
+	var repos []string
+	for name := range stargazers {
+		repos = append(repos, name)
+	}
+
+	sort.Strings(repos)
+
+	switch options.View {
+	case "timeline":
+		return renderStarTimeline(t, now, options, repos, stargazers)
+	case "new":
+		return renderNewStargazers(t, now, options, stargazers)
+	case "overlap":
+		return renderStarOverlap(t, stargazers)
+	default:
+		return renderStarsByRepository(t, now, options, repos, stargazers, watchers)
+	}
+}
+
+func renderStarsByRepository(t tablewriter.TableWriter, now time.Time, options StarsOptions, repos []string, stargazers map[string][]*github.Stargazer, watchers map[string][]*github.User) error {
+	t.SetHeader([]string{"Repository", "Stars", "Watchers", "New Stars", "Star History"})
+	t.SetSparkline(4)
+
+	var stars, watching, fresh int
+
+	for _, name := range repos {
+		history := StarHistory(stargazers[name], now, options)
+
+		var totals []string
+		recent := 0
+		for _, point := range history {
+			totals = append(totals, fmt.Sprintf("%d", point.Stars))
+			recent += point.New
+		}
+
+		t.Append([]string{
+			name,
+			fmt.Sprintf("%d", len(stargazers[name])),
+			fmt.Sprintf("%d", len(watchers[name])),
+			fmt.Sprintf("%d", recent),
+			strings.Join(totals, " "),
+		})
+
+		stars += len(stargazers[name])
+		watching += len(watchers[name])
+		fresh += recent
+	}
+
+	t.SetFooter([]string{"Total", fmt.Sprintf("%d", stars), fmt.Sprintf("%d", watching), fmt.Sprintf("%d", fresh), ""})
+
+	return t.Render()
+}
+
+func renderStarTimeline(t tablewriter.TableWriter, now time.Time, options StarsOptions, repos []string, stargazers map[string][]*github.Stargazer) error {
+	t.SetHeader([]string{"Repository", bucketHeaders[options.Bucket], "New Stars", "Stars"})
+
+	for _, name := range repos {
+		for _, point := range StarHistory(stargazers[name], now, options) {
+			t.Append([]string{
+				name,
+				point.Start.Format("2006-01-02"),
+				fmt.Sprintf("%d", point.New),
+				fmt.Sprintf("%d", point.Stars),
+			})
+		}
+	}
+
+	return t.Render()
+}
+
+// renderNewStargazers lists the stars given within the window, newest first.
+func renderNewStargazers(t tablewriter.TableWriter, now time.Time, options StarsOptions, stargazers map[string][]*github.Stargazer) error {
+	type star struct {
+		login      string
+		repository string
+		at         time.Time
+	}
+
+	windowStart := now.Add(-options.Window)
+
+	var stars []star
+	for name, gazers := range stargazers {
+		for _, s := range gazers {
+			at := s.GetStarredAt().Time
+			if s.User != nil && !at.Before(windowStart) && at.Before(now) {
+				stars = append(stars, star{login: s.User.GetLogin(), repository: name, at: at})
+			}
+		}
+	}
+
+	sort.Slice(stars, func(i, j int) bool {
+		if !stars[i].at.Equal(stars[j].at) {
+			return stars[i].at.After(stars[j].at)
+		}
+
+		if stars[i].repository != stars[j].repository {
+			return stars[i].repository < stars[j].repository
+		}
+
+		return stars[i].login < stars[j].login
+	})
+
+	t.SetHeader([]string{"Github User", "Repository", "Starred At"})
+
+	for _, s := range stars {
+		t.Append([]string{s.login, s.repository, s.at.Format("2006-01-02")})
+	}
+
+	return t.Render()
+}
+
+// renderStarOverlap lists the users who starred more than one repository,
+// those who starred the most first.
+func renderStarOverlap(t tablewriter.TableWriter, stargazers map[string][]*github.Stargazer) error {
+	starred := map[string][]string{}
+	for name, gazers := range stargazers {
+		for _, s := range gazers {
+			if s.User != nil {
+				starred[s.User.GetLogin()] = append(starred[s.User.GetLogin()], name)
+			}
+		}
+	}
+
+	var logins []string
+	for login, repos := range starred {
+		if len(repos) > 1 {
+			sort.Strings(repos)
+			logins = append(logins, login)
+		}
+	}
+
+	sort.Slice(logins, func(i, j int) bool {
+		ci, cj := len(starred[logins[i]]), len(starred[logins[j]])
+		if ci != cj {
+			return ci > cj
+		}
+
+		return logins[i] < logins[j]
+	})
+
+	t.SetHeader([]string{"Github User", "Repositories", "Starred Repositories"})
+
+	for _, login := range logins {
+		t.Append([]string{login, fmt.Sprintf("%d", len(starred[login])), strings.Join(starred[login], ", ")})
+	}
+
+	return t.Render()
+}
+
+// StarPoint is a repository's stars during one day or week.
+type StarPoint struct {
+	Start time.Time
+
+	// New is the number of stars given during the period, and Stars the
+	// number of stars the repository had at its end.
+	New   int
+	Stars int
+}
+
+// StarHistory counts a repository's stars in every day or week overlapping
+// the window, oldest first. Stars removed since are not counted, as GitHub
+// only lists current stargazers.
+func StarHistory(stargazers []*github.Stargazer, now time.Time, options StarsOptions) []StarPoint {
+	var history []StarPoint
+
+	for start := bucketStart(now.Add(-options.Window), options.Bucket); start.Before(now); start = nextBucket(start, options.Bucket) {
+		end := nextBucket(start, options.Bucket)
+
+		point := StarPoint{Start: start}
+		for _, s := range stargazers {
+			at := s.GetStarredAt().Time
+			if at.Before(end) {
+				point.Stars++
+
+				if !at.Before(start) {
+					point.New++
+				}
+			}
+		}
+
+		history = append(history, point)
+	}
+
+	return history
+}
+
+// bucketStart returns the start of the day or week holding t, in UTC.
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	if bucket == "week" {
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+
+	return day
+}
+
+func nextBucket(start time.Time, bucket string) time.Time {
+	if bucket == "week" {
+		return start.AddDate(0, 0, 7)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
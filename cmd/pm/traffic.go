@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+type TrafficCommand struct {
+	Options TrafficOptions `group:"Traffic Report"`
+
+	NoCollect bool `long:"no-collect" description:"With --store, report the stored traffic without crawling GitHub first"`
+}
+
+// Execute crawls the organizations' traffic into the store given with
+// --store, if any, and then reports it. GitHub keeps only 14 days of
+// traffic, so running pm traffic at least every two weeks keeps the stored
+// history complete.
+func (command *TrafficCommand) Execute(argv []string) error {
+	if PM.Store != "" && !command.NoCollect {
+		err := command.collect()
+		if err != nil {
+			return err
+		}
+	}
+
+	return PM.Run("traffic", command.Report)
+}
+
+func (command *TrafficCommand) collect() error {
+	ctx := context.Background()
+
+	logger, closeLog, err := PM.Logger("traffic")
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	backend, done, err := PM.GitHubBackend(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	s, err := store.Open(PM.Store)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	logger.Info("recording traffic into store", lager.Data{"path": PM.Store})
+
+	repos, err := backend.PublicRepositoriesForOrganizations(ctx, PM.GitHub.OrganizationNames)
+	if err != nil {
+		return err
+	}
+
+	progress := backend.ProgressReporter()
+	progress.Begin("traffic", len(repos))
+	defer progress.End()
+
+	for _, repo := range repos {
+		traffic, err := backend.TrafficForRepository(ctx, repo)
+		if err == nil && traffic != nil {
+			err = s.RecordTraffic(repo, traffic)
+		}
+
+		if err != nil {
+			logger.Error("failed", err)
+			return err
+		}
+
+		if traffic == nil {
+			logger.Info("skipping repository without visible traffic", lager.Data{"repository": repo.GetFullName()})
+		}
+
+		progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return nil
+}
+
+// Report renders the traffic report from the crawler's traffic.
+func (command *TrafficCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	logger.Debug("gathering traffic")
+	traffic, err := crawler.TrafficForOrganizations(ctx, PM.GitHub.OrganizationNames)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return TrafficReport(ctx, t, now, command.Options, traffic)
+}
+
+type TrafficOptions struct {
+	Window time.Duration `long:"window" default:"8760h"      description:"Length of the trailing window whose traffic is reported"`
+	Bucket string        `long:"bucket" default:"week"       choice:"day" choice:"week" description:"Period each point of the traffic history covers; weeks start on Monday"`
+	View   string        `long:"view"   default:"repository" choice:"repository" choice:"timeline" choice:"referrers" choice:"paths" description:"Show views and clones per repository, views and clones per repository and period, or the top referrers or paths"`
+	Top    int           `long:"top"    default:"10"         description:"Number of referrers or paths to list per repository"`
+}
+
+// TrafficPoint is a repository's traffic over one day or week. Uniques are
+// the daily unique visitors or cloners added up, so someone who came back
+// on several days counts once per day.
+type TrafficPoint struct {
+	Start        time.Time
+	Views        int
+	UniqueViews  int
+	Clones       int
+	UniqueClones int
+}
+
+// TrafficReport renders the view of the traffic (keyed by repository) chosen
+// in the options.
+func TrafficReport(ctx context.Context, t tablewriter.TableWriter, now time.Time, options TrafficOptions, traffic map[string]gh.TrafficHistory) error {
+	var repos []string
+	for name := range traffic {
+		repos = append(repos, name)
+	}
+
+	sort.Strings(repos)
+
+	switch options.View {
+	case "timeline":
+		return renderTrafficTimeline(t, now, options, repos, traffic)
+	case "referrers":
+		return renderTopReferrers(t, now, options, repos, traffic)
+	case "paths":
+		return renderTopPaths(t, now, options, repos, traffic)
+	default:
+		return renderTrafficByRepository(t, now, options, repos, traffic)
+	}
+}
+
+func renderTrafficByRepository(t tablewriter.TableWriter, now time.Time, options TrafficOptions, repos []string, traffic map[string]gh.TrafficHistory) error {
+	t.SetHeader([]string{"Repository", "Since", "Views", "Unique Views", "Clones", "Unique Clones", "View History"})
+	t.SetSparkline(6)
+
+	var total TrafficPoint
+
+	for _, name := range repos {
+		history := TrafficHistory(traffic[name], now, options)
+
+		var sum TrafficPoint
+		var views []string
+		for _, point := range history {
+			sum.add(point)
+			views = append(views, fmt.Sprintf("%d", point.Views))
+		}
+
+		since := ""
+		if len(history) > 0 {
+			since = history[0].Start.Format("2006-01-02")
+		}
+
+		t.Append([]string{
+			name,
+			since,
+			fmt.Sprintf("%d", sum.Views),
+			fmt.Sprintf("%d", sum.UniqueViews),
+			fmt.Sprintf("%d", sum.Clones),
+			fmt.Sprintf("%d", sum.UniqueClones),
+			strings.Join(views, " "),
+		})
+
+		total.add(sum)
+	}
+
+	t.SetFooter([]string{
+		"Total",
+		"",
+		fmt.Sprintf("%d", total.Views),
+		fmt.Sprintf("%d", total.UniqueViews),
+		fmt.Sprintf("%d", total.Clones),
+		fmt.Sprintf("%d", total.UniqueClones),
+		"",
+	})
+
+	return t.Render()
+}
+
+func renderTrafficTimeline(t tablewriter.TableWriter, now time.Time, options TrafficOptions, repos []string, traffic map[string]gh.TrafficHistory) error {
+	t.SetHeader([]string{"Repository", bucketHeaders[options.Bucket], "Views", "Unique Views", "Clones", "Unique Clones"})
+
+	for _, name := range repos {
+		for _, point := range TrafficHistory(traffic[name], now, options) {
+			t.Append([]string{
+				name,
+				point.Start.Format("2006-01-02"),
+				fmt.Sprintf("%d", point.Views),
+				fmt.Sprintf("%d", point.UniqueViews),
+				fmt.Sprintf("%d", point.Clones),
+				fmt.Sprintf("%d", point.UniqueClones),
+			})
+		}
+	}
+
+	return t.Render()
+}
+
+// trafficTotal is a referrer's or path's views over several crawls.
+type trafficTotal struct {
+	key     string
+	title   string
+	views   int
+	uniques int
+}
+
+func renderTopReferrers(t tablewriter.TableWriter, now time.Time, options TrafficOptions, repos []string, traffic map[string]gh.TrafficHistory) error {
+	t.SetHeader([]string{"Repository", "Referrer", "Views", "Unique Visitors"})
+
+	for _, name := range repos {
+		totals := map[string]*trafficTotal{}
+		for _, period := range trafficPeriods(traffic[name], now, options) {
+			for _, r := range period.Referrers {
+				total := totalFor(totals, r.GetReferrer())
+				total.views += r.GetCount()
+				total.uniques += r.GetUniques()
+			}
+		}
+
+		for _, total := range topTotals(totals, options.Top) {
+			t.Append([]string{name, total.key, fmt.Sprintf("%d", total.views), fmt.Sprintf("%d", total.uniques)})
+		}
+	}
+
+	return t.Render()
+}
+
+func renderTopPaths(t tablewriter.TableWriter, now time.Time, options TrafficOptions, repos []string, traffic map[string]gh.TrafficHistory) error {
+	t.SetHeader([]string{"Repository", "Path", "Title", "Views", "Unique Visitors"})
+
+	for _, name := range repos {
+		totals := map[string]*trafficTotal{}
+		for _, period := range trafficPeriods(traffic[name], now, options) {
+			for _, p := range period.Paths {
+				total := totalFor(totals, p.GetPath())
+				total.views += p.GetCount()
+				total.uniques += p.GetUniques()
+
+				if total.title == "" {
+					total.title = p.GetTitle()
+				}
+			}
+		}
+
+		for _, total := range topTotals(totals, options.Top) {
+			t.Append([]string{name, total.key, total.title, fmt.Sprintf("%d", total.views), fmt.Sprintf("%d", total.uniques)})
+		}
+	}
+
+	return t.Render()
+}
+
+// TrafficHistory adds up a repository's daily views and clones per day or
+// week, from the first recorded day within the window up to now. Periods
+// without any traffic are included, so the history can be charted as is.
+func TrafficHistory(history gh.TrafficHistory, now time.Time, options TrafficOptions) []TrafficPoint {
+	windowStart := now.Add(-options.Window)
+
+	points := map[time.Time]*TrafficPoint{}
+	first := time.Time{}
+
+	count := func(days []*github.TrafficData, add func(p *TrafficPoint, day *github.TrafficData)) {
+		for _, day := range days {
+			at := day.GetTimestamp().Time
+			if at.Before(windowStart) || !at.Before(now) {
+				continue
+			}
+
+			start := bucketStart(at, options.Bucket)
+			if points[start] == nil {
+				points[start] = &TrafficPoint{Start: start}
+			}
+
+			add(points[start], day)
+
+			if first.IsZero() || start.Before(first) {
+				first = start
+			}
+		}
+	}
+
+	count(history.Views(), func(p *TrafficPoint, day *github.TrafficData) {
+		p.Views += day.GetCount()
+		p.UniqueViews += day.GetUniques()
+	})
+
+	count(history.Clones(), func(p *TrafficPoint, day *github.TrafficData) {
+		p.Clones += day.GetCount()
+		p.UniqueClones += day.GetUniques()
+	})
+
+	if first.IsZero() {
+		return nil
+	}
+
+	var all []TrafficPoint
+	for start := first; start.Before(now); start = nextBucket(start, options.Bucket) {
+		point := TrafficPoint{Start: start}
+		if p, found := points[start]; found {
+			point = *p
+		}
+
+		all = append(all, point)
+	}
+
+	return all
+}
+
+func (p *TrafficPoint) add(other TrafficPoint) {
+	p.Views += other.Views
+	p.UniqueViews += other.UniqueViews
+	p.Clones += other.Clones
+	p.UniqueClones += other.UniqueClones
+}
+
+// trafficPeriods returns the non-overlapping crawls collected within the
+// window, whose referrers and paths can be added up.
+func trafficPeriods(history gh.TrafficHistory, now time.Time, options TrafficOptions) []*gh.Traffic {
+	windowStart := now.Add(-options.Window)
+
+	var within gh.TrafficHistory
+	for _, traffic := range history {
+		if !traffic.CollectedAt.Before(windowStart) {
+			within = append(within, traffic)
+		}
+	}
+
+	return within.Periods()
+}
+
+func totalFor(totals map[string]*trafficTotal, key string) *trafficTotal {
+	total, found := totals[key]
+	if !found {
+		total = &trafficTotal{key: key}
+		totals[key] = total
+	}
+
+	return total
+}
+
+// topTotals returns the n totals with the most views, most first.
+func topTotals(totals map[string]*trafficTotal, n int) []*trafficTotal {
+	var all []*trafficTotal
+	for _, total := range totals {
+		all = append(all, total)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].views != all[j].views {
+			return all[i].views > all[j].views
+		}
+
+		return all[i].key < all[j].key
+	})
+
+	if n > 0 && len(all) > n {
+		all = all[:n]
+	}
+
+	return all
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"github.com/google/go-github/github"
+)
+
+const noLabel = "(unlabeled)"
+
+type TriageCommand struct {
+	Options TriageOptions `group:"Triage Report"`
+}
+
+func (command *TriageCommand) Execute(argv []string) error {
+	return PM.Run("triage", command.Report)
+}
+
+// Report renders the triage report from the crawler's activity.
+func (command *TriageCommand) Report(ctx context.Context, logger lager.Logger, crawler gh.Crawler, t tablewriter.TableWriter, now time.Time) error {
+	orgs := PM.GitHub.OrganizationNames
+
+	logger.Debug("gathering issues")
+	issues, err := crawler.AllIssuesForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering issue comments")
+	issueComments, err := crawler.AllIssueCommentsForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("gathering organization members")
+	members, err := crawler.AllMembersForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("calculating report")
+	return TriageReport(ctx, t, now, command.Options, issues, issueComments, members)
+}
+
+type TriageOptions struct {
+	StaleDays int    `long:"stale-days" default:"14"                                                     description:"Flag open issues that have not been updated for this many days"`
+	GroupBy   string `long:"group-by"   default:"repository" choice:"repository" choice:"label" description:"Group the triage queue by repository or by label"`
+}
+
+// TriageItem is an open issue that needs attention from a maintainer.
+type TriageItem struct {
+	Issue      *github.Issue
+	Repository string
+	Age        time.Duration
+
+	// Unanswered is set when no organization member has commented on an issue
+	// opened by someone outside the organization.
+	Unanswered bool
+
+	// AwaitingReply is set when the most recent comment is from a non-member,
+	// whoever opened the issue, unless the issue is already Unanswered.
+	AwaitingReply bool
+
+	// Stale is set when the issue has not been updated for StaleDays.
+	Stale bool
+}
+
+func (i TriageItem) Needs() string {
+	var needs []string
+
+	if i.Unanswered {
+		needs = append(needs, "unanswered")
+	}
+
+	if i.AwaitingReply {
+		needs = append(needs, "awaiting reply")
+	}
+
+	if i.Stale {
+		needs = append(needs, "stale")
+	}
+
+	return strings.Join(needs, ", ")
+}
+
+// TriageReport renders the open issues that are unanswered, awaiting a reply
+// or stale, grouped by repository or label and oldest first within a group.
+func TriageReport(ctx context.Context, t tablewriter.TableWriter, now time.Time, options TriageOptions, issues []*github.Issue, issueComments []*github.IssueComment, members []*github.User) error {
+	items := TriageQueue(now, options, issues, issueComments, members)
+
+	type row struct {
+		group string
+		item  TriageItem
+	}
+
+	var rows []row
+	for _, item := range items {
+		if options.GroupBy != "label" {
+			rows = append(rows, row{group: item.Repository, item: item})
+			continue
+		}
+
+		if len(item.Issue.Labels) == 0 {
+			rows = append(rows, row{group: noLabel, item: item})
+		}
+
+		for _, l := range item.Issue.Labels {
+			rows = append(rows, row{group: l.GetName(), item: item})
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].group != rows[j].group {
+			return rows[i].group < rows[j].group
+		}
+
+		return rows[i].item.Age > rows[j].item.Age
+	})
+
+	if options.GroupBy == "label" {
+		t.SetHeader([]string{"Label", "Repository", "Issue", "Title", "Age (Days)", "Needs", "URL"})
+	} else {
+		t.SetHeader([]string{"Repository", "Issue", "Title", "Age (Days)", "Needs", "URL"})
+	}
+
+	for _, r := range rows {
+		columns := []string{r.group}
+		if options.GroupBy == "label" {
+			columns = append(columns, r.item.Repository)
+		}
+
+		columns = append(columns,
+			fmt.Sprintf("#%d", r.item.Issue.GetNumber()),
+			r.item.Issue.GetTitle(),
+			fmt.Sprintf("%d", int(r.item.Age.Hours()/24)),
+			r.item.Needs(),
+			r.item.Issue.GetHTMLURL(),
+		)
+
+		t.Append(columns)
+	}
+
+	return t.Render()
+}
+
+// TriageQueue returns the open issues (excluding pull requests) that need
+// attention, in no particular order.
+func TriageQueue(now time.Time, options TriageOptions, issues []*github.Issue, issueComments []*github.IssueComment, members []*github.User) []TriageItem {
+	memberLogins := map[string]bool{}
+	for _, m := range members {
+		memberLogins[m.GetLogin()] = true
+	}
+
+	commentsByIssue := map[string][]*github.IssueComment{}
+	for _, c := range issueComments {
+		commentsByIssue[c.GetIssueURL()] = append(commentsByIssue[c.GetIssueURL()], c)
+	}
+
+	staleBefore := now.AddDate(0, 0, -options.StaleDays)
+
+	var items []TriageItem
+
+	for _, i := range issues {
+		if i.PullRequestLinks != nil || i.GetState() == "closed" {
+			continue
+		}
+
+		item := TriageItem{
+			Issue:      i,
+			Repository: gh.IssueRepository(i),
+			Age:        now.Sub(i.GetCreatedAt()),
+			Stale:      i.GetUpdatedAt().Before(staleBefore),
+		}
+
+		comments := commentsByIssue[i.GetURL()]
+		sort.Slice(comments, func(a, b int) bool {
+			return comments[a].GetCreatedAt().Before(comments[b].GetCreatedAt())
+		})
+
+		responded := false
+		for _, c := range comments {
+			if memberLogins[c.User.GetLogin()] {
+				responded = true
+				break
+			}
+		}
+
+		if !responded && !memberLogins[i.User.GetLogin()] {
+			item.Unanswered = true
+		}
+
+		if !item.Unanswered && len(comments) > 0 && !memberLogins[comments[len(comments)-1].User.GetLogin()] {
+			item.AwaitingReply = true
+		}
+
+		if item.Unanswered || item.AwaitingReply || item.Stale {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
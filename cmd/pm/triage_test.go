@@ -0,0 +1,91 @@
+package main_test
+
+import (
+	"time"
+
+	"github.com/google/go-github/github"
+
+	. "github.com/chendrix/pm/cmd/pm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TriageQueue", func() {
+	var (
+		created time.Time
+		options TriageOptions
+		members []*github.User
+	)
+
+	BeforeEach(func() {
+		created = now.Add(-72 * time.Hour)
+		options = TriageOptions{StaleDays: 14}
+		members = []*github.User{{Login: github.String("alice")}, {Login: github.String("bob")}}
+	})
+
+	issueBy := func(login string) *github.Issue {
+		return &github.Issue{
+			Number:    github.Int(1),
+			State:     github.String("open"),
+			User:      &github.User{Login: github.String(login)},
+			CreatedAt: &created,
+			UpdatedAt: &created,
+			URL:       github.String("https://api.github.com/repos/acme/widget/issues/1"),
+		}
+	}
+
+	comment := func(issue *github.Issue, login string, after time.Duration) *github.IssueComment {
+		at := created.Add(after)
+		return &github.IssueComment{
+			User:      &github.User{Login: github.String(login)},
+			CreatedAt: &at,
+			IssueURL:  issue.URL,
+		}
+	}
+
+	It("awaits a reply to a non-member on an issue a member opened", func() {
+		issue := issueBy("alice")
+
+		queue := TriageQueue(now, options, []*github.Issue{issue},
+			[]*github.IssueComment{comment(issue, "carol", time.Hour)}, members)
+
+		Expect(queue).To(HaveLen(1))
+		Expect(queue[0].Unanswered).To(BeFalse())
+		Expect(queue[0].AwaitingReply).To(BeTrue())
+	})
+
+	It("awaits a reply to a non-member who commented after a member responded", func() {
+		issue := issueBy("carol")
+
+		queue := TriageQueue(now, options, []*github.Issue{issue}, []*github.IssueComment{
+			comment(issue, "carol", 2*time.Hour),
+			comment(issue, "bob", time.Hour),
+		}, members)
+
+		Expect(queue).To(HaveLen(1))
+		Expect(queue[0].Unanswered).To(BeFalse())
+		Expect(queue[0].AwaitingReply).To(BeTrue())
+	})
+
+	It("leaves an issue no member has answered as unanswered only", func() {
+		issue := issueBy("carol")
+
+		queue := TriageQueue(now, options, []*github.Issue{issue},
+			[]*github.IssueComment{comment(issue, "carol", time.Hour)}, members)
+
+		Expect(queue).To(HaveLen(1))
+		Expect(queue[0].Unanswered).To(BeTrue())
+		Expect(queue[0].AwaitingReply).To(BeFalse())
+	})
+
+	It("leaves out an issue a member commented on last", func() {
+		issue := issueBy("alice")
+
+		queue := TriageQueue(now, options, []*github.Issue{issue}, []*github.IssueComment{
+			comment(issue, "carol", time.Hour),
+			comment(issue, "bob", 2*time.Hour),
+		}, members)
+
+		Expect(queue).To(BeEmpty())
+	})
+})
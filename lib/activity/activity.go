@@ -0,0 +1,68 @@
+// Package activity describes what users did in a repository independently of
+// where it was crawled from, so that reports need not know about go-github,
+// snapshots or the store.
+package activity
+
+import (
+	"context"
+	"time"
+)
+
+// Kind is what an event records a user doing.
+type Kind string
+
+const (
+	IssueOpened       Kind = "issue-opened"
+	IssueComment      Kind = "issue-comment"
+	RepositoryComment Kind = "repository-comment"
+	Review            Kind = "review"
+
+	// The remaining kinds are only seen in an organization's event stream.
+	IssueClosed Kind = "issue-closed"
+	Push        Kind = "push"
+	Fork        Kind = "fork"
+	Star        Kind = "star"
+	WikiEdit    Kind = "wiki-edit"
+	Release     Kind = "release"
+)
+
+// IsComment tells whether the kind is a comment, as opposed to opening an
+// issue or pull request.
+func (k Kind) IsComment() bool {
+	return k == IssueComment || k == RepositoryComment
+}
+
+// IsContribution tells whether the kind adds to a repository, as opposed to
+// starring or forking it.
+func (k Kind) IsContribution() bool {
+	return k != Star && k != Fork
+}
+
+// Event is a single thing a user did in a repository.
+type Event struct {
+	// Actor is the login of the user who did it, or empty if the source did
+	// not say who it was.
+	Actor string
+
+	Kind Kind
+
+	// Repository is the "owner/name" of the repository it happened in.
+	Repository string
+
+	// Target is what it was done to within the repository: "#12" for an
+	// issue or pull request, the commit SHA for a commit comment, the ref for
+	// a push, the tag for a release, the page for a wiki edit and the new
+	// repository for a fork.
+	Target string
+
+	CreatedAt time.Time
+	Body      string
+
+	// URL is where the event can be seen in a browser.
+	URL string
+}
+
+// Source provides the events of a set of organizations.
+type Source interface {
+	Events(ctx context.Context, orgs []string) ([]Event, error)
+}
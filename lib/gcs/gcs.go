@@ -0,0 +1,90 @@
+// Package gcs uploads rendered reports to Google Cloud Storage.
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// Destination is a bucket and an optional object prefix, given as
+// gs://bucket/prefix.
+type Destination struct {
+	Bucket string
+	Prefix string
+}
+
+func ParseURL(rawURL string) (Destination, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Destination{}, err
+	}
+
+	if u.Scheme != "gs" || u.Host == "" {
+		return Destination{}, fmt.Errorf("upload destination %q must be given as gs://bucket/prefix", rawURL)
+	}
+
+	return Destination{
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+func (d Destination) String() string {
+	return "gs://" + path.Join(d.Bucket, d.Prefix)
+}
+
+// Uploader stores each report under a date-stamped name and keeps a copy of
+// the most recent one under latest:
+//
+//	gs://bucket/prefix/2017-06-01/users.csv
+//	gs://bucket/prefix/latest/users.csv
+type Uploader struct {
+	Client      *storage.Client
+	Destination Destination
+}
+
+func NewUploader(client *storage.Client, destination Destination) *Uploader {
+	return &Uploader{
+		Client:      client,
+		Destination: destination,
+	}
+}
+
+// Upload stores content as name under the date of now (in UTC) and copies it
+// to latest, returning the dated object's name.
+func (u *Uploader) Upload(ctx context.Context, name string, contentType string, content io.Reader, now time.Time) (string, error) {
+	bucket := u.Client.Bucket(u.Destination.Bucket)
+
+	dated := path.Join(u.Destination.Prefix, now.UTC().Format("2006-01-02"), name)
+
+	w := bucket.Object(dated).NewWriter(ctx)
+	w.ContentType = contentType
+
+	_, err := io.Copy(w, content)
+	if err != nil {
+		w.CloseWithError(err)
+		return "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	copier := bucket.Object(path.Join(u.Destination.Prefix, "latest", name)).CopierFrom(bucket.Object(dated))
+	copier.ContentType = contentType
+
+	_, err = copier.Run(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return dated, nil
+}
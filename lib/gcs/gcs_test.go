@@ -0,0 +1,251 @@
+package gcs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/chendrix/pm/lib/gcs"
+	"github.com/chendrix/pm/lib/tablewriter"
+	"google.golang.org/api/option"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type object struct {
+	ContentType string
+	Content     string
+}
+
+// fakeStorage keeps the objects uploaded to it or copied within it, keyed
+// by bucket/name.
+type fakeStorage struct {
+	*httptest.Server
+
+	lock    sync.Mutex
+	objects map[string]object
+}
+
+func newFakeStorage() *fakeStorage {
+	f := &fakeStorage{objects: map[string]object{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+
+	return f
+}
+
+func (f *fakeStorage) Objects() map[string]object {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	objects := map[string]object{}
+	for name, o := range f.objects {
+		objects[name] = o
+	}
+
+	return objects
+}
+
+// segments splits the request's path, unescaping object names that contain
+// slashes.
+func segments(r *http.Request) []string {
+	var all []string
+	for _, s := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(s)
+		Expect(err).NotTo(HaveOccurred())
+
+		all = append(all, unescaped)
+	}
+
+	return all
+}
+
+func (f *fakeStorage) serve(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	path := segments(r)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case r.Method == "POST" && len(path) == 3 && path[0] == "b" && path[2] == "o":
+		Expect(r.URL.Query().Get("uploadType")).To(Equal("multipart"))
+
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		Expect(err).NotTo(HaveOccurred())
+
+		parts := multipart.NewReader(r.Body, params["boundary"])
+
+		part, err := parts.NextPart()
+		Expect(err).NotTo(HaveOccurred())
+
+		var attrs struct {
+			Name        string `json:"name"`
+			ContentType string `json:"contentType"`
+		}
+		Expect(json.NewDecoder(part).Decode(&attrs)).To(Succeed())
+
+		part, err = parts.NextPart()
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := ioutil.ReadAll(part)
+		Expect(err).NotTo(HaveOccurred())
+
+		f.objects[path[1]+"/"+attrs.Name] = object{ContentType: attrs.ContentType, Content: string(content)}
+
+		fmt.Fprintf(w, `{"bucket": %q, "name": %q, "contentType": %q}`, path[1], attrs.Name, attrs.ContentType)
+
+	case r.Method == "POST" && len(path) == 9 && path[4] == "rewriteTo":
+		var attrs struct {
+			ContentType string `json:"contentType"`
+		}
+		Expect(json.NewDecoder(r.Body).Decode(&attrs)).To(Succeed())
+
+		source, found := f.objects[path[1]+"/"+path[3]]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": 404, "message": "No such object"}}`)
+			return
+		}
+
+		copied := object{ContentType: attrs.ContentType, Content: source.Content}
+		if copied.ContentType == "" {
+			copied.ContentType = source.ContentType
+		}
+
+		f.objects[path[6]+"/"+path[8]] = copied
+
+		fmt.Fprintf(w, `{"done": true, "resource": {"bucket": %q, "name": %q, "contentType": %q}}`, path[6], path[8], copied.ContentType)
+
+	default:
+		Fail(fmt.Sprintf("unexpected Cloud Storage request %s %s", r.Method, r.URL))
+	}
+}
+
+var _ = Describe("ParseURL", func() {
+	DescribeTable("reading destinations",
+		func(rawURL string, expected gcs.Destination, name string) {
+			destination, err := gcs.ParseURL(rawURL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(destination).To(Equal(expected))
+			Expect(destination.String()).To(Equal(name))
+		},
+		Entry("bucket only", "gs://reports", gcs.Destination{Bucket: "reports"}, "gs://reports"),
+		Entry("bucket and prefix", "gs://reports/acme/weekly/", gcs.Destination{Bucket: "reports", Prefix: "acme/weekly"}, "gs://reports/acme/weekly"),
+	)
+
+	DescribeTable("rejecting anything but gs://bucket/prefix",
+		func(rawURL string) {
+			_, err := gcs.ParseURL(rawURL)
+			Expect(err).To(MatchError(ContainSubstring("must be given as gs://bucket/prefix")))
+		},
+		Entry("another scheme", "s3://reports/acme"),
+		Entry("no bucket", "gs:///acme"),
+		Entry("a local path", "reports/acme"),
+	)
+})
+
+var _ = Describe("Uploader", func() {
+	var (
+		server   *fakeStorage
+		uploader *gcs.Uploader
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		server = newFakeStorage()
+
+		client, err := storage.NewClient(context.Background(),
+			option.WithEndpoint(server.URL+"/"),
+			option.WithHTTPClient(http.DefaultClient),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		uploader = gcs.NewUploader(client, gcs.Destination{Bucket: "reports", Prefix: "acme/weekly"})
+		now = time.Date(2017, time.June, 1, 9, 0, 0, 0, time.UTC)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("stores the report under the date and copies it to latest", func() {
+		name, err := uploader.Upload(context.Background(), "manifest.csv", "text/csv; charset=utf-8", strings.NewReader("Github User\nalice\n"), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("acme/weekly/2017-06-01/manifest.csv"))
+
+		Expect(server.Objects()).To(Equal(map[string]object{
+			"reports/acme/weekly/2017-06-01/manifest.csv": {ContentType: "text/csv; charset=utf-8", Content: "Github User\nalice\n"},
+			"reports/acme/weekly/latest/manifest.csv":     {ContentType: "text/csv; charset=utf-8", Content: "Github User\nalice\n"},
+		}))
+	})
+
+	It("dates the report in UTC", func() {
+		pacific := time.FixedZone("PDT", -7*60*60)
+
+		name, err := uploader.Upload(context.Background(), "manifest.csv", "text/csv; charset=utf-8", strings.NewReader(""), time.Date(2017, time.June, 1, 20, 0, 0, 0, pacific))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("acme/weekly/2017-06-02/manifest.csv"))
+	})
+
+	It("stores the report at the top of the bucket without a prefix", func() {
+		uploader.Destination.Prefix = ""
+
+		name, err := uploader.Upload(context.Background(), "manifest.csv", "text/csv; charset=utf-8", strings.NewReader(""), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("2017-06-01/manifest.csv"))
+
+		Expect(server.Objects()).To(HaveKey("reports/2017-06-01/manifest.csv"))
+		Expect(server.Objects()).To(HaveKey("reports/latest/manifest.csv"))
+	})
+
+	It("replaces latest with each newer report, keeping the earlier dated ones", func() {
+		_, err := uploader.Upload(context.Background(), "manifest.csv", "text/csv; charset=utf-8", strings.NewReader("first\n"), now)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = uploader.Upload(context.Background(), "manifest.csv", "text/csv; charset=utf-8", strings.NewReader("second\n"), now.AddDate(0, 0, 7))
+		Expect(err).NotTo(HaveOccurred())
+
+		objects := server.Objects()
+		Expect(objects).To(HaveLen(3))
+		Expect(objects["reports/acme/weekly/2017-06-01/manifest.csv"].Content).To(Equal("first\n"))
+		Expect(objects["reports/acme/weekly/2017-06-08/manifest.csv"].Content).To(Equal("second\n"))
+		Expect(objects["reports/acme/weekly/latest/manifest.csv"].Content).To(Equal("second\n"))
+	})
+
+	It("gives both copies the content type of every format", func() {
+		for _, format := range tablewriter.Formats {
+			name := "manifest" + tablewriter.Extensions[format]
+
+			_, err := uploader.Upload(context.Background(), name, tablewriter.ContentTypes[format], strings.NewReader(format), now)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(server.Objects()).To(Equal(map[string]object{
+			"reports/acme/weekly/2017-06-01/manifest.csv":  {ContentType: "text/csv; charset=utf-8", Content: "csv"},
+			"reports/acme/weekly/latest/manifest.csv":      {ContentType: "text/csv; charset=utf-8", Content: "csv"},
+			"reports/acme/weekly/2017-06-01/manifest.txt":  {ContentType: "text/plain; charset=utf-8", Content: "text"},
+			"reports/acme/weekly/latest/manifest.txt":      {ContentType: "text/plain; charset=utf-8", Content: "text"},
+			"reports/acme/weekly/2017-06-01/manifest.md":   {ContentType: "text/markdown; charset=utf-8", Content: "markdown"},
+			"reports/acme/weekly/latest/manifest.md":       {ContentType: "text/markdown; charset=utf-8", Content: "markdown"},
+			"reports/acme/weekly/2017-06-01/manifest.json": {ContentType: "application/json", Content: "json"},
+			"reports/acme/weekly/latest/manifest.json":     {ContentType: "application/json", Content: "json"},
+			"reports/acme/weekly/2017-06-01/manifest.html": {ContentType: "text/html; charset=utf-8", Content: "html"},
+			"reports/acme/weekly/latest/manifest.html":     {ContentType: "text/html; charset=utf-8", Content: "html"},
+		}))
+	})
+})
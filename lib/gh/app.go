@@ -0,0 +1,321 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+const appMediaType = "application/vnd.github.machine-man-preview+json"
+
+// installationTokenEarlyRefresh is how long before GitHub expires an
+// installation token it is replaced, so that a request never starts with a
+// token that runs out mid-flight.
+const installationTokenEarlyRefresh = 5 * time.Minute
+
+// App authenticates as a GitHub App. It signs short-lived JWTs with the
+// app's private key and exchanges them for installation access tokens.
+type App struct {
+	ID  int
+	Key *rsa.PrivateKey
+
+	// Now is the clock JWTs are issued by; it defaults to time.Now.
+	Now func() time.Time
+
+	client *github.Client
+}
+
+// NewApp returns an App that talks to the GitHub API at baseURL over base.
+func NewApp(id int, key *rsa.PrivateKey, baseURL *url.URL, base http.RoundTripper) *App {
+	app := &App{ID: id, Key: key, Now: time.Now}
+
+	app.client = github.NewClient(&http.Client{Transport: &jwtTransport{app: app, base: base}})
+	app.client.BaseURL = baseURL
+
+	return app
+}
+
+// ReadPrivateKey reads a PEM encoded RSA private key, as downloaded from a
+// GitHub App's settings page.
+func ReadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(payload)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key %s: %s", path, err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an RSA key", path)
+	}
+
+	return key, nil
+}
+
+// JWT returns a token identifying the app itself, valid for nine minutes.
+// It is backdated by a minute to allow for clock drift.
+func (app *App) JWT() (string, error) {
+	now := app.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.Itoa(app.ID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, app.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// OrganizationInstallation returns the ID of the app's installation on an
+// organization.
+func (app *App) OrganizationInstallation(ctx context.Context, org string) (int, error) {
+	req, err := app.client.NewRequest("GET", fmt.Sprintf("orgs/%s/installation", url.PathEscape(org)), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Accept", appMediaType)
+
+	var installation github.Installation
+	_, err = app.client.Do(ctx, req, &installation)
+	if err != nil {
+		return 0, err
+	}
+
+	return installation.GetID(), nil
+}
+
+// InstallationToken creates an access token for an installation.
+func (app *App) InstallationToken(ctx context.Context, installation int) (*oauth2.Token, error) {
+	req, err := app.client.NewRequest("POST", fmt.Sprintf("app/installations/%d/access_tokens", installation), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", appMediaType)
+
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	_, err = app.client.Do(ctx, req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("creating an access token for GitHub App installation %d: %s", installation, err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.Token,
+		TokenType:   "token",
+		Expiry:      token.ExpiresAt.Add(-installationTokenEarlyRefresh),
+	}, nil
+}
+
+// InstallationTokenSource returns a source of access tokens for an
+// installation that creates a new token whenever the last one is about to
+// expire.
+func (app *App) InstallationTokenSource(ctx context.Context, installation int) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, installationTokenSource{ctx: ctx, app: app, installation: installation})
+}
+
+type installationTokenSource struct {
+	ctx          context.Context
+	app          *App
+	installation int
+}
+
+func (s installationTokenSource) Token() (*oauth2.Token, error) {
+	return s.app.InstallationToken(s.ctx, s.installation)
+}
+
+type jwtTransport struct {
+	app  *App
+	base http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.app.JWT()
+	if err != nil {
+		return nil, err
+	}
+
+	req = cloneRequest(req)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return t.base.RoundTrip(req)
+}
+
+// AppTransport authenticates every request as the app installation of the
+// organization it is about. Installations are looked up and their tokens
+// created the first time an organization is requested, and refreshed as
+// they expire, so long crawls of several organizations never run out of
+// credentials.
+type AppTransport struct {
+	App  *App
+	Base http.RoundTripper
+
+	// Organizations are the organizations being crawled. Requests that are
+	// not about an organization, such as for rate limits, or that are about
+	// an owner the app is not installed on, use the first one's installation.
+	Organizations []string
+
+	ctx        context.Context
+	lock       sync.Mutex
+	transports map[string]http.RoundTripper
+}
+
+// NewAppTransport returns a transport authenticating as the app's
+// installations on orgs.
+func NewAppTransport(ctx context.Context, app *App, base http.RoundTripper, orgs []string) (*AppTransport, error) {
+	if len(orgs) == 0 {
+		return nil, errors.New("a GitHub App needs at least one organization to authenticate as")
+	}
+
+	return &AppTransport{
+		App:           app,
+		Base:          base,
+		Organizations: orgs,
+
+		ctx:        ctx,
+		transports: map[string]http.RoundTripper{},
+	}, nil
+}
+
+func (t *AppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	owner := requestOwner(t.App.client.BaseURL, req.URL)
+	if owner == "" {
+		owner = t.Organizations[0]
+	}
+
+	transport, err := t.transport(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.RoundTrip(req)
+}
+
+func (t *AppTransport) transport(owner string) (http.RoundTripper, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.installationTransport(owner)
+}
+
+func (t *AppTransport) installationTransport(owner string) (http.RoundTripper, error) {
+	key := strings.ToLower(owner)
+	if transport, found := t.transports[key]; found {
+		return transport, nil
+	}
+
+	installation, err := t.App.OrganizationInstallation(t.ctx, owner)
+	if err != nil {
+		// Public repositories of owners the app is not installed on are
+		// still readable with another installation's token.
+		if isNotFound(err) && !strings.EqualFold(owner, t.Organizations[0]) {
+			transport, err := t.installationTransport(t.Organizations[0])
+			if err != nil {
+				return nil, err
+			}
+
+			t.transports[key] = transport
+			return transport, nil
+		}
+
+		return nil, fmt.Errorf("finding the GitHub App installation for %s: %s", owner, err)
+	}
+
+	transport := &oauth2.Transport{
+		Source: t.App.InstallationTokenSource(t.ctx, installation),
+		Base:   t.Base,
+	}
+
+	t.transports[key] = transport
+
+	return transport, nil
+}
+
+// requestOwner returns the organization or user an API request is about,
+// or an empty string.
+func requestOwner(baseURL *url.URL, u *url.URL) string {
+	path := strings.TrimPrefix(u.Path, baseURL.Path)
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+
+	switch segments[0] {
+	case "orgs", "repos", "users":
+		return segments[1]
+	}
+
+	return ""
+}
+
+func isNotFound(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	return ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// cloneRequest returns a shallow copy of req with its own headers, as
+// RoundTrippers must not modify the request they are given.
+func cloneRequest(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+
+	clone.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		clone.Header[k] = append([]string(nil), v...)
+	}
+
+	return clone
+}
@@ -0,0 +1,260 @@
+package gh_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeAppServer issues installation tokens for acme and serves repository
+// lists to requests authenticated with them.
+type fakeAppServer struct {
+	*httptest.Server
+
+	key      *rsa.PrivateKey
+	tokenTTL time.Duration
+
+	lock   sync.Mutex
+	tokens []string
+	auth   map[string]string
+}
+
+func newFakeAppServer(key *rsa.PrivateKey) *fakeAppServer {
+	s := &fakeAppServer{key: key, tokenTTL: time.Hour, auth: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *fakeAppServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	authorization := r.Header.Get("Authorization")
+	s.auth[r.URL.Path] = authorization
+
+	switch {
+	case r.URL.Path == "/orgs/acme/installation":
+		if !s.validJWT(authorization) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"id": 42}`)
+
+	case strings.HasPrefix(r.URL.Path, "/orgs/") && strings.HasSuffix(r.URL.Path, "/installation"):
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+
+	case r.URL.Path == "/app/installations/42/access_tokens" && r.Method == "POST":
+		if !s.validJWT(authorization) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		token := fmt.Sprintf("installation-token-%d", len(s.tokens)+1)
+		s.tokens = append(s.tokens, token)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      token,
+			"expires_at": time.Now().Add(s.tokenTTL).UTC().Format(time.RFC3339),
+		})
+
+	default:
+		if !strings.HasPrefix(authorization, "token installation-token-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `[]`)
+	}
+}
+
+func (s *fakeAppServer) validJWT(authorization string) bool {
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	return rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, digest[:], signature) == nil
+}
+
+func (s *fakeAppServer) issued() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, s.tokens...)
+}
+
+func (s *fakeAppServer) authorization(path string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.auth[path]
+}
+
+var _ = Describe("App", func() {
+	var (
+		key    *rsa.PrivateKey
+		server *fakeAppServer
+		app    *gh.App
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+
+		server = newFakeAppServer(key)
+
+		baseURL, err := url.Parse(server.URL + "/")
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Date(2017, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+		app = gh.NewApp(1234, key, baseURL, http.DefaultTransport)
+		app.Now = func() time.Time { return now }
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("JWT", func() {
+		It("is signed with the app's key and issued by the app", func() {
+			token, err := app.JWT()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.validJWT("Bearer " + token)).To(BeTrue())
+
+			payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+			Expect(err).NotTo(HaveOccurred())
+
+			var claims struct {
+				Iat int64  `json:"iat"`
+				Exp int64  `json:"exp"`
+				Iss string `json:"iss"`
+			}
+			Expect(json.Unmarshal(payload, &claims)).To(Succeed())
+			Expect(claims.Iss).To(Equal("1234"))
+			Expect(claims.Iat).To(Equal(now.Add(-time.Minute).Unix()))
+			Expect(claims.Exp).To(Equal(now.Add(9 * time.Minute).Unix()))
+		})
+	})
+
+	Describe("ReadPrivateKey", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "gh")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		write := func(block *pem.Block) string {
+			path := filepath.Join(dir, "key.pem")
+			Expect(ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600)).To(Succeed())
+			return path
+		}
+
+		It("reads PKCS #1 keys", func() {
+			read, err := gh.ReadPrivateKey(write(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read.Equal(key)).To(BeTrue())
+		})
+
+		It("reads PKCS #8 keys", func() {
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+
+			read, err := gh.ReadPrivateKey(write(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read.Equal(key)).To(BeTrue())
+		})
+
+		It("fails for files without a key", func() {
+			_, err := gh.ReadPrivateKey(write(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("nope")}))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("AppTransport", func() {
+		var client *gh.Client
+
+		BeforeEach(func() {
+			transport, err := gh.NewAppTransport(context.Background(), app, http.DefaultTransport, []string{"acme"})
+			Expect(err).NotTo(HaveOccurred())
+
+			client, err = gh.NewClientWithBaseURL(&http.Client{Transport: transport}, server.URL)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("authenticates as the organization's installation and reuses its token", func() {
+			_, err := client.PublicRepositories(context.Background(), "acme")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.OrganizationMembers(context.Background(), "acme")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(server.issued()).To(Equal([]string{"installation-token-1"}))
+			Expect(server.authorization("/orgs/acme/members")).To(Equal("token installation-token-1"))
+		})
+
+		It("creates a new token when the last one is about to expire", func() {
+			server.tokenTTL = 5 * time.Minute
+
+			_, err := client.PublicRepositories(context.Background(), "acme")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.PublicRepositories(context.Background(), "acme")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(server.issued()).To(Equal([]string{"installation-token-1", "installation-token-2"}))
+			Expect(server.authorization("/orgs/acme/repos")).To(Equal("token installation-token-2"))
+		})
+
+		It("uses the first organization's installation for owners the app is not installed on", func() {
+			repo := &github.Repository{Owner: &github.User{Login: github.String("elsewhere")}, Name: github.String("tool")}
+
+			_, err := client.AllCommentsForRepository(context.Background(), repo)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.authorization("/repos/elsewhere/tool/comments")).To(Equal("token installation-token-1"))
+		})
+
+		It("requires an organization", func() {
+			_, err := gh.NewAppTransport(context.Background(), app, http.DefaultTransport, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
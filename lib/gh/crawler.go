@@ -0,0 +1,62 @@
+package gh
+
+import (
+	"context"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/google/go-github/github"
+)
+
+// Crawler provides the activity reports are built from for a set of
+// organizations. Client crawls the GitHub API for it; other implementations
+// serve activity that was crawled earlier.
+type Crawler interface {
+	activity.Source
+
+	AllIssuesForOrganizations(ctx context.Context, orgs []string) ([]*github.Issue, error)
+	IssuesUpdatedSinceForOrganizations(ctx context.Context, orgs []string, since time.Time) ([]*github.Issue, error)
+	AllIssueCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueComment, error)
+	AllRepositoryCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.RepositoryComment, error)
+	AllIssueEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueEvent, error)
+	AllReviewsForIssues(ctx context.Context, issues []*github.Issue) ([]*github.PullRequestReview, error)
+	AllMembersForOrganizations(ctx context.Context, orgs []string) ([]*github.User, error)
+	StreamEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.Event, error)
+	StargazersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.Stargazer, error)
+	WatchersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.User, error)
+	ForksForOrganizations(ctx context.Context, orgs []string) (map[string][]*Fork, error)
+	TrafficForOrganizations(ctx context.Context, orgs []string) (map[string]TrafficHistory, error)
+	ReleasesForOrganizations(ctx context.Context, orgs []string) (map[string][]*Release, error)
+}
+
+var _ Crawler = &Client{}
+
+// Fetcher crawls the complete history of a repository, which is what pm
+// fetch and pm export record. Client fetches it over the REST API.
+type Fetcher interface {
+	PublicRepositoriesForOrganizations(ctx context.Context, orgs []string) ([]*github.Repository, error)
+	AllIssuesIncludingClosed(ctx context.Context, repo *github.Repository) ([]*github.Issue, error)
+	AllIssueCommentsForRepository(ctx context.Context, repo *github.Repository) ([]*github.IssueComment, error)
+	AllCommentsForRepository(ctx context.Context, repo *github.Repository) ([]*github.RepositoryComment, error)
+	AllIssueEventsForRepository(ctx context.Context, repo *github.Repository) ([]*github.IssueEvent, error)
+	AllReviewsForPullRequests(ctx context.Context, repo *github.Repository, issues []*github.Issue) ([]*github.PullRequestReview, error)
+	AllStargazersForRepository(ctx context.Context, repo *github.Repository) ([]*github.Stargazer, error)
+	AllWatchersForRepository(ctx context.Context, repo *github.Repository) ([]*github.User, error)
+	AllForksForRepository(ctx context.Context, repo *github.Repository) ([]*Fork, error)
+	TrafficForRepository(ctx context.Context, repo *github.Repository) (*Traffic, error)
+	AllReleasesForRepository(ctx context.Context, repo *github.Repository) ([]*Release, error)
+	OrganizationMembers(ctx context.Context, org string) ([]*github.User, error)
+	StreamEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.Event, error)
+
+	// ProgressReporter is notified as the fetch goes along.
+	ProgressReporter() Progress
+}
+
+// Backend is a way of crawling GitHub live, as opposed to replaying
+// activity crawled earlier.
+type Backend interface {
+	Crawler
+	Fetcher
+}
+
+var _ Backend = &Client{}
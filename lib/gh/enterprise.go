@@ -0,0 +1,175 @@
+package gh
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// Feature is an API endpoint that older GitHub Enterprise Server releases
+// do not provide.
+type Feature string
+
+const (
+	FeatureReviews Feature = "pull request reviews"
+)
+
+// featureVersions is the first GitHub Enterprise Server release providing
+// each feature.
+var featureVersions = map[Feature]string{
+	FeatureReviews: "2.10",
+}
+
+// ServerVersion describes the GitHub server a client talks to. The zero
+// value is github.com, which provides every feature.
+type ServerVersion struct {
+	// Enterprise is set for GitHub Enterprise Server, whose installed
+	// release, such as 2.9.3, is Version.
+	Enterprise bool
+	Version    string
+}
+
+func (v ServerVersion) String() string {
+	if !v.Enterprise {
+		return "github.com"
+	}
+
+	return "GitHub Enterprise Server " + v.Version
+}
+
+// Supports reports whether the server provides a feature. Enterprise
+// servers whose version could not be determined are assumed to.
+func (v ServerVersion) Supports(f Feature) bool {
+	if !v.Enterprise || v.Version == "" {
+		return true
+	}
+
+	return compareVersions(v.Version, featureVersions[f]) >= 0
+}
+
+// NewEnterpriseClient returns a client for a GitHub Enterprise Server. URLs
+// without a path get the server's default API prefix, so
+// https://github.example.com is the same as
+// https://github.example.com/api/v3/. An empty uploadURL is derived from
+// apiURL.
+func NewEnterpriseClient(httpClient *http.Client, apiURL string, uploadURL string) (*Client, error) {
+	baseURL, err := enterpriseURL(apiURL, "api/v3/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL: %s", err)
+	}
+
+	if uploadURL == "" {
+		uploadURL = strings.TrimSuffix(baseURL.String(), "api/v3/") + "api/uploads/"
+	}
+
+	uploadsURL, err := enterpriseURL(uploadURL, "api/uploads/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub upload URL: %s", err)
+	}
+
+	githubClient := github.NewClient(httpClient)
+	githubClient.BaseURL = baseURL
+	githubClient.UploadURL = uploadsURL
+
+	return NewClient(githubClient), nil
+}
+
+func enterpriseURL(raw string, defaultPath string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%s is not an absolute URL", raw)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/" + defaultPath
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return u, nil
+}
+
+// DetectServerVersion asks the server which release it runs. GitHub
+// Enterprise Server reports its version in a header on every response;
+// github.com does not.
+func (client *Client) DetectServerVersion(ctx context.Context) (ServerVersion, error) {
+	_, resp, err := client.GithubClient.APIMeta(ctx)
+	if err != nil {
+		return ServerVersion{}, err
+	}
+
+	version := resp.Header.Get("X-GitHub-Enterprise-Version")
+	if version == "" {
+		return ServerVersion{}, nil
+	}
+
+	return ServerVersion{Enterprise: true, Version: version}, nil
+}
+
+// NewTransport returns an HTTP transport that trusts the PEM certificates in
+// caBundle in addition to the system roots, or that does not verify
+// certificates at all if insecureSkipVerify is set.
+func NewTransport(caBundle string, insecureSkipVerify bool) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+
+	if caBundle != "" {
+		pem, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caBundle)
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return transport, nil
+}
+
+// compareVersions compares dotted version numbers, returning -1, 0 or 1.
+// Missing or non-numeric components count as 0.
+func compareVersions(a string, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,165 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GitHub Enterprise Server", func() {
+	Describe("NewEnterpriseClient", func() {
+		It("defaults to the server's API and upload prefixes", func() {
+			client, err := gh.NewEnterpriseClient(http.DefaultClient, "https://github.example.com", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.GithubClient.BaseURL.String()).To(Equal("https://github.example.com/api/v3/"))
+			Expect(client.GithubClient.UploadURL.String()).To(Equal("https://github.example.com/api/uploads/"))
+		})
+
+		It("derives the upload URL from an explicit API URL", func() {
+			client, err := gh.NewEnterpriseClient(http.DefaultClient, "https://github.example.com/api/v3", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.GithubClient.BaseURL.String()).To(Equal("https://github.example.com/api/v3/"))
+			Expect(client.GithubClient.UploadURL.String()).To(Equal("https://github.example.com/api/uploads/"))
+		})
+
+		It("uses the given upload URL", func() {
+			client, err := gh.NewEnterpriseClient(http.DefaultClient, "https://github.example.com/api/v3/", "https://uploads.example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.GithubClient.UploadURL.String()).To(Equal("https://uploads.example.com/api/uploads/"))
+		})
+
+		It("rejects relative URLs", func() {
+			_, err := gh.NewEnterpriseClient(http.DefaultClient, "github.example.com", "")
+			Expect(err).To(MatchError(ContainSubstring("not an absolute URL")))
+		})
+	})
+
+	Describe("DetectServerVersion", func() {
+		var server *ghtest.Server
+
+		BeforeEach(func() {
+			server = ghtest.NewServer("ghtest/testdata/acme")
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("recognizes github.com", func() {
+			version, err := server.GitHubClient().DetectServerVersion(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(gh.ServerVersion{}))
+			Expect(version.String()).To(Equal("github.com"))
+		})
+
+		It("reads the Enterprise Server release", func() {
+			server.EnterpriseVersion = "2.9.3"
+
+			version, err := server.GitHubClient().DetectServerVersion(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(gh.ServerVersion{Enterprise: true, Version: "2.9.3"}))
+		})
+
+		It("skips reviews on servers that do not support them", func() {
+			client := server.GitHubClient()
+			client.Version = gh.ServerVersion{Enterprise: true, Version: "2.9.3"}
+
+			repo := &github.Repository{Owner: &github.User{Login: github.String("acme")}, Name: github.String("widget")}
+			issues, err := client.AllIssuesIncludingClosed(context.Background(), repo)
+			Expect(err).NotTo(HaveOccurred())
+
+			reviews, err := client.AllReviewsForPullRequests(context.Background(), repo, issues)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reviews).To(BeEmpty())
+			Expect(server.Requests()).NotTo(ContainElement(ContainSubstring("/reviews")))
+
+			client.Version.Version = "2.10.0"
+
+			reviews, err = client.AllReviewsForPullRequests(context.Background(), repo, issues)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reviews).To(HaveLen(4))
+		})
+	})
+
+	DescribeTable("ServerVersion.Supports",
+		func(version gh.ServerVersion, supported bool) {
+			Expect(version.Supports(gh.FeatureReviews)).To(Equal(supported))
+		},
+		Entry("github.com", gh.ServerVersion{}, true),
+		Entry("an unknown release", gh.ServerVersion{Enterprise: true}, true),
+		Entry("an older release", gh.ServerVersion{Enterprise: true, Version: "2.9.3"}, false),
+		Entry("the first release", gh.ServerVersion{Enterprise: true, Version: "2.10"}, true),
+		Entry("a later release", gh.ServerVersion{Enterprise: true, Version: "2.14.1"}, true),
+	)
+
+	Describe("NewTransport", func() {
+		var (
+			server *httptest.Server
+			dir    string
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			var err error
+			dir, err = ioutil.TempDir("", "gh")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		get := func(transport *http.Transport) error {
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			return err
+		}
+
+		It("does not trust unknown certificate authorities", func() {
+			transport, err := gh.NewTransport("", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(get(transport)).To(HaveOccurred())
+		})
+
+		It("trusts the certificates in the CA bundle", func() {
+			bundle := filepath.Join(dir, "ca.pem")
+			payload := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			Expect(ioutil.WriteFile(bundle, payload, 0644)).To(Succeed())
+
+			transport, err := gh.NewTransport(bundle, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(get(transport)).To(Succeed())
+		})
+
+		It("fails for a bundle without certificates", func() {
+			bundle := filepath.Join(dir, "ca.pem")
+			Expect(ioutil.WriteFile(bundle, []byte("nope"), 0644)).To(Succeed())
+
+			_, err := gh.NewTransport(bundle, false)
+			Expect(err).To(MatchError(ContainSubstring("no certificates found")))
+		})
+
+		It("skips verification when asked to", func() {
+			transport, err := gh.NewTransport("", true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(get(transport)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,145 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/google/go-github/github"
+)
+
+// CrawlEvents returns the open issues, every issue and repository comment
+// and the event stream of the organizations as activity events, which is how
+// every Crawler implements activity.Source.
+func CrawlEvents(ctx context.Context, crawler Crawler, orgs []string) ([]activity.Event, error) {
+	issues, err := crawler.AllIssuesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return crawlEvents(ctx, crawler, orgs, issues)
+}
+
+// CrawlEventsSince is CrawlEvents with every issue updated since the given
+// time, open or closed, in place of the open issues, for reports that must
+// see activity that has since been closed.
+func CrawlEventsSince(ctx context.Context, crawler Crawler, orgs []string, since time.Time) ([]activity.Event, error) {
+	issues, err := crawler.IssuesUpdatedSinceForOrganizations(ctx, orgs, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return crawlEvents(ctx, crawler, orgs, issues)
+}
+
+func crawlEvents(ctx context.Context, crawler Crawler, orgs []string, issues []*github.Issue) ([]activity.Event, error) {
+	issueComments, err := crawler.AllIssueCommentsForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryComments, err := crawler.AllRepositoryCommentsForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := crawler.StreamEventsForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []activity.Event
+
+	for _, i := range issues {
+		events = append(events, IssueOpenedEvent(i))
+	}
+
+	for _, c := range issueComments {
+		events = append(events, IssueCommentEvent(c))
+	}
+
+	for _, c := range repositoryComments {
+		events = append(events, RepositoryCommentEvent(c))
+	}
+
+	for _, e := range stream {
+		if event, ok := StreamEvent(e); ok {
+			events = append(events, event)
+		}
+	}
+
+	return Dated(events), nil
+}
+
+// Dated leaves out the events that do not say when they happened, such as
+// reviews that are still pending, rather than have them counted as activity
+// in year one.
+func Dated(events []activity.Event) []activity.Event {
+	var dated []activity.Event
+	for _, e := range events {
+		if !e.CreatedAt.IsZero() {
+			dated = append(dated, e)
+		}
+	}
+
+	return dated
+}
+
+// IssueOpenedEvent records the opening of an issue or pull request.
+func IssueOpenedEvent(i *github.Issue) activity.Event {
+	return activity.Event{
+		Actor:      i.User.GetLogin(),
+		Kind:       activity.IssueOpened,
+		Repository: IssueRepository(i),
+		Target:     fmt.Sprintf("#%d", i.GetNumber()),
+		CreatedAt:  i.GetCreatedAt(),
+		Body:       i.GetBody(),
+		URL:        i.GetHTMLURL(),
+	}
+}
+
+// IssueCommentEvent records a comment on an issue or pull request.
+func IssueCommentEvent(c *github.IssueComment) activity.Event {
+	return activity.Event{
+		Actor:      c.User.GetLogin(),
+		Kind:       activity.IssueComment,
+		Repository: RepositoryFullName(c.GetURL()),
+		Target:     "#" + path.Base(c.GetIssueURL()),
+		CreatedAt:  c.GetCreatedAt(),
+		Body:       c.GetBody(),
+		URL:        c.GetHTMLURL(),
+	}
+}
+
+// RepositoryCommentEvent records a comment on a commit.
+func RepositoryCommentEvent(c *github.RepositoryComment) activity.Event {
+	return activity.Event{
+		Actor:      c.User.GetLogin(),
+		Kind:       activity.RepositoryComment,
+		Repository: RepositoryFullName(c.GetURL()),
+		Target:     c.GetCommitID(),
+		CreatedAt:  c.GetCreatedAt(),
+		Body:       c.GetBody(),
+		URL:        c.GetHTMLURL(),
+	}
+}
+
+// ReviewEvent records the review of a pull request.
+func ReviewEvent(r *github.PullRequestReview) activity.Event {
+	return activity.Event{
+		Actor:      r.User.GetLogin(),
+		Kind:       activity.Review,
+		Repository: RepositoryFullName(r.GetPullRequestURL()),
+		Target:     "#" + path.Base(r.GetPullRequestURL()),
+		CreatedAt:  r.GetSubmittedAt(),
+		Body:       r.GetBody(),
+		URL:        r.GetHTMLURL(),
+	}
+}
+
+// Events returns the organizations' activity.
+func (client *Client) Events(ctx context.Context, orgs []string) ([]activity.Event, error) {
+	return CrawlEvents(ctx, client, orgs)
+}
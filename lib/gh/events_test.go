@@ -0,0 +1,91 @@
+package gh_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	var server *ghtest.Server
+
+	BeforeEach(func() {
+		server = ghtest.NewServer("ghtest/testdata/acme")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	find := func(events []activity.Event, url string) activity.Event {
+		for _, e := range events {
+			if e.URL == url {
+				return e
+			}
+		}
+
+		Fail("no event at " + url)
+		return activity.Event{}
+	}
+
+	It("returns open issues, every comment and event stream activity as events", func() {
+		events, err := server.GitHubClient().Events(context.Background(), []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		kinds := map[activity.Kind]int{}
+		for _, e := range events {
+			kinds[e.Kind]++
+		}
+
+		Expect(kinds).To(Equal(map[activity.Kind]int{
+			activity.IssueOpened:       5,
+			activity.IssueComment:      11,
+			activity.RepositoryComment: 2,
+			activity.Push:              1,
+			activity.IssueClosed:       1,
+			activity.WikiEdit:          1,
+			activity.Release:           1,
+			activity.Fork:              1,
+			activity.Star:              2,
+		}))
+	})
+
+	It("describes who did what, where and when", func() {
+		events, err := server.GitHubClient().Events(context.Background(), []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(find(events, "https://github.com/acme/widget/issues/1#issuecomment-2001")).To(Equal(activity.Event{
+			Actor:      "alice",
+			Kind:       activity.IssueComment,
+			Repository: "acme/widget",
+			Target:     "#1",
+			CreatedAt:  time.Date(2017, time.February, 2, 9, 0, 0, 0, time.UTC),
+			Body:       "Thanks, looking into it.",
+			URL:        "https://github.com/acme/widget/issues/1#issuecomment-2001",
+		}))
+
+		commitComment := find(events, "https://github.com/acme/widget/commit/0000000000000000000000000000000000000bba#commitcomment-3002")
+		Expect(commitComment.Actor).To(Equal("carol"))
+		Expect(commitComment.Kind.IsComment()).To(BeTrue())
+		Expect(commitComment.Target).To(Equal("0000000000000000000000000000000000000bba"))
+	})
+
+	It("leaves out events that do not say when they happened", func() {
+		submitted := time.Date(2017, time.June, 6, 12, 0, 0, 0, time.UTC)
+
+		events := gh.Dated([]activity.Event{
+			gh.ReviewEvent(&github.PullRequestReview{User: &github.User{Login: github.String("bob")}, State: github.String("APPROVED"), SubmittedAt: &submitted}),
+			gh.ReviewEvent(&github.PullRequestReview{User: &github.User{Login: github.String("frank")}, State: github.String("PENDING")}),
+		})
+
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Actor).To(Equal("bob"))
+	})
+})
@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"path"
+
+	"github.com/google/go-github/github"
+)
+
+// RepositoryFilter selects repositories by glob patterns (as in path.Match)
+// matched against either the repository's name or its "owner/name".
+type RepositoryFilter struct {
+	// Include, if not empty, lists the patterns a repository must match at
+	// least one of.
+	Include []string
+
+	// Exclude lists patterns that rule a repository out, even if it matches
+	// an Include pattern.
+	Exclude []string
+}
+
+func (f RepositoryFilter) Allows(repo *github.Repository) bool {
+	if len(f.Include) > 0 && !matchesAny(f.Include, repo) {
+		return false
+	}
+
+	return !matchesAny(f.Exclude, repo)
+}
+
+func matchesAny(patterns []string, repo *github.Repository) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, repo.GetName()); matched {
+			return true
+		}
+
+		if matched, _ := path.Match(pattern, repo.GetFullName()); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,126 @@
+package gh
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Fork is a fork of a repository and how its default branch compares to the
+// default branch of the repository it was forked from.
+type Fork struct {
+	Repository *github.Repository `json:"repository"`
+
+	// Status is "ahead", "behind", "diverged" or "identical", or empty if
+	// the branches could not be compared, e.g. because the fork's default
+	// branch no longer shares any history with the upstream's.
+	Status   string `json:"status,omitempty"`
+	AheadBy  int    `json:"ahead_by"`
+	BehindBy int    `json:"behind_by"`
+}
+
+// ForksForOrganizations returns the forks of every repository of the
+// organizations that passes the repository filter, keyed by the full name of
+// the forked repository.
+func (client *Client) ForksForOrganizations(ctx context.Context, orgs []string) (map[string][]*Fork, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("forks", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*Fork{}
+	for _, repo := range repos {
+		forks, err := client.AllForksForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = forks
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// AllForksForRepository returns the repository's direct forks, except those
+// owned by excluded users, each compared to the repository. Comparing takes
+// one request per fork.
+func (client *Client) AllForksForRepository(
+	ctx context.Context,
+	repo *github.Repository,
+) ([]*Fork, error) {
+	options := &github.RepositoryListForksOptions{Sort: "oldest"}
+
+	var all []*Fork
+
+	for {
+		resources, resp, err := client.GithubClient.Repositories.ListForks(
+			ctx,
+			*repo.Owner.Login,
+			*repo.Name,
+			options,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		for _, fork := range resources {
+			if client.excludes(fork.Owner) {
+				continue
+			}
+
+			compared, err := client.compareFork(ctx, repo, fork)
+			if err != nil {
+				return nil, err
+			}
+
+			all = append(all, compared)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+func (client *Client) compareFork(ctx context.Context, upstream *github.Repository, fork *github.Repository) (*Fork, error) {
+	comparison, resp, err := client.GithubClient.Repositories.CompareCommits(
+		ctx,
+		*upstream.Owner.Login,
+		*upstream.Name,
+		upstream.GetDefaultBranch(),
+		fork.Owner.GetLogin()+":"+fork.GetDefaultBranch(),
+	)
+	if resp != nil {
+		client.Progress.PageFetched(resp.Rate)
+	}
+
+	if isNotFound(err) {
+		return &Fork{Repository: fork}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Fork{
+		Repository: fork,
+		Status:     comparison.GetStatus(),
+		AheadBy:    comparison.GetAheadBy(),
+		BehindBy:   comparison.GetBehindBy(),
+	}, nil
+}
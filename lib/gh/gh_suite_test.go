@@ -0,0 +1,13 @@
+package gh_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGh(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gh Suite")
+}
@@ -0,0 +1,263 @@
+// Package ghtest provides a fake GitHub API for tests.
+package ghtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+)
+
+const defaultPageSize = 30
+
+// Server is an in-process fake of the GitHub API serving JSON fixtures from
+// a directory. A request is answered with the file named after its path, so
+// GET /repos/acme/widget/issues serves <dir>/repos/acme/widget/issues.json
+// and GET /orgs/acme/repos serves <dir>/orgs/acme/repos.json. Paths without
+// a fixture are answered with 404 Not Found.
+//
+// Arrays are filtered by the state, since and type query parameters the way
+// GitHub does and split into pages with Link headers, and every response
+// carries rate limit headers.
+type Server struct {
+	*httptest.Server
+
+	Dir string
+
+	// PageSize caps the number of items per page, so small fixtures can
+	// exercise pagination.
+	PageSize int
+
+	// RateLimit is the number of requests each credential, identified by
+	// its Authorization header, is allowed before the server answers 403
+	// rate limit exceeded.
+	RateLimit int
+	// RateLimitReset is reported as the time the rate limit resets.
+	RateLimitReset time.Time
+
+	// EnterpriseVersion, if set, is reported the way GitHub Enterprise Server
+	// reports its release.
+	EnterpriseVersion string
+
+	lock     sync.Mutex
+	requests []string
+	used     map[string]int
+}
+
+// NewServer starts a server for the fixtures in dir.
+func NewServer(dir string) *Server {
+	s := &Server{
+		Dir:            dir,
+		PageSize:       defaultPageSize,
+		RateLimit:      5000,
+		RateLimitReset: time.Date(2017, time.July, 1, 1, 0, 0, 0, time.UTC),
+		used:           map[string]int{},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// GitHubClient returns a gh.Client for the server.
+func (s *Server) GitHubClient() *gh.Client {
+	client, err := gh.NewClientWithBaseURL(s.Server.Client(), s.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	return client
+}
+
+// Used returns how many requests were made with an Authorization header.
+func (s *Server) Used(authorization string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.used[authorization]
+}
+
+// Requests returns the path and query of every request served so far.
+func (s *Server) Requests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	s.used[r.Header.Get("Authorization")]++
+	remaining := s.RateLimit - s.used[r.Header.Get("Authorization")]
+	s.lock.Unlock()
+
+	limited := remaining < 0
+	if limited {
+		remaining = 0
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.RateLimitReset.Unix(), 10))
+
+	if s.EnterpriseVersion != "" {
+		w.Header().Set("X-GitHub-Enterprise-Version", s.EnterpriseVersion)
+	}
+
+	if limited {
+		writeError(w, http.StatusForbidden, "API rate limit exceeded for 127.0.0.1.")
+		return
+	}
+
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	payload, err := ioutil.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(strings.Trim(r.URL.Path, "/"))+".json"))
+	if os.IsNotExist(err) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var items []map[string]interface{}
+	if json.Unmarshal(payload, &items) != nil {
+		w.Write(payload)
+		return
+	}
+
+	items, err = filter(r, items)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	page, err := s.paginate(w, r, items)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(page)
+}
+
+// filter applies the query parameters GitHub filters lists by.
+func filter(r *http.Request, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	query := r.URL.Query()
+
+	state := query.Get("state")
+	if state == "" && strings.HasSuffix(r.URL.Path, "/issues") {
+		state = "open"
+	}
+
+	var since time.Time
+	if query.Get("since") != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, query.Get("since"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid since: %s", err)
+		}
+	}
+
+	filtered := []map[string]interface{}{}
+	for _, item := range items {
+		if state != "" && state != "all" && item["state"] != state {
+			continue
+		}
+
+		if !since.IsZero() {
+			updatedAt, _ := time.Parse(time.RFC3339, fmt.Sprint(item["updated_at"]))
+			if updatedAt.Before(since) {
+				continue
+			}
+		}
+
+		if query.Get("type") == "public" && item["private"] == true {
+			continue
+		}
+
+		filtered = append(filtered, item)
+	}
+
+	return filtered, nil
+}
+
+// paginate returns the requested page of items and sets the Link header
+// pointing at the next and last pages.
+func (s *Server) paginate(w http.ResponseWriter, r *http.Request, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	query := r.URL.Query()
+
+	perPage := defaultPageSize
+	if query.Get("per_page") != "" {
+		var err error
+		perPage, err = strconv.Atoi(query.Get("per_page"))
+		if err != nil || perPage < 1 {
+			return nil, fmt.Errorf("invalid per_page: %s", query.Get("per_page"))
+		}
+	}
+
+	if s.PageSize > 0 && perPage > s.PageSize {
+		perPage = s.PageSize
+	}
+
+	page := 1
+	if query.Get("page") != "" {
+		var err error
+		page, err = strconv.Atoi(query.Get("page"))
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("invalid page: %s", query.Get("page"))
+		}
+	}
+
+	lastPage := (len(items) + perPage - 1) / perPage
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	link := func(n int, rel string) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(n))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, s.URL, r.URL.Path, q.Encode(), rel)
+	}
+
+	if page < lastPage {
+		w.Header().Set("Link", link(page+1, "next")+", "+link(lastPage, "last"))
+	}
+
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return items[start:end], nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":           message,
+		"documentation_url": "https://developer.github.com/v3",
+	})
+}
@@ -2,23 +2,77 @@ package gh
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 )
 
 var publicReposFilter = github.RepositoryListByOrgOptions{Type: "public"}
 var openIssuesFilter = github.IssueListByRepoOptions{State: "open"}
+var allIssuesFilter = github.IssueListByRepoOptions{State: "all"}
 
 type Client struct {
 	GithubClient *github.Client
+	Progress     Progress
+
+	// RepositoryFilter limits which of an organization's public repositories
+	// are crawled.
+	RepositoryFilter RepositoryFilter
+
+	// ExcludedUsers are logins, such as bots, whose issues, comments and
+	// events are dropped from every result.
+	ExcludedUsers map[string]bool
+
+	// Version is the server being crawled. Features it does not support are
+	// skipped rather than failing the crawl.
+	Version ServerVersion
 }
 
 func NewClient(githubClient *github.Client) *Client {
 	return &Client{
 		GithubClient: githubClient,
+		Progress:     nopProgress{},
 	}
 }
 
+// NewClientWithBaseURL returns a client for the GitHub API served at
+// baseURL, such as a fake server in tests, instead of api.github.com.
+func NewClientWithBaseURL(httpClient *http.Client, baseURL string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	githubClient := github.NewClient(httpClient)
+	githubClient.BaseURL = u
+
+	return NewClient(githubClient), nil
+}
+
+// PublicRepositoriesForOrganizations returns the public repositories of every
+// given organization that pass the repository filter.
+func (client *Client) PublicRepositoriesForOrganizations(ctx context.Context, orgs []string) ([]*github.Repository, error) {
+	var all []*github.Repository
+	for _, org := range orgs {
+		repos, err := client.PublicRepositories(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, repos...)
+	}
+
+	return all, nil
+}
+
 func (client *Client) PublicRepositories(ctx context.Context, org string) ([]*github.Repository, error) {
 	options := publicReposFilter
 
@@ -34,11 +88,17 @@ func (client *Client) PublicRepositories(ctx context.Context, org string) ([]*gi
 			return nil, err
 		}
 
+		client.Progress.PageFetched(resp.Rate)
+
 		if len(resources) == 0 {
 			break
 		}
 
-		all = append(all, resources...)
+		for _, repo := range resources {
+			if client.RepositoryFilter.Allows(repo) {
+				all = append(all, repo)
+			}
+		}
 
 		if resp.NextPage == 0 {
 			break
@@ -50,12 +110,15 @@ func (client *Client) PublicRepositories(ctx context.Context, org string) ([]*gi
 	return all, nil
 }
 
-func (client *Client) AllIssuesForOrganization(ctx context.Context, org string) ([]*github.Issue, error) {
-	repos, err := client.PublicRepositories(ctx, org)
+func (client *Client) AllIssuesForOrganizations(ctx context.Context, orgs []string) ([]*github.Issue, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
 	if err != nil {
 		return nil, err
 	}
 
+	client.Progress.Begin("issues", len(repos))
+	defer client.Progress.End()
+
 	var all []*github.Issue
 	for _, repo := range repos {
 		issues, err := client.AllIssues(ctx, repo)
@@ -64,17 +127,70 @@ func (client *Client) AllIssuesForOrganization(ctx context.Context, org string)
 		}
 
 		all = append(all, issues...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
 	}
 
 	return all, nil
 }
 
-func (client *Client) AllRepositoryCommentsForOrganization(ctx context.Context, org string) ([]*github.RepositoryComment, error) {
-	repos, err := client.PublicRepositories(ctx, org)
+func (client *Client) IssuesUpdatedSinceForOrganizations(ctx context.Context, orgs []string, since time.Time) ([]*github.Issue, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
 	if err != nil {
 		return nil, err
 	}
 
+	client.Progress.Begin("issues", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.Issue
+	for _, repo := range repos {
+		issues, err := client.IssuesUpdatedSince(ctx, repo, since)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, issues...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllIssueEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueEvent, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("issue events", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.IssueEvent
+	for _, repo := range repos {
+		events, err := client.AllIssueEventsForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, events...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllRepositoryCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.RepositoryComment, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("repository comments", len(repos))
+	defer client.Progress.End()
+
 	var all []*github.RepositoryComment
 	for _, repo := range repos {
 		issues, err := client.AllCommentsForRepository(ctx, repo)
@@ -83,17 +199,22 @@ func (client *Client) AllRepositoryCommentsForOrganization(ctx context.Context,
 		}
 
 		all = append(all, issues...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
 	}
 
 	return all, nil
 }
 
-func (client *Client) AllIssueCommentsForOrganization(ctx context.Context, org string) ([]*github.IssueComment, error) {
-	repos, err := client.PublicRepositories(ctx, org)
+func (client *Client) AllIssueCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueComment, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
 	if err != nil {
 		return nil, err
 	}
 
+	client.Progress.Begin("issue comments", len(repos))
+	defer client.Progress.End()
+
 	var all []*github.IssueComment
 	for _, repo := range repos {
 		issues, err := client.AllIssueCommentsForRepository(ctx, repo)
@@ -102,14 +223,33 @@ func (client *Client) AllIssueCommentsForOrganization(ctx context.Context, org s
 		}
 
 		all = append(all, issues...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
 	}
 
 	return all, nil
 }
 
 func (client *Client) AllIssues(ctx context.Context, repo *github.Repository) ([]*github.Issue, error) {
-	options := openIssuesFilter
+	return client.listIssues(ctx, repo, openIssuesFilter)
+}
+
+// IssuesUpdatedSince returns open and closed issues in the repository that
+// have been updated since the given time.
+func (client *Client) IssuesUpdatedSince(ctx context.Context, repo *github.Repository, since time.Time) ([]*github.Issue, error) {
+	options := allIssuesFilter
+	options.Since = since
+
+	return client.listIssues(ctx, repo, options)
+}
 
+// AllIssuesIncludingClosed returns every open and closed issue in the
+// repository.
+func (client *Client) AllIssuesIncludingClosed(ctx context.Context, repo *github.Repository) ([]*github.Issue, error) {
+	return client.listIssues(ctx, repo, allIssuesFilter)
+}
+
+func (client *Client) listIssues(ctx context.Context, repo *github.Repository, options github.IssueListByRepoOptions) ([]*github.Issue, error) {
 	var all []*github.Issue
 
 	for {
@@ -123,11 +263,17 @@ func (client *Client) AllIssues(ctx context.Context, repo *github.Repository) ([
 			return nil, err
 		}
 
+		client.Progress.PageFetched(resp.Rate)
+
 		if len(resources) == 0 {
 			break
 		}
 
-		all = append(all, resources...)
+		for _, issue := range resources {
+			if !client.excludes(issue.User) {
+				all = append(all, issue)
+			}
+		}
 
 		if resp.NextPage == 0 {
 			break
@@ -158,11 +304,17 @@ func (client *Client) AllCommentsForRepository(
 			return nil, err
 		}
 
+		client.Progress.PageFetched(resp.Rate)
+
 		if len(resources) == 0 {
 			break
 		}
 
-		all = append(all, resources...)
+		for _, comment := range resources {
+			if !client.excludes(comment.User) {
+				all = append(all, comment)
+			}
+		}
 
 		if resp.NextPage == 0 {
 			break
@@ -195,11 +347,17 @@ func (client *Client) AllIssueCommentsForRepository(
 			return nil, err
 		}
 
+		client.Progress.PageFetched(resp.Rate)
+
 		if len(resources) == 0 {
 			break
 		}
 
-		all = append(all, resources...)
+		for _, comment := range resources {
+			if !client.excludes(comment.User) {
+				all = append(all, comment)
+			}
+		}
 
 		if resp.NextPage == 0 {
 			break
@@ -210,3 +368,199 @@ func (client *Client) AllIssueCommentsForRepository(
 
 	return all, nil
 }
+
+func (client *Client) AllIssueEventsForRepository(
+	ctx context.Context,
+	repo *github.Repository,
+) ([]*github.IssueEvent, error) {
+	options := &github.ListOptions{}
+
+	var all []*github.IssueEvent
+
+	for {
+		resources, resp, err := client.GithubClient.Issues.ListRepositoryEvents(
+			ctx,
+			*repo.Owner.Login,
+			*repo.Name,
+			options,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		for _, event := range resources {
+			if !client.excludes(event.Actor) {
+				all = append(all, event)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllMembersForOrganizations(ctx context.Context, orgs []string) ([]*github.User, error) {
+	var all []*github.User
+	for _, org := range orgs {
+		members, err := client.OrganizationMembers(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, members...)
+	}
+
+	return all, nil
+}
+
+func (client *Client) OrganizationMembers(ctx context.Context, org string) ([]*github.User, error) {
+	options := &github.ListMembersOptions{}
+
+	var all []*github.User
+
+	for {
+		resources, resp, err := client.GithubClient.Organizations.ListMembers(
+			ctx,
+			org,
+			options,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		all = append(all, resources...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.ListOptions.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+func (client *Client) ProgressReporter() Progress {
+	return client.Progress
+}
+
+func (client *Client) excludes(user *github.User) bool {
+	return user != nil && client.ExcludedUsers[user.GetLogin()]
+}
+
+// AllReviewsForIssues returns the reviews of every pull request among the
+// issues, which may belong to several repositories, so that a report that
+// has crawled the issues already need not crawl them again.
+func (client *Client) AllReviewsForIssues(ctx context.Context, issues []*github.Issue) ([]*github.PullRequestReview, error) {
+	repos, grouped := GroupIssuesByRepository(issues)
+
+	client.Progress.Begin("reviews", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.PullRequestReview
+	for _, repo := range repos {
+		reviews, err := client.AllReviewsForPullRequests(ctx, repo, grouped[repo.GetFullName()])
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, reviews...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// AllReviewsForPullRequests returns the reviews of every pull request among
+// the given issues of the repository, or none if the server does not support
+// reviews.
+func (client *Client) AllReviewsForPullRequests(
+	ctx context.Context,
+	repo *github.Repository,
+	issues []*github.Issue,
+) ([]*github.PullRequestReview, error) {
+	if !client.Version.Supports(FeatureReviews) {
+		return nil, nil
+	}
+
+	var all []*github.PullRequestReview
+
+	for _, issue := range issues {
+		if issue.PullRequestLinks == nil {
+			continue
+		}
+
+		options := &github.ListOptions{}
+
+		for {
+			resources, resp, err := client.listReviews(ctx, repo, issue.GetNumber(), options)
+			if err != nil {
+				return nil, err
+			}
+
+			client.Progress.PageFetched(resp.Rate)
+
+			for _, review := range resources {
+				if !client.excludes(review.User) {
+					all = append(all, review)
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+
+			options.Page = resp.NextPage
+		}
+	}
+
+	return all, nil
+}
+
+// listReviews lists one page of a pull request's reviews. The vendored
+// PullRequests.ListReviews takes no list options, so it only ever returns the
+// first page.
+func (client *Client) listReviews(
+	ctx context.Context,
+	repo *github.Repository,
+	number int,
+	options *github.ListOptions,
+) ([]*github.PullRequestReview, *github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/pulls/%d/reviews", repo.Owner.GetLogin(), repo.GetName(), number)
+	if options.Page != 0 {
+		u += fmt.Sprintf("?page=%d", options.Page)
+	}
+
+	req, err := client.GithubClient.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.black-cat-preview+json")
+
+	var reviews []*github.PullRequestReview
+	resp, err := client.GithubClient.Do(ctx, req, &reviews)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return reviews, resp, nil
+}
@@ -0,0 +1,402 @@
+package gh_test
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingProgress struct {
+	phases       []string
+	repositories []string
+	pages        int
+	remaining    []int
+}
+
+func (p *recordingProgress) Begin(phase string, repositories int) { p.phases = append(p.phases, phase) }
+func (p *recordingProgress) RepositoryDone(repository string) {
+	p.repositories = append(p.repositories, repository)
+}
+func (p *recordingProgress) PageFetched(rate github.Rate) {
+	p.pages++
+	p.remaining = append(p.remaining, rate.Remaining)
+}
+func (p *recordingProgress) End() {}
+
+var _ = Describe("Client", func() {
+	var (
+		ctx    context.Context
+		server *ghtest.Server
+		client *gh.Client
+		orgs   []string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = ghtest.NewServer("ghtest/testdata/acme")
+		client = server.GitHubClient()
+		orgs = []string{"acme"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fullNames := func(repos []*github.Repository) []string {
+		var names []string
+		for _, repo := range repos {
+			names = append(names, repo.GetFullName())
+		}
+
+		sort.Strings(names)
+		return names
+	}
+
+	numbers := func(issues []*github.Issue) []string {
+		var refs []string
+		for _, i := range issues {
+			refs = append(refs, gh.IssueRepository(i)+"#"+strconv.Itoa(i.GetNumber()))
+		}
+
+		sort.Strings(refs)
+		return refs
+	}
+
+	Describe("PublicRepositoriesForOrganizations", func() {
+		It("returns only the public repositories", func() {
+			repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fullNames(repos)).To(Equal([]string{"acme/gadget", "acme/widget"}))
+		})
+
+		It("applies the repository filter", func() {
+			client.RepositoryFilter = gh.RepositoryFilter{Exclude: []string{"acme/gad*"}}
+
+			repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fullNames(repos)).To(Equal([]string{"acme/widget"}))
+		})
+
+		It("fails for an organization without fixtures", func() {
+			_, err := client.PublicRepositoriesForOrganizations(ctx, []string{"nobody"})
+			Expect(err).To(HaveOccurred())
+
+			errResp, ok := err.(*github.ErrorResponse)
+			Expect(ok).To(BeTrue())
+			Expect(errResp.Response.StatusCode).To(Equal(404))
+		})
+	})
+
+	Describe("AllIssuesForOrganizations", func() {
+		It("returns the open issues and pull requests of every repository", func() {
+			issues, err := client.AllIssuesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numbers(issues)).To(Equal([]string{
+				"acme/gadget#1",
+				"acme/gadget#2",
+				"acme/widget#1",
+				"acme/widget#3",
+				"acme/widget#5",
+			}))
+		})
+
+		It("follows every page", func() {
+			server.PageSize = 2
+			progress := &recordingProgress{}
+			client.Progress = progress
+
+			issues, err := client.AllIssuesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(issues).To(HaveLen(5))
+
+			Expect(progress.phases).To(Equal([]string{"issues"}))
+			Expect(progress.repositories).To(ConsistOf("acme/widget", "acme/gadget"))
+
+			// One page of repositories, two pages of widget issues and one of
+			// gadget issues.
+			Expect(progress.pages).To(Equal(4))
+			Expect(server.Requests()).To(ContainElement(ContainSubstring("/repos/acme/widget/issues?page=2")))
+		})
+
+		It("drops issues opened by excluded users", func() {
+			client.ExcludedUsers = map[string]bool{"carol": true}
+
+			issues, err := client.AllIssuesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numbers(issues)).To(Equal([]string{"acme/gadget#1", "acme/widget#3"}))
+		})
+	})
+
+	Describe("AllIssuesIncludingClosed", func() {
+		It("includes closed issues and issues opened by deleted users", func() {
+			repos, err := client.PublicRepositories(ctx, "acme")
+			Expect(err).NotTo(HaveOccurred())
+
+			var all []*github.Issue
+			for _, repo := range repos {
+				issues, err := client.AllIssuesIncludingClosed(ctx, repo)
+				Expect(err).NotTo(HaveOccurred())
+				all = append(all, issues...)
+			}
+
+			Expect(all).To(HaveLen(9))
+
+			var logins []string
+			for _, i := range all {
+				logins = append(logins, i.User.GetLogin())
+			}
+
+			Expect(logins).To(ContainElement("ghost"))
+		})
+	})
+
+	Describe("AllIssueCommentsForOrganizations", func() {
+		It("keeps comments whose author is unknown", func() {
+			comments, err := client.AllIssueCommentsForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(comments).To(HaveLen(11))
+
+			var anonymous int
+			for _, c := range comments {
+				if c.User == nil {
+					anonymous++
+				}
+			}
+
+			Expect(anonymous).To(Equal(1))
+		})
+	})
+
+	Describe("AllMembersForOrganizations", func() {
+		It("returns every member", func() {
+			members, err := client.AllMembersForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			var logins []string
+			for _, m := range members {
+				logins = append(logins, m.GetLogin())
+			}
+
+			Expect(logins).To(ConsistOf("alice", "bob"))
+		})
+	})
+
+	Describe("StreamEventsForOrganizations", func() {
+		ids := func(events []*github.Event) []string {
+			var ids []string
+			for _, e := range events {
+				ids = append(ids, e.GetID())
+			}
+
+			return ids
+		}
+
+		It("merges the organization and repository streams once each, oldest first", func() {
+			events, err := client.StreamEventsForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ids(events)).To(Equal([]string{"9001", "9002", "9003", "9004", "9005", "9007", "9008", "9009"}))
+		})
+
+		It("drops events by excluded users and in filtered repositories", func() {
+			client.ExcludedUsers = map[string]bool{"carol": true}
+			client.RepositoryFilter = gh.RepositoryFilter{Exclude: []string{"acme/gadget"}}
+
+			events, err := client.StreamEventsForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ids(events)).To(Equal([]string{"9001", "9004", "9005", "9007"}))
+		})
+
+		It("converts the kinds of events the listings do not cover", func() {
+			events, err := client.StreamEventsForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			var converted []activity.Event
+			for _, e := range events {
+				if event, ok := gh.StreamEvent(e); ok {
+					converted = append(converted, event)
+				}
+			}
+
+			Expect(converted).To(HaveLen(7))
+			Expect(converted[0]).To(Equal(activity.Event{
+				Actor:      "alice",
+				Kind:       activity.Push,
+				Repository: "acme/widget",
+				Target:     "refs/heads/master",
+				CreatedAt:  time.Date(2017, time.June, 10, 9, 0, 0, 0, time.UTC),
+			}))
+			Expect(converted[4].Kind).To(Equal(activity.IssueClosed))
+			Expect(converted[4].Target).To(Equal("#2"))
+			Expect(converted[5].Kind).To(Equal(activity.Release))
+			Expect(converted[5].Target).To(Equal("v1.0.0"))
+			Expect(converted[5].Body).To(Equal("Gadget 1.0"))
+		})
+	})
+
+	Describe("StargazersForOrganizations", func() {
+		It("lists every repository's stargazers with when they starred", func() {
+			stargazers, err := client.StargazersForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stargazers).To(HaveLen(2))
+
+			widget := stargazers["acme/widget"]
+			Expect(widget).To(HaveLen(4))
+			Expect(widget[0].User.GetLogin()).To(Equal("alice"))
+			Expect(widget[0].GetStarredAt().Time).To(Equal(time.Date(2017, time.February, 1, 10, 0, 0, 0, time.UTC)))
+		})
+
+		It("drops excluded users", func() {
+			client.ExcludedUsers = map[string]bool{"carol": true}
+
+			stargazers, err := client.StargazersForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stargazers["acme/widget"]).To(HaveLen(3))
+			Expect(stargazers["acme/gadget"]).To(HaveLen(2))
+		})
+	})
+
+	Describe("WatchersForOrganizations", func() {
+		It("lists every repository's watchers", func() {
+			watchers, err := client.WatchersForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(watchers["acme/widget"]).To(HaveLen(2))
+			Expect(watchers["acme/gadget"]).To(HaveLen(1))
+		})
+	})
+
+	Describe("ForksForOrganizations", func() {
+		It("compares each fork's default branch to the upstream's", func() {
+			forks, err := client.ForksForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(forks["acme/widget"]).To(HaveLen(3))
+
+			frank := forks["acme/widget"][2]
+			Expect(frank.Repository.GetFullName()).To(Equal("frank/widget"))
+			Expect(frank.Status).To(Equal("diverged"))
+			Expect(frank.AheadBy).To(Equal(5))
+			Expect(frank.BehindBy).To(Equal(1))
+			Expect(server.Requests()).To(ContainElement("/repos/acme/widget/compare/master...frank:master"))
+		})
+
+		It("leaves the status empty when the branches cannot be compared", func() {
+			forks, err := client.ForksForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			erin := forks["acme/gadget"][1]
+			Expect(erin.Repository.GetFullName()).To(Equal("erin/gadget"))
+			Expect(erin.Status).To(BeEmpty())
+		})
+
+		It("drops forks owned by excluded users", func() {
+			client.ExcludedUsers = map[string]bool{"frank": true}
+
+			forks, err := client.ForksForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(forks["acme/widget"]).To(HaveLen(2))
+			Expect(server.Requests()).NotTo(ContainElement(ContainSubstring("frank:master")))
+		})
+	})
+
+	Describe("AllReviewsForPullRequests", func() {
+		It("follows every page of each pull request's reviews", func() {
+			server.PageSize = 1
+
+			repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			widget := repos[0]
+			Expect(widget.GetFullName()).To(Equal("acme/widget"))
+
+			issues, err := client.AllIssuesIncludingClosed(ctx, widget)
+			Expect(err).NotTo(HaveOccurred())
+
+			reviews, err := client.AllReviewsForPullRequests(ctx, widget, issues)
+			Expect(err).NotTo(HaveOccurred())
+
+			var ids []int
+			for _, review := range reviews {
+				ids = append(ids, review.GetID())
+			}
+
+			Expect(ids).To(ConsistOf(6001, 6002, 6003, 6004))
+			Expect(server.Requests()).To(ContainElement("/repos/acme/widget/pulls/6/reviews?page=2"))
+		})
+	})
+
+	Describe("ReleasesForOrganizations", func() {
+		It("lists published releases oldest first, leaving out drafts", func() {
+			releases, err := client.ReleasesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			widget := releases["acme/widget"]
+			Expect(widget).To(HaveLen(3))
+			Expect(widget[0].Release.GetTagName()).To(Equal("v1.0.0"))
+			Expect(widget[1].Downloads()).To(Equal(375))
+			Expect(widget[2].Release.GetPrerelease()).To(BeTrue())
+			Expect(widget[2].Previous).To(BeNil())
+		})
+
+		It("remembers the download count of an earlier crawl", func() {
+			earlier, err := client.ReleasesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			later, err := client.ReleasesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+
+			release := later["acme/widget"][0]
+			release.CollectedAt = release.CollectedAt.Add(time.Hour)
+			release.Follow(earlier["acme/widget"][0])
+			Expect(release.Previous.Downloads).To(Equal(410))
+			Expect(release.Previous.CollectedAt).To(Equal(earlier["acme/widget"][0].CollectedAt))
+		})
+	})
+
+	Describe("TrafficForOrganizations", func() {
+		It("crawls the traffic of the repositories the token may see", func() {
+			traffic, err := client.TrafficForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(traffic).To(HaveKey("acme/widget"))
+			Expect(traffic).NotTo(HaveKey("acme/gadget"))
+
+			widget := traffic["acme/widget"]
+			Expect(widget).To(HaveLen(1))
+			Expect(widget[0].Views).To(HaveLen(13))
+			Expect(widget[0].Clones).To(HaveLen(8))
+			Expect(widget[0].Referrers[0].GetReferrer()).To(Equal("github.com"))
+			Expect(widget[0].Paths[0].GetPath()).To(Equal("/acme/widget"))
+		})
+	})
+
+	Describe("rate limiting", func() {
+		It("reports the remaining quota of every page", func() {
+			server.RateLimit = 10
+			progress := &recordingProgress{}
+			client.Progress = progress
+
+			_, err := client.AllIssuesForOrganizations(ctx, orgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(progress.remaining).To(Equal([]int{9, 8, 7}))
+		})
+
+		It("fails with a rate limit error once the quota is exhausted", func() {
+			server.RateLimit = 2
+
+			_, err := client.AllIssuesForOrganizations(ctx, orgs)
+			Expect(err).To(HaveOccurred())
+
+			rateErr, ok := err.(*github.RateLimitError)
+			Expect(ok).To(BeTrue())
+			Expect(rateErr.Rate.Remaining).To(Equal(0))
+			Expect(rateErr.Rate.Reset.Unix()).To(Equal(server.RateLimitReset.Unix()))
+		})
+	})
+})
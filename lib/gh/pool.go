@@ -0,0 +1,263 @@
+package gh
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// TokenPool authenticates each request with whichever of several access
+// tokens has the most rate limit quota left, so a crawl can use the combined
+// quota of all of them. A token that runs out is parked until its rate limit
+// resets.
+//
+// Responses report the pool's combined rate limit rather than the limit of
+// the token that served them, so the client only considers itself rate
+// limited once every token is.
+type TokenPool struct {
+	Base   http.RoundTripper
+	Logger lager.Logger
+
+	// Now is the clock rate limit resets are compared with; it defaults to
+	// time.Now.
+	Now func() time.Time
+
+	lock   sync.Mutex
+	tokens []*pooledToken
+}
+
+type pooledToken struct {
+	name  string
+	token string
+
+	requests int
+
+	// known is set once a response has reported the token's rate limit.
+	known     bool
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// NewTokenPool returns a pool of tokens. Tokens are named token-1, token-2
+// and so on in logs, in the order given, so that they are never logged
+// themselves.
+func NewTokenPool(tokens []string, base http.RoundTripper, logger lager.Logger) *TokenPool {
+	pool := &TokenPool{
+		Base:   base,
+		Logger: logger.Session("token-pool"),
+		Now:    time.Now,
+	}
+
+	for i, token := range tokens {
+		pool.tokens = append(pool.tokens, &pooledToken{
+			name:  fmt.Sprintf("token-%d", i+1),
+			token: token,
+		})
+	}
+
+	return pool
+}
+
+func (p *TokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	tried := map[*pooledToken]bool{}
+
+	for {
+		t := p.pick(tried)
+		tried[t] = true
+
+		authenticated := cloneRequest(req)
+		authenticated.Header.Set("Authorization", "token "+t.token)
+
+		resp, err := p.Base.RoundTrip(authenticated)
+		if err != nil {
+			return nil, err
+		}
+
+		exhausted := p.record(t, resp)
+
+		// A token that was rate limited before the pool knew it was running
+		// low is retried with another token, as long as there is one left
+		// and the request can be sent again.
+		retry := exhausted && resp.StatusCode == http.StatusForbidden && len(tried) < len(p.tokens) && (req.Body == nil || req.GetBody != nil)
+		if !retry {
+			p.aggregate(resp)
+			return resp, nil
+		}
+
+		resp.Body.Close()
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = cloneRequest(req)
+			req.Body = body
+		}
+	}
+}
+
+// pick returns the token with the most quota left that has not been tried
+// yet. Tokens whose rate limit is unknown are tried before any other, and
+// parked tokens only once every token is parked, starting with the one that
+// resets first.
+func (p *TokenPool) pick(tried map[*pooledToken]bool) *pooledToken {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.Now()
+
+	var best, earliest *pooledToken
+	for _, t := range p.tokens {
+		if tried[t] {
+			continue
+		}
+
+		if t.known && t.remaining == 0 && now.Before(t.reset) {
+			if earliest == nil || t.reset.Before(earliest.reset) {
+				earliest = t
+			}
+
+			continue
+		}
+
+		if t.known && t.remaining == 0 {
+			p.Logger.Info("token-reset", lager.Data{"token": t.name})
+			t.known = false
+		}
+
+		if best == nil || quota(t) > quota(best) {
+			best = t
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+
+	if earliest != nil {
+		return earliest
+	}
+
+	return p.tokens[0]
+}
+
+func quota(t *pooledToken) int {
+	if !t.known {
+		return int(^uint(0) >> 1)
+	}
+
+	return t.remaining
+}
+
+// record updates a token's rate limit from a response and returns whether
+// the token is exhausted.
+func (p *TokenPool) record(t *pooledToken, resp *http.Response) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	t.requests++
+
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return false
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return false
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	t.known = true
+	t.limit = limit
+	t.remaining = remaining
+	t.reset = time.Unix(reset, 0)
+
+	if remaining == 0 {
+		p.Logger.Info("token-parked", lager.Data{
+			"token":    t.name,
+			"requests": t.requests,
+			"reset":    t.reset.UTC().Format(time.RFC3339),
+		})
+
+		return true
+	}
+
+	return false
+}
+
+// aggregate rewrites a response's rate limit headers to describe the whole
+// pool. Tokens the pool has not used yet are assumed to have the limit of
+// the token that served the response. The reset is the earliest of any
+// token's.
+func (p *TokenPool) aggregate(resp *http.Response) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	seen, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	now := p.Now()
+
+	var limit, remaining int
+	var reset time.Time
+
+	for _, t := range p.tokens {
+		if !t.known {
+			limit += seen
+			remaining += seen
+			continue
+		}
+
+		limit += t.limit
+
+		if now.Before(t.reset) {
+			remaining += t.remaining
+		} else {
+			remaining += t.limit
+		}
+
+		if reset.IsZero() || t.reset.Before(reset) {
+			reset = t.reset
+		}
+	}
+
+	resp.Header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	resp.Header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+	if !reset.IsZero() {
+		resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}
+}
+
+// LogUsage logs how many requests each token served and how much of its
+// quota is left.
+func (p *TokenPool) LogUsage() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, t := range p.tokens {
+		data := lager.Data{"token": t.name, "requests": t.requests}
+
+		if t.known {
+			data["limit"] = t.limit
+			data["remaining"] = t.remaining
+			data["reset"] = t.reset.UTC().Format(time.RFC3339)
+		}
+
+		p.Logger.Info("token-usage", data)
+	}
+}
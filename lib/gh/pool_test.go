@@ -0,0 +1,129 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenPool", func() {
+	var (
+		ctx    context.Context
+		server *ghtest.Server
+		logger *lagertest.TestLogger
+		pool   *gh.TokenPool
+		client *gh.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = ghtest.NewServer("ghtest/testdata/acme")
+		logger = lagertest.NewTestLogger("test")
+
+		pool = gh.NewTokenPool([]string{"secret-a", "secret-b"}, http.DefaultTransport, logger)
+		pool.Now = func() time.Time { return server.RateLimitReset.Add(-time.Hour) }
+
+		var err error
+		client, err = gh.NewClientWithBaseURL(&http.Client{Transport: pool}, server.URL)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	messages := func(message string) []lager.LogFormat {
+		var matching []lager.LogFormat
+		for _, log := range logger.Logs() {
+			if log.Message == "test.token-pool."+message {
+				matching = append(matching, log)
+			}
+		}
+
+		return matching
+	}
+
+	It("sends each request with the token with the most quota left", func() {
+		server.RateLimit = 3
+		progress := &recordingProgress{}
+		client.Progress = progress
+
+		_, err := client.AllIssuesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(server.Used("token secret-a")).To(Equal(2))
+		Expect(server.Used("token secret-b")).To(Equal(1))
+
+		// The client sees the quota of the whole pool.
+		Expect(progress.remaining).To(Equal([]int{5, 4, 3}))
+	})
+
+	It("parks exhausted tokens and fails once every token is exhausted", func() {
+		server.RateLimit = 2
+
+		_, err := client.AllIssuesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.PublicRepositories(ctx, "acme")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(server.Used("token secret-a")).To(Equal(2))
+		Expect(server.Used("token secret-b")).To(Equal(2))
+
+		parked := messages("token-parked")
+		Expect(parked).To(HaveLen(2))
+		Expect(parked[0].Data["token"]).To(Equal("token-1"))
+		Expect(parked[0].Data["reset"]).To(Equal("2017-07-01T01:00:00Z"))
+		Expect(parked[1].Data["token"]).To(Equal("token-2"))
+
+		_, err = client.PublicRepositories(ctx, "acme")
+		Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+	})
+
+	It("retries with another token when a token turns out to be rate limited", func() {
+		server.RateLimit = 1
+
+		for i := 0; i < 2; i++ {
+			req, err := http.NewRequest("GET", server.URL+"/orgs/acme/members", nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Authorization", "token secret-a")
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+		}
+
+		members, err := client.OrganizationMembers(ctx, "acme")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(2))
+
+		Expect(server.Used("token secret-a")).To(Equal(3))
+		Expect(server.Used("token secret-b")).To(Equal(1))
+	})
+
+	It("logs each token's usage without logging the token", func() {
+		_, err := client.AllIssuesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		pool.LogUsage()
+
+		usage := messages("token-usage")
+		Expect(usage).To(HaveLen(2))
+		Expect(usage[0].Data).To(HaveKeyWithValue("token", "token-1"))
+		Expect(usage[0].Data).To(HaveKeyWithValue("requests", BeNumerically("==", 2)))
+		Expect(usage[0].Data).To(HaveKeyWithValue("remaining", BeNumerically("==", 4998)))
+		Expect(usage[1].Data).To(HaveKeyWithValue("token", "token-2"))
+		Expect(usage[1].Data).To(HaveKeyWithValue("requests", BeNumerically("==", 1)))
+
+		Expect(string(logger.Buffer().Contents())).NotTo(ContainSubstring("secret"))
+	})
+})
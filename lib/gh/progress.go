@@ -0,0 +1,32 @@
+package gh
+
+import "github.com/google/go-github/github"
+
+// Progress is notified as the client crawls an organization so that long runs
+// can report how far along they are.
+type Progress interface {
+	// Begin starts a new phase of the crawl that visits the given number of
+	// repositories.
+	Begin(phase string, repositories int)
+
+	// RepositoryDone is called once every page for a repository in the current
+	// phase has been fetched.
+	RepositoryDone(repository string)
+
+	// PageFetched is called for every page returned by the API, with the rate
+	// limit reported alongside it.
+	PageFetched(rate github.Rate)
+
+	// End finishes the current phase.
+	End()
+}
+
+// NopProgress ignores progress.
+var NopProgress Progress = nopProgress{}
+
+type nopProgress struct{}
+
+func (nopProgress) Begin(string, int)       {}
+func (nopProgress) RepositoryDone(string)   {}
+func (nopProgress) PageFetched(github.Rate) {}
+func (nopProgress) End()                    {}
@@ -0,0 +1,129 @@
+package gh
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Release is a published release of a repository as crawled at one point in
+// time, with how often its assets had been downloaded when it was crawled
+// before.
+type Release struct {
+	Release     *github.RepositoryRelease `json:"release"`
+	CollectedAt time.Time                 `json:"collected_at"`
+
+	// Previous is nil if the release was not crawled before.
+	Previous *ReleaseDownloads `json:"previous,omitempty"`
+}
+
+// ReleaseDownloads is a release's download count as of an earlier crawl.
+type ReleaseDownloads struct {
+	CollectedAt time.Time `json:"collected_at"`
+	Downloads   int       `json:"downloads"`
+}
+
+// Downloads returns the number of times the release's assets have been
+// downloaded, which GitHub counts from when each asset was uploaded.
+func (r *Release) Downloads() int {
+	downloads := 0
+	for _, asset := range r.Release.Assets {
+		downloads += asset.GetDownloadCount()
+	}
+
+	return downloads
+}
+
+// Follow records earlier, the same release as crawled before, as r's
+// previous download count. If both come from the same crawl, r keeps
+// earlier's previous count instead, so recording a crawl twice changes
+// nothing.
+func (r *Release) Follow(earlier *Release) {
+	if earlier.CollectedAt.Before(r.CollectedAt) {
+		r.Previous = &ReleaseDownloads{CollectedAt: earlier.CollectedAt, Downloads: earlier.Downloads()}
+	} else if r.Previous == nil {
+		r.Previous = earlier.Previous
+	}
+}
+
+// ReleasesForOrganizations returns the published releases of every
+// repository of the organizations that passes the repository filter, keyed
+// by the repository's full name.
+func (client *Client) ReleasesForOrganizations(ctx context.Context, orgs []string) (map[string][]*Release, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("releases", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*Release{}
+	for _, repo := range repos {
+		releases, err := client.AllReleasesForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = releases
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// AllReleasesForRepository returns the repository's published releases,
+// oldest first. Drafts are left out.
+func (client *Client) AllReleasesForRepository(
+	ctx context.Context,
+	repo *github.Repository,
+) ([]*Release, error) {
+	options := &github.ListOptions{}
+	collectedAt := time.Now().UTC()
+
+	var all []*Release
+
+	for {
+		resources, resp, err := client.GithubClient.Repositories.ListReleases(
+			ctx,
+			*repo.Owner.Login,
+			*repo.Name,
+			options,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		for _, release := range resources {
+			if !release.GetDraft() && release.PublishedAt != nil {
+				all = append(all, &Release{Release: release, CollectedAt: collectedAt})
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.Page = resp.NextPage
+	}
+
+	SortReleases(all)
+
+	return all, nil
+}
+
+// SortReleases orders releases by when they were published, oldest first.
+func SortReleases(releases []*Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Release.GetPublishedAt().Before(releases[j].Release.GetPublishedAt().Time)
+	})
+}
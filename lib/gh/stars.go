@@ -0,0 +1,147 @@
+package gh
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// StargazersForOrganizations returns the stargazers of every repository of
+// the organizations that passes the repository filter, keyed by the
+// repository's full name.
+func (client *Client) StargazersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.Stargazer, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("stargazers", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*github.Stargazer{}
+	for _, repo := range repos {
+		stargazers, err := client.AllStargazersForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = stargazers
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// WatchersForOrganizations returns the watchers of every repository of the
+// organizations that passes the repository filter, keyed by the repository's
+// full name.
+func (client *Client) WatchersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.User, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("watchers", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*github.User{}
+	for _, repo := range repos {
+		watchers, err := client.AllWatchersForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = watchers
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// AllStargazersForRepository returns everyone who starred the repository,
+// oldest star first, with when they starred it.
+func (client *Client) AllStargazersForRepository(
+	ctx context.Context,
+	repo *github.Repository,
+) ([]*github.Stargazer, error) {
+	options := &github.ListOptions{}
+
+	var all []*github.Stargazer
+
+	for {
+		resources, resp, err := client.GithubClient.Activity.ListStargazers(
+			ctx,
+			*repo.Owner.Login,
+			*repo.Name,
+			options,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		for _, stargazer := range resources {
+			if !client.excludes(stargazer.User) {
+				all = append(all, stargazer)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// AllWatchersForRepository returns everyone watching the repository. GitHub
+// does not say since when.
+func (client *Client) AllWatchersForRepository(
+	ctx context.Context,
+	repo *github.Repository,
+) ([]*github.User, error) {
+	options := &github.ListOptions{}
+
+	var all []*github.User
+
+	for {
+		resources, resp, err := client.GithubClient.Activity.ListWatchers(
+			ctx,
+			*repo.Owner.Login,
+			*repo.Name,
+			options,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		for _, user := range resources {
+			if !client.excludes(user) {
+				all = append(all, user)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.Page = resp.NextPage
+	}
+
+	return all, nil
+}
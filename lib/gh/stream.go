@@ -0,0 +1,187 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/google/go-github/github"
+)
+
+// StreamEventsForOrganizations returns the recent public events of the
+// organizations and of each of their repositories that pass the repository
+// filter, oldest first. GitHub only keeps the latest 300 events of each
+// stream, so pushes, forks, stars and the like are only complete if the
+// streams are read at least that often; an event seen in both the
+// organization's and a repository's stream is returned once.
+func (client *Client) StreamEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.Event, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[string]bool{}
+	for _, repo := range repos {
+		allowed[strings.ToLower(repo.GetFullName())] = true
+	}
+
+	client.Progress.Begin("events", len(repos))
+	defer client.Progress.End()
+
+	seen := map[string]bool{}
+
+	var all []*github.Event
+	keep := func(events []*github.Event) {
+		for _, e := range events {
+			if seen[e.GetID()] || !allowed[strings.ToLower(e.Repo.GetName())] || client.excludes(e.Actor) {
+				continue
+			}
+
+			seen[e.GetID()] = true
+			all = append(all, e)
+		}
+	}
+
+	for _, org := range orgs {
+		events, err := client.listEvents(func(options *github.ListOptions) ([]*github.Event, *github.Response, error) {
+			return client.GithubClient.Activity.ListEventsForOrganization(ctx, org, options)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		keep(events)
+	}
+
+	for _, repo := range repos {
+		events, err := client.listEvents(func(options *github.ListOptions) ([]*github.Event, *github.Response, error) {
+			return client.GithubClient.Activity.ListRepositoryEvents(ctx, *repo.Owner.Login, *repo.Name, options)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		keep(events)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	SortEvents(all)
+
+	return all, nil
+}
+
+func (client *Client) listEvents(list func(*github.ListOptions) ([]*github.Event, *github.Response, error)) ([]*github.Event, error) {
+	options := &github.ListOptions{}
+
+	var all []*github.Event
+
+	for {
+		resources, resp, err := list(options)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Progress.PageFetched(resp.Rate)
+
+		if len(resources) == 0 {
+			break
+		}
+
+		all = append(all, resources...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		options.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// SortEvents orders events oldest first. Event IDs increase over time, so
+// they break ties between events created in the same second.
+func SortEvents(events []*github.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		ti, tj := events[i].GetCreatedAt(), events[j].GetCreatedAt()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+
+		return EventID(events[i]) < EventID(events[j])
+	})
+}
+
+// EventID returns the numeric ID of an event, which the API gives as a
+// string.
+func EventID(e *github.Event) int {
+	id, _ := strconv.Atoi(e.GetID())
+	return id
+}
+
+// StreamEvent converts an event from an event stream to an activity event.
+// It returns false for kinds of events that are not activity pm tracks, or
+// that it already sees through the issue and comment listings.
+func StreamEvent(e *github.Event) (activity.Event, bool) {
+	event := activity.Event{
+		Actor:      e.Actor.GetLogin(),
+		Repository: e.Repo.GetName(),
+		CreatedAt:  e.GetCreatedAt(),
+	}
+
+	payload, err := e.ParsePayload()
+	if err != nil {
+		return event, false
+	}
+
+	switch p := payload.(type) {
+	case *github.PushEvent:
+		event.Kind = activity.Push
+		event.Target = p.GetRef()
+
+	case *github.IssuesEvent:
+		if p.GetAction() != "closed" {
+			return event, false
+		}
+
+		event.Kind = activity.IssueClosed
+		event.Target = fmt.Sprintf("#%d", p.Issue.GetNumber())
+		event.URL = p.Issue.GetHTMLURL()
+
+	case *github.GollumEvent:
+		event.Kind = activity.WikiEdit
+
+		var titles []string
+		for _, page := range p.Pages {
+			titles = append(titles, page.GetTitle())
+		}
+
+		event.Target = strings.Join(titles, ", ")
+		if len(p.Pages) > 0 {
+			event.URL = p.Pages[0].GetHTMLURL()
+		}
+
+	case *github.ReleaseEvent:
+		event.Kind = activity.Release
+		event.Target = p.Release.GetTagName()
+		event.Body = p.Release.GetName()
+		event.URL = p.Release.GetHTMLURL()
+
+	case *github.ForkEvent:
+		event.Kind = activity.Fork
+		event.Target = p.Forkee.GetFullName()
+		event.URL = p.Forkee.GetHTMLURL()
+
+	case *github.WatchEvent:
+		event.Kind = activity.Star
+
+	default:
+		return event, false
+	}
+
+	return event, true
+}
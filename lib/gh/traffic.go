@@ -0,0 +1,165 @@
+package gh
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// TrafficRetention is how far back GitHub keeps a repository's traffic.
+const TrafficRetention = 14 * 24 * time.Hour
+
+// Traffic is a repository's traffic as crawled at one point in time.
+type Traffic struct {
+	CollectedAt time.Time `json:"collected_at"`
+
+	// Views and Clones count each of the last 14 days, oldest first.
+	Views  []*github.TrafficData `json:"views"`
+	Clones []*github.TrafficData `json:"clones"`
+
+	// Referrers and Paths are the top 10 over the 14 days before
+	// CollectedAt, as GitHub does not break them down by day.
+	Referrers []*github.TrafficReferrer `json:"referrers"`
+	Paths     []*github.TrafficPath     `json:"paths"`
+}
+
+// TrafficHistory is a repository's traffic as crawled one or more times,
+// oldest crawl first. Crawls less than 14 days apart overlap, so the
+// history merges them rather than adding them up.
+type TrafficHistory []*Traffic
+
+// Views returns the views of every day any crawl covered, oldest first. A
+// day covered by several crawls counts as the latest one saw it, as GitHub
+// keeps counting the current day until it is over.
+func (h TrafficHistory) Views() []*github.TrafficData {
+	return mergeDays(h, func(t *Traffic) []*github.TrafficData { return t.Views })
+}
+
+// Clones returns the clones of every day any crawl covered, oldest first,
+// merged as Views are.
+func (h TrafficHistory) Clones() []*github.TrafficData {
+	return mergeDays(h, func(t *Traffic) []*github.TrafficData { return t.Clones })
+}
+
+// Periods returns the crawls whose 14 days do not overlap, newest first:
+// the latest crawl, then the latest one at least 14 days older, and so on.
+// Adding up their referrers or paths never counts a day twice, but misses
+// the days between crawls more than 14 days apart.
+func (h TrafficHistory) Periods() []*Traffic {
+	var periods []*Traffic
+
+	for i := len(h) - 1; i >= 0; i-- {
+		if len(periods) > 0 && h[i].CollectedAt.After(periods[len(periods)-1].CollectedAt.Add(-TrafficRetention)) {
+			continue
+		}
+
+		periods = append(periods, h[i])
+	}
+
+	return periods
+}
+
+func mergeDays(h TrafficHistory, days func(*Traffic) []*github.TrafficData) []*github.TrafficData {
+	merged := map[time.Time]*github.TrafficData{}
+	for _, t := range h {
+		for _, day := range days(t) {
+			merged[day.GetTimestamp().Time.UTC()] = day
+		}
+	}
+
+	var all []*github.TrafficData
+	for _, day := range merged {
+		all = append(all, day)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].GetTimestamp().Before(all[j].GetTimestamp().Time)
+	})
+
+	return all
+}
+
+// TrafficForOrganizations crawls the traffic of every repository of the
+// organizations that passes the repository filter, keyed by the
+// repository's full name. Repositories whose traffic the token may not see
+// are left out.
+func (client *Client) TrafficForOrganizations(ctx context.Context, orgs []string) (map[string]TrafficHistory, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("traffic", len(repos))
+	defer client.Progress.End()
+
+	all := map[string]TrafficHistory{}
+	for _, repo := range repos {
+		traffic, err := client.TrafficForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		if traffic != nil {
+			all[repo.GetFullName()] = TrafficHistory{traffic}
+		}
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// TrafficForRepository crawls the repository's traffic, or returns nil if
+// the token may not see it: GitHub only shows traffic to those who can push
+// to the repository.
+func (client *Client) TrafficForRepository(ctx context.Context, repo *github.Repository) (*Traffic, error) {
+	owner, name := repo.Owner.GetLogin(), repo.GetName()
+
+	traffic := &Traffic{CollectedAt: time.Now().UTC()}
+
+	views, resp, err := client.GithubClient.Repositories.ListTrafficViews(ctx, owner, name, nil)
+	if isNotVisible(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.PageFetched(resp.Rate)
+	traffic.Views = views.Views
+
+	clones, resp, err := client.GithubClient.Repositories.ListTrafficClones(ctx, owner, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.PageFetched(resp.Rate)
+	traffic.Clones = clones.Clones
+
+	traffic.Referrers, resp, err = client.GithubClient.Repositories.ListTrafficReferrers(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.PageFetched(resp.Rate)
+
+	traffic.Paths, resp, err = client.GithubClient.Repositories.ListTrafficPaths(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.PageFetched(resp.Rate)
+
+	return traffic, nil
+}
+
+// isNotVisible tells whether GitHub refused to show a resource: 403 if the
+// token lacks permission, 404 if it cannot see the repository at all.
+func isNotVisible(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	return isNotFound(err) || ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,53 @@
+package gh_test
+
+import (
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrafficHistory", func() {
+	day := func(d, count int) *github.TrafficData {
+		return &github.TrafficData{
+			Timestamp: &github.Timestamp{Time: time.Date(2017, time.June, d, 0, 0, 0, 0, time.UTC)},
+			Count:     github.Int(count),
+			Uniques:   github.Int(1),
+		}
+	}
+
+	crawl := func(d int, views ...*github.TrafficData) *gh.Traffic {
+		return &gh.Traffic{
+			CollectedAt: time.Date(2017, time.June, d, 12, 0, 0, 0, time.UTC),
+			Views:       views,
+		}
+	}
+
+	It("counts a day seen by overlapping crawls once, as the latest crawl saw it", func() {
+		history := gh.TrafficHistory{
+			crawl(2, day(1, 3), day(2, 1)),
+			crawl(3, day(1, 3), day(2, 5), day(3, 2)),
+		}
+
+		var counts []int
+		for _, v := range history.Views() {
+			counts = append(counts, v.GetCount())
+		}
+
+		Expect(counts).To(Equal([]int{3, 5, 2}))
+	})
+
+	It("picks crawls at least 14 days apart, newest first", func() {
+		history := gh.TrafficHistory{crawl(1), crawl(10), crawl(16), crawl(20), crawl(30)}
+
+		var collected []int
+		for _, t := range history.Periods() {
+			collected = append(collected, t.CollectedAt.Day())
+		}
+
+		Expect(collected).To(Equal([]int{30, 16, 1}))
+	})
+})
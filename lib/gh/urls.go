@@ -0,0 +1,83 @@
+package gh
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// RepositoryFullName extracts "owner/repo" from a GitHub API URL such as an
+// issue's or comment's URL. It returns an empty string if the URL does not
+// point into a repository.
+func RepositoryFullName(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+2 < len(segments); i++ {
+		if segments[i] == "repos" {
+			return segments[i+1] + "/" + segments[i+2]
+		}
+	}
+
+	return ""
+}
+
+// IssueRepository returns the "owner/repo" an issue belongs to, preferring the
+// embedded repository when the API included one.
+func IssueRepository(i *github.Issue) string {
+	if i.Repository != nil && i.Repository.FullName != nil {
+		return *i.Repository.FullName
+	}
+
+	return RepositoryFullName(i.GetURL())
+}
+
+// PullRequestsAmong returns the "owner/repo#number" of every pull request
+// among the issues, as ReviewedPullRequest names them.
+func PullRequestsAmong(issues []*github.Issue) map[string]bool {
+	pullRequests := map[string]bool{}
+	for _, i := range issues {
+		if i.PullRequestLinks != nil {
+			pullRequests[fmt.Sprintf("%s#%d", IssueRepository(i), i.GetNumber())] = true
+		}
+	}
+
+	return pullRequests
+}
+
+// ReviewedPullRequest returns the "owner/repo#number" of the pull request a
+// review belongs to.
+func ReviewedPullRequest(r *github.PullRequestReview) string {
+	return RepositoryFullName(r.GetPullRequestURL()) + "#" + path.Base(r.GetPullRequestURL())
+}
+
+// GroupIssuesByRepository returns the repositories the issues belong to, in
+// the order they are first seen, and the issues of each keyed by its full
+// name.
+func GroupIssuesByRepository(issues []*github.Issue) ([]*github.Repository, map[string][]*github.Issue) {
+	var repos []*github.Repository
+	grouped := map[string][]*github.Issue{}
+
+	for _, i := range issues {
+		name := IssueRepository(i)
+
+		if _, seen := grouped[name]; !seen {
+			owner := strings.SplitN(name, "/", 2)[0]
+			repos = append(repos, &github.Repository{
+				FullName: github.String(name),
+				Owner:    &github.User{Login: github.String(owner)},
+				Name:     github.String(strings.TrimPrefix(name, owner+"/")),
+			})
+		}
+
+		grouped[name] = append(grouped[name], i)
+	}
+
+	return repos, grouped
+}
@@ -0,0 +1,200 @@
+// Package graphql crawls GitHub through the GraphQL API. Each repository's
+// issues and pull requests are fetched together with their comments,
+// reviews, events and reactions in nested queries, instead of one paginated
+// REST call chain per kind of activity.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+// DefaultPageSize is how many nodes are requested per connection. Nested
+// connections multiply, so it also bounds the cost of each query: a page of
+// issues with a page of comments and events each costs about one point.
+const DefaultPageSize = 50
+
+// Client crawls GitHub through the GraphQL API. It presents what it crawls
+// as the same go-github types as gh.Client, with API URLs in the REST
+// API's format, so reports, snapshots and the store cannot tell the
+// backends apart.
+//
+// A repository is crawled in full the first time any of its activity is
+// asked for and served from memory after that.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Endpoint is the GraphQL endpoint, e.g. https://api.github.com/graphql.
+	Endpoint string
+
+	// RESTBaseURL is the REST API the URLs of crawled resources point into,
+	// e.g. https://api.github.com/.
+	RESTBaseURL *url.URL
+
+	PageSize int
+	Progress gh.Progress
+
+	RepositoryFilter gh.RepositoryFilter
+	ExcludedUsers    map[string]bool
+
+	rate github.Rate
+
+	repositories map[string][]*github.Repository
+	activity     map[string]*crawled
+}
+
+var _ gh.Backend = &Client{}
+
+// NewClient returns a client for the GraphQL API belonging to the REST API
+// at restBaseURL: https://api.github.com/graphql for github.com, and
+// https://host/api/graphql for GitHub Enterprise Server.
+func NewClient(httpClient *http.Client, restBaseURL *url.URL) *Client {
+	endpoint := *restBaseURL
+	if strings.HasSuffix(endpoint.Path, "/api/v3/") {
+		endpoint.Path = strings.TrimSuffix(endpoint.Path, "v3/") + "graphql"
+	} else {
+		endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/graphql"
+	}
+
+	return &Client{
+		HTTPClient:  httpClient,
+		Endpoint:    endpoint.String(),
+		RESTBaseURL: restBaseURL,
+		PageSize:    DefaultPageSize,
+		Progress:    gh.NopProgress,
+
+		repositories: map[string][]*github.Repository{},
+		activity:     map[string]*crawled{},
+	}
+}
+
+func (client *Client) ProgressReporter() gh.Progress {
+	return client.Progress
+}
+
+type request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []queryError    `json:"errors"`
+}
+
+type queryError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type rateLimit struct {
+	Limit     int       `json:"limit"`
+	Cost      int       `json:"cost"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// query runs a GraphQL query and decodes its data into result. Every query
+// asks for its rate limit, which is reported to Progress like the rate of a
+// REST page.
+func (client *Client) query(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	payload, err := json.Marshal(request{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", client.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Message string `json:"message"`
+		}
+
+		json.Unmarshal(body, &failure)
+
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return &github.RateLimitError{Rate: client.rate, Response: resp, Message: failure.Message}
+		}
+
+		return fmt.Errorf("POST %s: %d %s", client.Endpoint, resp.StatusCode, failure.Message)
+	}
+
+	var decoded response
+	err = json.Unmarshal(body, &decoded)
+	if err != nil {
+		return fmt.Errorf("decoding GraphQL response: %s", err)
+	}
+
+	if len(decoded.Errors) > 0 {
+		for _, e := range decoded.Errors {
+			if e.Type == "RATE_LIMITED" {
+				return &github.RateLimitError{Rate: client.rate, Response: resp, Message: e.Message}
+			}
+		}
+
+		var messages []string
+		for _, e := range decoded.Errors {
+			messages = append(messages, e.Message)
+		}
+
+		return fmt.Errorf("GraphQL query failed: %s", strings.Join(messages, "; "))
+	}
+
+	var limited struct {
+		RateLimit *rateLimit `json:"rateLimit"`
+	}
+
+	err = json.Unmarshal(decoded.Data, &limited)
+	if err != nil {
+		return fmt.Errorf("decoding GraphQL response: %s", err)
+	}
+
+	if limited.RateLimit != nil {
+		client.rate = github.Rate{
+			Limit:     limited.RateLimit.Limit,
+			Remaining: limited.RateLimit.Remaining,
+			Reset:     github.Timestamp{Time: limited.RateLimit.ResetAt},
+		}
+	}
+
+	client.Progress.PageFetched(client.rate)
+
+	err = json.Unmarshal(decoded.Data, result)
+	if err != nil {
+		return fmt.Errorf("decoding GraphQL response: %s", err)
+	}
+
+	return nil
+}
+
+func (client *Client) excludes(user *github.User) bool {
+	return user != nil && client.ExcludedUsers[user.GetLogin()]
+}
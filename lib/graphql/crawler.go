@@ -0,0 +1,617 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+// crawled is everything crawled from one repository, before users are
+// excluded.
+type crawled struct {
+	issues             []*github.Issue
+	issueComments      []*github.IssueComment
+	repositoryComments []*github.RepositoryComment
+	issueEvents        []*github.IssueEvent
+	reviews            map[int][]*github.PullRequestReview
+}
+
+type issueConnection struct {
+	PageInfo pageInfo    `json:"pageInfo"`
+	Nodes    []issueNode `json:"nodes"`
+}
+
+// PublicRepositoriesForOrganizations returns the public repositories of every
+// given organization that pass the repository filter.
+func (client *Client) PublicRepositoriesForOrganizations(ctx context.Context, orgs []string) ([]*github.Repository, error) {
+	var all []*github.Repository
+	for _, org := range orgs {
+		repos, err := client.PublicRepositories(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, repos...)
+	}
+
+	return all, nil
+}
+
+func (client *Client) PublicRepositories(ctx context.Context, org string) ([]*github.Repository, error) {
+	if repos, found := client.repositories[org]; found {
+		return repos, nil
+	}
+
+	var all []*github.Repository
+	var after *string
+
+	for {
+		var result struct {
+			Organization struct {
+				Repositories struct {
+					PageInfo pageInfo         `json:"pageInfo"`
+					Nodes    []repositoryNode `json:"nodes"`
+				} `json:"repositories"`
+			} `json:"organization"`
+		}
+
+		err := client.query(ctx, repositoriesQuery, map[string]interface{}{
+			"org":   org,
+			"first": client.PageSize,
+			"after": after,
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := result.Organization.Repositories
+
+		for _, node := range connection.Nodes {
+			if node.IsPrivate {
+				continue
+			}
+
+			repo := node.repository()
+			repo.URL = github.String(client.urls(repo).repository())
+
+			if client.RepositoryFilter.Allows(repo) {
+				all = append(all, repo)
+			}
+		}
+
+		if !connection.PageInfo.HasNextPage {
+			break
+		}
+
+		after = github.String(connection.PageInfo.EndCursor)
+	}
+
+	client.repositories[org] = all
+
+	return all, nil
+}
+
+func (client *Client) AllIssuesForOrganizations(ctx context.Context, orgs []string) ([]*github.Issue, error) {
+	return client.issuesForOrganizations(ctx, orgs, "issues", func(issue *github.Issue) bool {
+		return issue.GetState() == "open"
+	})
+}
+
+// IssuesUpdatedSinceForOrganizations returns open and closed issues that have
+// been updated since the given time.
+func (client *Client) IssuesUpdatedSinceForOrganizations(ctx context.Context, orgs []string, since time.Time) ([]*github.Issue, error) {
+	return client.issuesForOrganizations(ctx, orgs, "issues updated since", func(issue *github.Issue) bool {
+		return !issue.GetUpdatedAt().Before(since)
+	})
+}
+
+func (client *Client) issuesForOrganizations(ctx context.Context, orgs []string, phase string, keep func(*github.Issue) bool) ([]*github.Issue, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin(phase, len(repos))
+	defer client.Progress.End()
+
+	var all []*github.Issue
+	for _, repo := range repos {
+		issues, err := client.AllIssuesIncludingClosed(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			if keep(issue) {
+				all = append(all, issue)
+			}
+		}
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllIssueCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueComment, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("issue comments", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.IssueComment
+	for _, repo := range repos {
+		comments, err := client.AllIssueCommentsForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, comments...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllRepositoryCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.RepositoryComment, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("repository comments", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.RepositoryComment
+	for _, repo := range repos {
+		comments, err := client.AllCommentsForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, comments...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllIssueEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueEvent, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("issue events", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.IssueEvent
+	for _, repo := range repos {
+		events, err := client.AllIssueEventsForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, events...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllReviewsForIssues(ctx context.Context, issues []*github.Issue) ([]*github.PullRequestReview, error) {
+	repos, grouped := gh.GroupIssuesByRepository(issues)
+
+	client.Progress.Begin("reviews", len(repos))
+	defer client.Progress.End()
+
+	var all []*github.PullRequestReview
+	for _, repo := range repos {
+		reviews, err := client.AllReviewsForPullRequests(ctx, repo, grouped[repo.GetFullName()])
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, reviews...)
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// StreamEventsForOrganizations reads the organizations' event streams from
+// the REST API, as the GraphQL API has none.
+func (client *Client) StreamEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.Event, error) {
+	return client.rest().StreamEventsForOrganizations(ctx, orgs)
+}
+
+// ForksForOrganizations lists and compares forks through the REST API, as
+// the GraphQL API cannot compare branches across repositories.
+func (client *Client) ForksForOrganizations(ctx context.Context, orgs []string) (map[string][]*gh.Fork, error) {
+	return client.rest().ForksForOrganizations(ctx, orgs)
+}
+
+// TrafficForOrganizations crawls traffic through the REST API, as the
+// GraphQL API does not expose it.
+func (client *Client) TrafficForOrganizations(ctx context.Context, orgs []string) (map[string]gh.TrafficHistory, error) {
+	return client.rest().TrafficForOrganizations(ctx, orgs)
+}
+
+// TrafficForRepository crawls the repository's traffic through the REST API.
+func (client *Client) TrafficForRepository(ctx context.Context, repo *github.Repository) (*gh.Traffic, error) {
+	return client.rest().TrafficForRepository(ctx, repo)
+}
+
+// AllForksForRepository lists and compares the repository's forks through
+// the REST API.
+func (client *Client) AllForksForRepository(ctx context.Context, repo *github.Repository) ([]*gh.Fork, error) {
+	return client.rest().AllForksForRepository(ctx, repo)
+}
+
+// rest returns a REST API client for what the GraphQL API does not offer,
+// sharing the client's connection, progress, filter and exclusions.
+func (client *Client) rest() *gh.Client {
+	rest := gh.NewClient(github.NewClient(client.HTTPClient))
+	rest.GithubClient.BaseURL = client.RESTBaseURL
+	rest.Progress = client.Progress
+	rest.RepositoryFilter = client.RepositoryFilter
+	rest.ExcludedUsers = client.ExcludedUsers
+
+	return rest
+}
+
+// Events returns the organizations' activity.
+func (client *Client) Events(ctx context.Context, orgs []string) ([]activity.Event, error) {
+	return gh.CrawlEvents(ctx, client, orgs)
+}
+
+func (client *Client) AllMembersForOrganizations(ctx context.Context, orgs []string) ([]*github.User, error) {
+	var all []*github.User
+	for _, org := range orgs {
+		members, err := client.OrganizationMembers(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, members...)
+	}
+
+	return all, nil
+}
+
+func (client *Client) OrganizationMembers(ctx context.Context, org string) ([]*github.User, error) {
+	var all []*github.User
+	var after *string
+
+	for {
+		var result struct {
+			Organization struct {
+				Members struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []actor  `json:"nodes"`
+				} `json:"membersWithRole"`
+			} `json:"organization"`
+		}
+
+		err := client.query(ctx, membersQuery, map[string]interface{}{
+			"org":   org,
+			"first": client.PageSize,
+			"after": after,
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := result.Organization.Members
+
+		for i := range connection.Nodes {
+			all = append(all, connection.Nodes[i].user())
+		}
+
+		if !connection.PageInfo.HasNextPage {
+			break
+		}
+
+		after = github.String(connection.PageInfo.EndCursor)
+	}
+
+	return all, nil
+}
+
+// AllIssuesIncludingClosed returns every open and closed issue and pull
+// request in the repository.
+func (client *Client) AllIssuesIncludingClosed(ctx context.Context, repo *github.Repository) ([]*github.Issue, error) {
+	a, err := client.repositoryActivity(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*github.Issue
+	for _, issue := range a.issues {
+		if !client.excludes(issue.User) {
+			all = append(all, issue)
+		}
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllIssueCommentsForRepository(ctx context.Context, repo *github.Repository) ([]*github.IssueComment, error) {
+	a, err := client.repositoryActivity(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*github.IssueComment
+	for _, comment := range a.issueComments {
+		if !client.excludes(comment.User) {
+			all = append(all, comment)
+		}
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllCommentsForRepository(ctx context.Context, repo *github.Repository) ([]*github.RepositoryComment, error) {
+	a, err := client.repositoryActivity(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*github.RepositoryComment
+	for _, comment := range a.repositoryComments {
+		if !client.excludes(comment.User) {
+			all = append(all, comment)
+		}
+	}
+
+	return all, nil
+}
+
+func (client *Client) AllIssueEventsForRepository(ctx context.Context, repo *github.Repository) ([]*github.IssueEvent, error) {
+	a, err := client.repositoryActivity(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*github.IssueEvent
+	for _, event := range a.issueEvents {
+		if !client.excludes(event.Actor) {
+			all = append(all, event)
+		}
+	}
+
+	return all, nil
+}
+
+// AllReviewsForPullRequests returns the reviews of every pull request among
+// the given issues of the repository.
+func (client *Client) AllReviewsForPullRequests(ctx context.Context, repo *github.Repository, issues []*github.Issue) ([]*github.PullRequestReview, error) {
+	a, err := client.repositoryActivity(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*github.PullRequestReview
+	for _, issue := range issues {
+		if issue.PullRequestLinks == nil {
+			continue
+		}
+
+		for _, review := range a.reviews[issue.GetNumber()] {
+			if !client.excludes(review.User) {
+				all = append(all, review)
+			}
+		}
+	}
+
+	return all, nil
+}
+
+func (client *Client) urls(repo *github.Repository) urls {
+	return urls{
+		base:     client.RESTBaseURL,
+		owner:    repo.Owner.GetLogin(),
+		name:     repo.GetName(),
+		fullName: repo.GetFullName(),
+	}
+}
+
+// repositoryActivity crawls the repository's issues and pull requests, with
+// everything nested in them, and its commit comments, the first time it is
+// asked for.
+func (client *Client) repositoryActivity(ctx context.Context, repo *github.Repository) (*crawled, error) {
+	if a, found := client.activity[repo.GetFullName()]; found {
+		return a, nil
+	}
+
+	u := client.urls(repo)
+	a := &crawled{reviews: map[int][]*github.PullRequestReview{}}
+
+	for _, kind := range []struct {
+		query       string
+		field       string
+		pullRequest bool
+	}{
+		{issuesQuery, "issues", false},
+		{pullRequestsQuery, "pullRequests", true},
+	} {
+		var after *string
+
+		for {
+			var result struct {
+				Repository map[string]issueConnection `json:"repository"`
+			}
+
+			err := client.query(ctx, kind.query, client.repositoryVariables(u, after), &result)
+			if err != nil {
+				return nil, err
+			}
+
+			connection := result.Repository[kind.field]
+
+			for _, node := range connection.Nodes {
+				err := client.collect(ctx, u, node, kind.pullRequest, a)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if !connection.PageInfo.HasNextPage {
+				break
+			}
+
+			after = github.String(connection.PageInfo.EndCursor)
+		}
+	}
+
+	var after *string
+
+	for {
+		var result struct {
+			Repository struct {
+				CommitComments commentConnection `json:"commitComments"`
+			} `json:"repository"`
+		}
+
+		err := client.query(ctx, commitCommentsQuery, client.repositoryVariables(u, after), &result)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := result.Repository.CommitComments
+
+		for _, node := range connection.Nodes {
+			a.repositoryComments = append(a.repositoryComments, node.repositoryComment(u))
+		}
+
+		if !connection.PageInfo.HasNextPage {
+			break
+		}
+
+		after = github.String(connection.PageInfo.EndCursor)
+	}
+
+	client.activity[repo.GetFullName()] = a
+
+	return a, nil
+}
+
+func (client *Client) repositoryVariables(u urls, after *string) map[string]interface{} {
+	return map[string]interface{}{
+		"owner": u.owner,
+		"name":  u.name,
+		"first": client.PageSize,
+		"after": after,
+	}
+}
+
+// collect converts an issue or pull request and everything nested in it,
+// fetching the rest of any nested connection the first page did not hold.
+func (client *Client) collect(ctx context.Context, u urls, node issueNode, pullRequest bool, a *crawled) error {
+	issue := node.issue(u, pullRequest)
+	a.issues = append(a.issues, issue)
+
+	comments := node.Comments
+	for {
+		for _, c := range comments.Nodes {
+			a.issueComments = append(a.issueComments, c.issueComment(u, issue))
+		}
+
+		if !comments.PageInfo.HasNextPage {
+			break
+		}
+
+		var result struct {
+			Node struct {
+				Comments commentConnection `json:"comments"`
+			} `json:"node"`
+		}
+
+		err := client.query(ctx, moreCommentsQuery, nestedVariables(node.ID, client.PageSize, comments.PageInfo.EndCursor), &result)
+		if err != nil {
+			return err
+		}
+
+		comments = result.Node.Comments
+	}
+
+	events := node.TimelineItems
+	for {
+		for _, e := range events.Nodes {
+			a.issueEvents = append(a.issueEvents, e.issueEvent(u, issue))
+		}
+
+		if !events.PageInfo.HasNextPage {
+			break
+		}
+
+		var result struct {
+			Node struct {
+				IssueEvents       *eventConnection `json:"issueEvents"`
+				PullRequestEvents *eventConnection `json:"pullRequestEvents"`
+			} `json:"node"`
+		}
+
+		err := client.query(ctx, moreEventsQuery, nestedVariables(node.ID, client.PageSize, events.PageInfo.EndCursor), &result)
+		if err != nil {
+			return err
+		}
+
+		if result.Node.PullRequestEvents != nil {
+			events = *result.Node.PullRequestEvents
+		} else if result.Node.IssueEvents != nil {
+			events = *result.Node.IssueEvents
+		} else {
+			break
+		}
+	}
+
+	if node.Reviews == nil {
+		return nil
+	}
+
+	reviews := *node.Reviews
+	for {
+		for _, r := range reviews.Nodes {
+			a.reviews[node.Number] = append(a.reviews[node.Number], r.review(u, node.Number))
+		}
+
+		if !reviews.PageInfo.HasNextPage {
+			break
+		}
+
+		var result struct {
+			Node struct {
+				Reviews reviewConnection `json:"reviews"`
+			} `json:"node"`
+		}
+
+		err := client.query(ctx, moreReviewsQuery, nestedVariables(node.ID, client.PageSize, reviews.PageInfo.EndCursor), &result)
+		if err != nil {
+			return err
+		}
+
+		reviews = result.Node.Reviews
+	}
+
+	return nil
+}
+
+func nestedVariables(id string, first int, after string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":    id,
+		"first": first,
+		"after": after,
+	}
+}
@@ -0,0 +1,405 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/graphql"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var operationName = regexp.MustCompile(`query (\w+)`)
+
+// fakeGraphQLServer answers each query by its operation name and the page
+// it asks for, and records the operations it was sent.
+type fakeGraphQLServer struct {
+	*httptest.Server
+
+	responses map[string]string
+
+	lock       sync.Mutex
+	operations []string
+}
+
+func newFakeGraphQLServer(responses map[string]string) *fakeGraphQLServer {
+	s := &fakeGraphQLServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *fakeGraphQLServer) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || r.URL.Path != "/graphql" || r.Method != "POST" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	op := operationName.FindStringSubmatch(req.Query)[1]
+	if name, ok := req.Variables["name"].(string); ok {
+		op += " " + name
+	}
+	if after, ok := req.Variables["after"].(string); ok {
+		op += " " + after
+	}
+
+	s.lock.Lock()
+	s.operations = append(s.operations, op)
+	s.lock.Unlock()
+
+	data, found := s.responses[op]
+	if !found {
+		fmt.Fprintf(w, `{"errors": [{"type": "NOT_FOUND", "message": "no response for %s"}]}`, op)
+		return
+	}
+
+	fmt.Fprintf(w, `{"data": {"rateLimit": {"limit": 5000, "cost": 1, "remaining": %d, "resetAt": "2017-07-01T01:00:00Z"}, %s}}`, 5000-len(s.operations), data)
+}
+
+func (s *fakeGraphQLServer) Operations() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string(nil), s.operations...)
+}
+
+const noPage = `"pageInfo": {"hasNextPage": false, "endCursor": null}`
+
+var acme = map[string]string{
+	"Repositories": `"organization": {"repositories": {` + noPage + `, "nodes": [
+		{"databaseId": 101, "name": "widget", "nameWithOwner": "acme/widget", "owner": {"login": "acme"}, "isPrivate": false, "createdAt": "2016-01-01T00:00:00Z", "defaultBranchRef": {"name": "main"}, "stargazers": {"totalCount": 3}, "forkCount": 1, "issues": {"totalCount": 2}},
+		{"databaseId": 103, "name": "secret", "nameWithOwner": "acme/secret", "owner": {"login": "acme"}, "isPrivate": true, "createdAt": "2016-01-01T00:00:00Z"}
+	]}}`,
+
+	"Members": `"organization": {"membersWithRole": {` + noPage + `, "nodes": [
+		{"__typename": "User", "login": "alice", "databaseId": 1},
+		{"__typename": "User", "login": "bob", "databaseId": 2}
+	]}}`,
+
+	"Issues widget": `"repository": {"issues": {"pageInfo": {"hasNextPage": true, "endCursor": "issues-2"}, "nodes": [
+		{"id": "I1", "databaseId": 1001, "number": 1, "title": "Broken", "state": "OPEN", "createdAt": "2017-01-01T00:00:00Z", "updatedAt": "2017-06-01T00:00:00Z",
+		 "author": {"__typename": "User", "login": "carol", "databaseId": 3},
+		 "labels": {"nodes": [{"name": "bug", "color": "ff0000"}]},
+		 "reactionGroups": [{"content": "THUMBS_UP", "reactors": {"totalCount": 2}}, {"content": "HEART", "reactors": {"totalCount": 1}}],
+		 "comments": {"totalCount": 2, "pageInfo": {"hasNextPage": true, "endCursor": "comments-2"}, "nodes": [
+			{"databaseId": 5001, "author": {"__typename": "User", "login": "alice", "databaseId": 1}, "body": "Looking", "createdAt": "2017-01-02T00:00:00Z", "updatedAt": "2017-01-02T00:00:00Z"}
+		 ]},
+		 "timelineItems": {` + noPage + `, "nodes": [
+			{"__typename": "LabeledEvent", "id": "LE1", "createdAt": "2017-01-03T00:00:00Z", "actor": {"__typename": "User", "login": "alice", "databaseId": 1}, "label": {"name": "bug", "color": "ff0000"}}
+		 ]}}
+	]}}`,
+
+	"Issues widget issues-2": `"repository": {"issues": {` + noPage + `, "nodes": [
+		{"id": "I2", "databaseId": 1002, "number": 2, "title": "Bump deps", "state": "CLOSED", "createdAt": "2017-02-01T00:00:00Z", "updatedAt": "2017-02-02T00:00:00Z", "closedAt": "2017-02-02T00:00:00Z",
+		 "author": {"__typename": "Bot", "login": "ci-bot", "databaseId": 9},
+		 "comments": {"totalCount": 0, ` + noPage + `, "nodes": []},
+		 "timelineItems": {` + noPage + `, "nodes": []}}
+	]}}`,
+
+	"MoreComments comments-2": `"node": {"comments": {"totalCount": 2, ` + noPage + `, "nodes": [
+		{"databaseId": 5002, "author": null, "body": "Me too", "createdAt": "2017-01-04T00:00:00Z", "updatedAt": "2017-01-04T00:00:00Z"}
+	]}}`,
+
+	"PullRequests widget": `"repository": {"pullRequests": {` + noPage + `, "nodes": [
+		{"id": "PR3", "databaseId": 1003, "number": 3, "title": "Fix", "state": "MERGED", "createdAt": "2017-03-01T00:00:00Z", "updatedAt": "2017-03-05T00:00:00Z", "closedAt": "2017-03-05T00:00:00Z",
+		 "author": {"__typename": "User", "login": "carol", "databaseId": 3},
+		 "comments": {"totalCount": 0, ` + noPage + `, "nodes": []},
+		 "timelineItems": {"pageInfo": {"hasNextPage": true, "endCursor": "events-2"}, "nodes": []},
+		 "reviews": {` + noPage + `, "nodes": [
+			{"databaseId": 7001, "author": {"__typename": "User", "login": "bob", "databaseId": 2}, "state": "APPROVED", "submittedAt": "2017-03-04T00:00:00Z", "commit": {"oid": "abc123"}}
+		 ]}}
+	]}}`,
+
+	"MoreEvents events-2": `"node": {"pullRequestEvents": {` + noPage + `, "nodes": [
+		{"__typename": "MergedEvent", "id": "ME1", "createdAt": "2017-03-05T00:00:00Z", "actor": {"__typename": "User", "login": "bob", "databaseId": 2}},
+		{"__typename": "ClosedEvent", "id": "CE1", "createdAt": "2017-03-05T00:00:00Z", "actor": {"__typename": "User", "login": "bob", "databaseId": 2}}
+	]}}`,
+
+	"Stargazers widget": `"repository": {"stargazers": {"pageInfo": {"hasNextPage": true, "endCursor": "stargazers-2"}, "edges": [
+		{"starredAt": "2017-02-01T10:00:00Z", "node": {"__typename": "User", "login": "alice", "databaseId": 1}},
+		{"starredAt": "2017-04-03T12:00:00Z", "node": {"__typename": "User", "login": "dave", "databaseId": 4}}
+	]}}`,
+
+	"Stargazers widget stargazers-2": `"repository": {"stargazers": {` + noPage + `, "edges": [
+		{"starredAt": "2017-06-11T09:00:00Z", "node": {"__typename": "User", "login": "carol", "databaseId": 3}}
+	]}}`,
+
+	"Releases widget": `"repository": {"releases": {` + noPage + `, "nodes": [
+		{"databaseId": 8002, "tagName": "v1.1.0", "name": "1.1", "isDraft": false, "isPrerelease": false, "createdAt": "2017-05-01T00:00:00Z", "publishedAt": "2017-05-01T00:00:00Z",
+		 "author": {"__typename": "User", "login": "alice", "databaseId": 1},
+		 "releaseAssets": {"nodes": [{"name": "widget.tgz", "downloadCount": 7}, {"name": "widget.zip", "downloadCount": 2}]}},
+		{"databaseId": 8001, "tagName": "v1.0.0", "name": "1.0", "isDraft": false, "isPrerelease": false, "createdAt": "2017-02-01T00:00:00Z", "publishedAt": "2017-03-01T00:00:00Z",
+		 "releaseAssets": {"nodes": [{"name": "widget.tgz", "downloadCount": 30}]}},
+		{"databaseId": 8003, "tagName": "v2.0.0", "name": "2.0", "isDraft": true, "isPrerelease": false, "createdAt": "2017-06-01T00:00:00Z", "publishedAt": null,
+		 "releaseAssets": {"nodes": []}}
+	]}}`,
+
+	"Watchers widget": `"repository": {"watchers": {` + noPage + `, "nodes": [
+		{"__typename": "User", "login": "alice", "databaseId": 1},
+		{"__typename": "User", "login": "bob", "databaseId": 2}
+	]}}`,
+
+	"CommitComments widget": `"repository": {"commitComments": {` + noPage + `, "nodes": [
+		{"databaseId": 6001, "author": {"__typename": "User", "login": "dave", "databaseId": 4}, "body": "Nice", "createdAt": "2017-04-01T00:00:00Z", "updatedAt": "2017-04-01T00:00:00Z", "commit": {"oid": "def456"}}
+	]}}`,
+}
+
+var _ = Describe("Client", func() {
+	var (
+		ctx    context.Context
+		server *fakeGraphQLServer
+		client *graphql.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = newFakeGraphQLServer(acme)
+
+		baseURL, err := url.Parse(server.URL + "/")
+		Expect(err).NotTo(HaveOccurred())
+
+		client = graphql.NewClient(http.DefaultClient, baseURL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("NewClient", func() {
+		It("uses /graphql on github.com and /api/graphql on GitHub Enterprise Server", func() {
+			dotcom, _ := url.Parse("https://api.github.com/")
+			Expect(graphql.NewClient(http.DefaultClient, dotcom).Endpoint).To(Equal("https://api.github.com/graphql"))
+
+			enterprise, _ := url.Parse("https://github.example.com/api/v3/")
+			Expect(graphql.NewClient(http.DefaultClient, enterprise).Endpoint).To(Equal("https://github.example.com/api/graphql"))
+		})
+	})
+
+	It("lists public repositories with REST API URLs", func() {
+		repos, err := client.PublicRepositoriesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repos).To(HaveLen(1))
+		Expect(repos[0].GetFullName()).To(Equal("acme/widget"))
+		Expect(repos[0].GetURL()).To(Equal(server.URL + "/repos/acme/widget"))
+		Expect(repos[0].GetStargazersCount()).To(Equal(3))
+		Expect(repos[0].GetDefaultBranch()).To(Equal("main"))
+	})
+
+	It("converts issues and pull requests, following every page", func() {
+		repos, err := client.PublicRepositoriesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		issues, err := client.AllIssuesIncludingClosed(ctx, repos[0])
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(issues).To(HaveLen(3))
+
+		Expect(issues[0].GetNumber()).To(Equal(1))
+		Expect(issues[0].GetState()).To(Equal("open"))
+		Expect(issues[0].GetURL()).To(Equal(server.URL + "/repos/acme/widget/issues/1"))
+		Expect(issues[0].User.GetLogin()).To(Equal("carol"))
+		Expect(issues[0].GetComments()).To(Equal(2))
+		Expect(issues[0].Labels[0].GetName()).To(Equal("bug"))
+		Expect(issues[0].Reactions.GetTotalCount()).To(Equal(3))
+		Expect(issues[0].Reactions.GetPlusOne()).To(Equal(2))
+		Expect(issues[0].PullRequestLinks).To(BeNil())
+
+		Expect(issues[1].GetState()).To(Equal("closed"))
+		Expect(issues[1].User.GetType()).To(Equal("Bot"))
+
+		Expect(issues[2].GetNumber()).To(Equal(3))
+		Expect(issues[2].GetState()).To(Equal("closed"))
+		Expect(issues[2].PullRequestLinks).NotTo(BeNil())
+	})
+
+	It("ties comments, events and reviews to their issues", func() {
+		repos, err := client.PublicRepositoriesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		issues, err := client.AllIssuesIncludingClosed(ctx, repos[0])
+		Expect(err).NotTo(HaveOccurred())
+
+		comments, err := client.AllIssueCommentsForRepository(ctx, repos[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comments).To(HaveLen(2))
+		Expect(comments[0].GetIssueURL()).To(Equal(issues[0].GetURL()))
+		Expect(comments[0].GetURL()).To(Equal(server.URL + "/repos/acme/widget/issues/comments/5001"))
+		Expect(comments[1].User).To(BeNil())
+
+		events, err := client.AllIssueEventsForRepository(ctx, repos[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+		Expect(events[0].GetEvent()).To(Equal("labeled"))
+		Expect(events[0].Label.GetName()).To(Equal("bug"))
+		Expect(events[0].Issue.GetURL()).To(Equal(issues[0].GetURL()))
+		Expect(events[1].GetEvent()).To(Equal("merged"))
+		Expect(events[1].Issue.GetNumber()).To(Equal(3))
+		Expect(events[2].GetEvent()).To(Equal("closed"))
+
+		reviews, err := client.AllReviewsForPullRequests(ctx, repos[0], issues)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reviews).To(HaveLen(1))
+		Expect(reviews[0].GetState()).To(Equal("APPROVED"))
+		Expect(reviews[0].GetCommitID()).To(Equal("abc123"))
+		Expect(reviews[0].GetPullRequestURL()).To(Equal(server.URL + "/repos/acme/widget/pulls/3"))
+
+		repoComments, err := client.AllCommentsForRepository(ctx, repos[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repoComments).To(HaveLen(1))
+		Expect(repoComments[0].GetCommitID()).To(Equal("def456"))
+	})
+
+	It("crawls each repository once", func() {
+		_, err := client.AllIssuesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.AllIssueCommentsForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.AllIssueEventsForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(server.Operations()).To(Equal([]string{
+			"Repositories",
+			"Issues widget",
+			"MoreComments comments-2",
+			"Issues widget issues-2",
+			"PullRequests widget",
+			"MoreEvents events-2",
+			"CommitComments widget",
+		}))
+	})
+
+	It("keeps only open issues for the organization-wide issue list", func() {
+		issues, err := client.AllIssuesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].GetNumber()).To(Equal(1))
+	})
+
+	It("keeps only issues updated since the given time", func() {
+		issues, err := client.IssuesUpdatedSinceForOrganizations(ctx, []string{"acme"}, time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(issues).To(HaveLen(2))
+		Expect(issues[0].GetNumber()).To(Equal(1))
+		Expect(issues[1].GetNumber()).To(Equal(3))
+	})
+
+	It("drops excluded users' activity", func() {
+		client.ExcludedUsers = map[string]bool{"ci-bot": true, "alice": true}
+
+		issues, err := client.IssuesUpdatedSinceForOrganizations(ctx, []string{"acme"}, time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issues).To(HaveLen(2))
+
+		comments, err := client.AllIssueCommentsForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comments).To(HaveLen(1))
+	})
+
+	It("lists stargazers with when they starred, following every page", func() {
+		stargazers, err := client.StargazersForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stargazers).To(HaveKey("acme/widget"))
+		Expect(stargazers).NotTo(HaveKey("acme/secret"))
+
+		widget := stargazers["acme/widget"]
+		Expect(widget).To(HaveLen(3))
+		Expect(widget[0].User.GetLogin()).To(Equal("alice"))
+		Expect(widget[0].User.GetID()).To(Equal(1))
+		Expect(widget[2].GetStarredAt().Time).To(Equal(time.Date(2017, time.June, 11, 9, 0, 0, 0, time.UTC)))
+	})
+
+	It("lists published releases by publication date with their download counts", func() {
+		releases, err := client.ReleasesForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		widget := releases["acme/widget"]
+		Expect(widget).To(HaveLen(2))
+		Expect(widget[0].Release.GetTagName()).To(Equal("v1.0.0"))
+		Expect(widget[0].Downloads()).To(Equal(30))
+		Expect(widget[1].Release.GetTagName()).To(Equal("v1.1.0"))
+		Expect(widget[1].Release.Author.GetLogin()).To(Equal("alice"))
+		Expect(widget[1].Downloads()).To(Equal(9))
+	})
+
+	It("lists watchers, dropping excluded users", func() {
+		client.ExcludedUsers = map[string]bool{"alice": true}
+
+		watchers, err := client.WatchersForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watchers["acme/widget"]).To(HaveLen(1))
+		Expect(watchers["acme/widget"][0].GetLogin()).To(Equal("bob"))
+	})
+
+	It("lists members", func() {
+		members, err := client.AllMembersForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(members).To(HaveLen(2))
+		Expect(members[0].GetLogin()).To(Equal("alice"))
+		Expect(members[1].GetID()).To(Equal(2))
+	})
+
+	It("reports the rate limit of every query", func() {
+		var rates []github.Rate
+		client.Progress = rateRecorder(func(rate github.Rate) { rates = append(rates, rate) })
+
+		_, err := client.AllMembersForOrganizations(ctx, []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rates).To(HaveLen(1))
+		Expect(rates[0].Remaining).To(Equal(4999))
+		Expect(rates[0].Limit).To(Equal(5000))
+	})
+
+	It("fails with the errors the query returned", func() {
+		_, err := client.AllIssuesIncludingClosed(ctx, &github.Repository{
+			Name:     github.String("missing"),
+			FullName: github.String("acme/missing"),
+			Owner:    &github.User{Login: github.String("acme")},
+		})
+		Expect(err).To(MatchError(ContainSubstring("no response for Issues missing")))
+	})
+
+	It("returns a rate limit error when the query is rate limited", func() {
+		limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`)
+		}))
+		defer limited.Close()
+
+		baseURL, _ := url.Parse(limited.URL + "/")
+		client = graphql.NewClient(http.DefaultClient, baseURL)
+
+		_, err := client.OrganizationMembers(ctx, "acme")
+		Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+	})
+})
+
+// rateRecorder is a gh.Progress that only records rates.
+type rateRecorder func(github.Rate)
+
+func (rateRecorder) Begin(string, int)           {}
+func (rateRecorder) RepositoryDone(string)       {}
+func (r rateRecorder) PageFetched(g github.Rate) { r(g) }
+func (rateRecorder) End()                        {}
+
+var _ gh.Progress = rateRecorder(nil)
@@ -0,0 +1,418 @@
+package graphql
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// The node types mirror the fields selected in queries.go.
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type actor struct {
+	Typename   string `json:"__typename"`
+	Login      string `json:"login"`
+	URL        string `json:"url"`
+	DatabaseID int    `json:"databaseId"`
+}
+
+type label struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type reactionGroup struct {
+	Content  string `json:"content"`
+	Reactors struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"reactors"`
+}
+
+type commentNode struct {
+	DatabaseID     int             `json:"databaseId"`
+	Author         *actor          `json:"author"`
+	Body           string          `json:"body"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	URL            string          `json:"url"`
+	ReactionGroups []reactionGroup `json:"reactionGroups"`
+	Commit         *struct {
+		OID string `json:"oid"`
+	} `json:"commit"`
+}
+
+type commentConnection struct {
+	TotalCount int           `json:"totalCount"`
+	PageInfo   pageInfo      `json:"pageInfo"`
+	Nodes      []commentNode `json:"nodes"`
+}
+
+type eventNode struct {
+	Typename  string    `json:"__typename"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     *actor    `json:"actor"`
+	Label     *label    `json:"label"`
+}
+
+type eventConnection struct {
+	PageInfo pageInfo    `json:"pageInfo"`
+	Nodes    []eventNode `json:"nodes"`
+}
+
+type reviewNode struct {
+	DatabaseID  int        `json:"databaseId"`
+	Author      *actor     `json:"author"`
+	Body        string     `json:"body"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submittedAt"`
+	URL         string     `json:"url"`
+	Commit      *struct {
+		OID string `json:"oid"`
+	} `json:"commit"`
+}
+
+type reviewConnection struct {
+	PageInfo pageInfo     `json:"pageInfo"`
+	Nodes    []reviewNode `json:"nodes"`
+}
+
+// issueNode is an issue or a pull request; only pull requests have
+// reviews.
+type issueNode struct {
+	ID         string     `json:"id"`
+	DatabaseID int        `json:"databaseId"`
+	Number     int        `json:"number"`
+	Title      string     `json:"title"`
+	State      string     `json:"state"`
+	URL        string     `json:"url"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	ClosedAt   *time.Time `json:"closedAt"`
+	Author     *actor     `json:"author"`
+	Labels     struct {
+		Nodes []label `json:"nodes"`
+	} `json:"labels"`
+	ReactionGroups []reactionGroup `json:"reactionGroups"`
+
+	Comments      commentConnection `json:"comments"`
+	TimelineItems eventConnection   `json:"timelineItems"`
+	Reviews       *reviewConnection `json:"reviews"`
+}
+
+type repositoryNode struct {
+	DatabaseID    int       `json:"databaseId"`
+	Name          string    `json:"name"`
+	NameWithOwner string    `json:"nameWithOwner"`
+	Owner         actor     `json:"owner"`
+	URL           string    `json:"url"`
+	IsFork        bool      `json:"isFork"`
+	IsPrivate     bool      `json:"isPrivate"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	// DefaultBranchRef is null for an empty repository.
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+
+	Stargazers struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"stargazers"`
+	ForkCount int `json:"forkCount"`
+	Issues    struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"issues"`
+}
+
+type releaseNode struct {
+	DatabaseID   int        `json:"databaseId"`
+	TagName      string     `json:"tagName"`
+	Name         string     `json:"name"`
+	IsDraft      bool       `json:"isDraft"`
+	IsPrerelease bool       `json:"isPrerelease"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	PublishedAt  *time.Time `json:"publishedAt"`
+	URL          string     `json:"url"`
+	Author       *actor     `json:"author"`
+
+	ReleaseAssets struct {
+		Nodes []struct {
+			Name          string    `json:"name"`
+			ContentType   string    `json:"contentType"`
+			Size          int       `json:"size"`
+			DownloadCount int       `json:"downloadCount"`
+			CreatedAt     time.Time `json:"createdAt"`
+		} `json:"nodes"`
+	} `json:"releaseAssets"`
+}
+
+// urls builds the REST API URLs of a repository's resources, which is how
+// the rest of pm tells which repository and issue an activity belongs to.
+type urls struct {
+	base     *url.URL
+	owner    string
+	name     string
+	fullName string
+}
+
+func (u urls) repository() string {
+	return fmt.Sprintf("%srepos/%s/%s", u.base, u.owner, u.name)
+}
+
+func (u urls) issue(number int) string {
+	return fmt.Sprintf("%s/issues/%d", u.repository(), number)
+}
+
+func (u urls) pull(number int) string {
+	return fmt.Sprintf("%s/pulls/%d", u.repository(), number)
+}
+
+func (u urls) issueComment(id int) string {
+	return fmt.Sprintf("%s/issues/comments/%d", u.repository(), id)
+}
+
+func (u urls) commitComment(id int) string {
+	return fmt.Sprintf("%s/comments/%d", u.repository(), id)
+}
+
+func (u urls) issueEvent(id int) string {
+	return fmt.Sprintf("%s/issues/events/%d", u.repository(), id)
+}
+
+func (a *actor) user() *github.User {
+	if a == nil {
+		return nil
+	}
+
+	user := &github.User{
+		Login:   github.String(a.Login),
+		HTMLURL: github.String(a.URL),
+		Type:    github.String(a.Typename),
+	}
+
+	if a.DatabaseID != 0 {
+		user.ID = github.Int(a.DatabaseID)
+	}
+
+	return user
+}
+
+func reactions(groups []reactionGroup) *github.Reactions {
+	r := &github.Reactions{TotalCount: github.Int(0)}
+
+	for _, g := range groups {
+		count := g.Reactors.TotalCount
+		*r.TotalCount += count
+
+		switch g.Content {
+		case "THUMBS_UP":
+			r.PlusOne = github.Int(count)
+		case "THUMBS_DOWN":
+			r.MinusOne = github.Int(count)
+		case "LAUGH":
+			r.Laugh = github.Int(count)
+		case "CONFUSED":
+			r.Confused = github.Int(count)
+		case "HEART":
+			r.Heart = github.Int(count)
+		case "HOORAY":
+			r.Hooray = github.Int(count)
+		}
+	}
+
+	return r
+}
+
+func (n issueNode) issue(u urls, pullRequest bool) *github.Issue {
+	issue := &github.Issue{
+		ID:        github.Int(n.DatabaseID),
+		Number:    github.Int(n.Number),
+		Title:     github.String(n.Title),
+		State:     github.String(state(n.State)),
+		User:      n.Author.user(),
+		Comments:  github.Int(n.Comments.TotalCount),
+		CreatedAt: timePtr(n.CreatedAt),
+		UpdatedAt: timePtr(n.UpdatedAt),
+		ClosedAt:  n.ClosedAt,
+		URL:       github.String(u.issue(n.Number)),
+		HTMLURL:   github.String(n.URL),
+		Reactions: reactions(n.ReactionGroups),
+	}
+
+	for _, l := range n.Labels.Nodes {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(l.Name), Color: github.String(l.Color)})
+	}
+
+	if pullRequest {
+		issue.PullRequestLinks = &github.PullRequestLinks{
+			URL:     github.String(u.pull(n.Number)),
+			HTMLURL: github.String(n.URL),
+		}
+	}
+
+	return issue
+}
+
+func (n commentNode) issueComment(u urls, issue *github.Issue) *github.IssueComment {
+	return &github.IssueComment{
+		ID:        github.Int(n.DatabaseID),
+		User:      n.Author.user(),
+		Body:      github.String(n.Body),
+		Reactions: reactions(n.ReactionGroups),
+		CreatedAt: timePtr(n.CreatedAt),
+		UpdatedAt: timePtr(n.UpdatedAt),
+		URL:       github.String(u.issueComment(n.DatabaseID)),
+		HTMLURL:   github.String(n.URL),
+		IssueURL:  issue.URL,
+	}
+}
+
+func (n commentNode) repositoryComment(u urls) *github.RepositoryComment {
+	c := &github.RepositoryComment{
+		ID:        github.Int(n.DatabaseID),
+		User:      n.Author.user(),
+		Body:      github.String(n.Body),
+		Reactions: reactions(n.ReactionGroups),
+		CreatedAt: timePtr(n.CreatedAt),
+		UpdatedAt: timePtr(n.UpdatedAt),
+		URL:       github.String(u.commitComment(n.DatabaseID)),
+		HTMLURL:   github.String(n.URL),
+	}
+
+	if n.Commit != nil {
+		c.CommitID = github.String(n.Commit.OID)
+	}
+
+	return c
+}
+
+func (n eventNode) issueEvent(u urls, issue *github.Issue) *github.IssueEvent {
+	id := eventID(n.ID)
+
+	event := &github.IssueEvent{
+		ID:        github.Int(id),
+		URL:       github.String(u.issueEvent(id)),
+		Actor:     n.Actor.user(),
+		CreatedAt: timePtr(n.CreatedAt),
+		Issue:     issue,
+	}
+
+	switch n.Typename {
+	case "LabeledEvent":
+		event.Event = github.String("labeled")
+	case "ClosedEvent":
+		event.Event = github.String("closed")
+	case "MergedEvent":
+		event.Event = github.String("merged")
+	default:
+		event.Event = github.String(strings.ToLower(strings.TrimSuffix(n.Typename, "Event")))
+	}
+
+	if n.Label != nil {
+		event.Label = &github.Label{Name: github.String(n.Label.Name), Color: github.String(n.Label.Color)}
+	}
+
+	return event
+}
+
+func (n reviewNode) review(u urls, number int) *github.PullRequestReview {
+	review := &github.PullRequestReview{
+		ID:             github.Int(n.DatabaseID),
+		User:           n.Author.user(),
+		Body:           github.String(n.Body),
+		State:          github.String(n.State),
+		SubmittedAt:    n.SubmittedAt,
+		HTMLURL:        github.String(n.URL),
+		PullRequestURL: github.String(u.pull(number)),
+	}
+
+	if n.Commit != nil {
+		review.CommitID = github.String(n.Commit.OID)
+	}
+
+	return review
+}
+
+func (n repositoryNode) repository() *github.Repository {
+	repo := &github.Repository{
+		ID:              github.Int(n.DatabaseID),
+		Name:            github.String(n.Name),
+		FullName:        github.String(n.NameWithOwner),
+		Owner:           n.Owner.user(),
+		HTMLURL:         github.String(n.URL),
+		Fork:            github.Bool(n.IsFork),
+		Private:         github.Bool(n.IsPrivate),
+		CreatedAt:       &github.Timestamp{Time: n.CreatedAt},
+		StargazersCount: github.Int(n.Stargazers.TotalCount),
+		ForksCount:      github.Int(n.ForkCount),
+		OpenIssuesCount: github.Int(n.Issues.TotalCount),
+	}
+
+	if n.DefaultBranchRef != nil {
+		repo.DefaultBranch = github.String(n.DefaultBranchRef.Name)
+	}
+
+	return repo
+}
+
+func (n releaseNode) release() *github.RepositoryRelease {
+	release := &github.RepositoryRelease{
+		ID:         github.Int(n.DatabaseID),
+		TagName:    github.String(n.TagName),
+		Name:       github.String(n.Name),
+		Draft:      github.Bool(n.IsDraft),
+		Prerelease: github.Bool(n.IsPrerelease),
+		CreatedAt:  &github.Timestamp{Time: n.CreatedAt},
+		HTMLURL:    github.String(n.URL),
+		Author:     n.Author.user(),
+	}
+
+	if n.PublishedAt != nil {
+		release.PublishedAt = &github.Timestamp{Time: *n.PublishedAt}
+	}
+
+	for _, a := range n.ReleaseAssets.Nodes {
+		release.Assets = append(release.Assets, github.ReleaseAsset{
+			Name:          github.String(a.Name),
+			ContentType:   github.String(a.ContentType),
+			Size:          github.Int(a.Size),
+			DownloadCount: github.Int(a.DownloadCount),
+			CreatedAt:     &github.Timestamp{Time: a.CreatedAt},
+		})
+	}
+
+	return release
+}
+
+// state converts OPEN, CLOSED and MERGED to the REST API's open and closed.
+func state(s string) string {
+	if s == "OPEN" {
+		return "open"
+	}
+
+	return "closed"
+}
+
+// eventID derives a stable numeric ID from an event's node ID, as the
+// GraphQL API does not expose the database IDs of timeline events.
+func eventID(nodeID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(nodeID))
+	return int(h.Sum32())
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,281 @@
+package graphql
+
+// Fragments are only included in the queries that use them, as GraphQL
+// rejects queries defining fragments they do not spread.
+
+const actorFragment = `
+fragment actor on Actor {
+  __typename
+  login
+  url
+  ... on User { databaseId }
+  ... on Bot { databaseId }
+  ... on Organization { databaseId }
+}
+`
+
+const reactionsFragment = `
+fragment reactions on Reactable {
+  reactionGroups { content reactors { totalCount } }
+}
+`
+
+const commentsFragment = `
+fragment comments on IssueCommentConnection {
+  totalCount
+  pageInfo { hasNextPage endCursor }
+  nodes {
+    databaseId
+    author { ...actor }
+    body
+    createdAt
+    updatedAt
+    url
+    ...reactions
+  }
+}
+`
+
+const eventsFragment = `
+fragment events on IssueTimelineItemsConnection {
+  pageInfo { hasNextPage endCursor }
+  nodes {
+    __typename
+    ... on LabeledEvent { id createdAt actor { ...actor } label { name color } }
+    ... on ClosedEvent { id createdAt actor { ...actor } }
+  }
+}
+`
+
+const pullRequestEventsFragment = `
+fragment pullRequestEvents on PullRequestTimelineItemsConnection {
+  pageInfo { hasNextPage endCursor }
+  nodes {
+    __typename
+    ... on LabeledEvent { id createdAt actor { ...actor } label { name color } }
+    ... on ClosedEvent { id createdAt actor { ...actor } }
+    ... on MergedEvent { id createdAt actor { ...actor } }
+  }
+}
+`
+
+const reviewsFragment = `
+fragment reviews on PullRequestReviewConnection {
+  pageInfo { hasNextPage endCursor }
+  nodes {
+    databaseId
+    author { ...actor }
+    body
+    state
+    submittedAt
+    url
+    commit { oid }
+  }
+}
+`
+
+const issueFragment = `
+fragment issue on Issue {
+  id
+  databaseId
+  number
+  title
+  state
+  url
+  createdAt
+  updatedAt
+  closedAt
+  author { ...actor }
+  labels(first: 100) { nodes { name color } }
+  ...reactions
+}
+`
+
+const pullRequestFragment = `
+fragment pullRequest on PullRequest {
+  id
+  databaseId
+  number
+  title
+  state
+  url
+  createdAt
+  updatedAt
+  closedAt
+  author { ...actor }
+  labels(first: 100) { nodes { name color } }
+  ...reactions
+}
+`
+
+const rateLimitField = `rateLimit { limit cost remaining resetAt }`
+
+const repositoriesQuery = `
+query Repositories($org: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  organization(login: $org) {
+    repositories(first: $first, after: $after, privacy: PUBLIC) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId
+        name
+        nameWithOwner
+        owner { login url }
+        url
+        isFork
+        isPrivate
+        createdAt
+        defaultBranchRef { name }
+        stargazers { totalCount }
+        forkCount
+        issues(states: OPEN) { totalCount }
+      }
+    }
+  }
+}
+`
+
+const membersQuery = `
+query Members($org: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  organization(login: $org) {
+    membersWithRole(first: $first, after: $after) {
+      pageInfo { hasNextPage endCursor }
+      nodes { __typename login url databaseId }
+    }
+  }
+}
+`
+
+const issuesQuery = `
+query Issues($owner: String!, $name: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  repository(owner: $owner, name: $name) {
+    issues(first: $first, after: $after, orderBy: {field: CREATED_AT, direction: ASC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        ...issue
+        comments(first: $first) { ...comments }
+        timelineItems(first: $first, itemTypes: [LABELED_EVENT, CLOSED_EVENT]) { ...events }
+      }
+    }
+  }
+}
+` + issueFragment + commentsFragment + eventsFragment + actorFragment + reactionsFragment
+
+const pullRequestsQuery = `
+query PullRequests($owner: String!, $name: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  repository(owner: $owner, name: $name) {
+    pullRequests(first: $first, after: $after, orderBy: {field: CREATED_AT, direction: ASC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        ...pullRequest
+        comments(first: $first) { ...comments }
+        timelineItems(first: $first, itemTypes: [LABELED_EVENT, CLOSED_EVENT, MERGED_EVENT]) { ...pullRequestEvents }
+        reviews(first: $first) { ...reviews }
+      }
+    }
+  }
+}
+` + pullRequestFragment + commentsFragment + pullRequestEventsFragment + reviewsFragment + actorFragment + reactionsFragment
+
+const commitCommentsQuery = `
+query CommitComments($owner: String!, $name: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  repository(owner: $owner, name: $name) {
+    commitComments(first: $first, after: $after) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId
+        author { ...actor }
+        body
+        createdAt
+        updatedAt
+        url
+        commit { oid }
+        ...reactions
+      }
+    }
+  }
+}
+` + actorFragment + reactionsFragment
+
+// The follow-up queries page through a connection nested in an issue or
+// pull request whose first page did not hold all of it.
+
+const moreCommentsQuery = `
+query MoreComments($id: ID!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  node(id: $id) {
+    ... on Issue { comments(first: $first, after: $after) { ...comments } }
+    ... on PullRequest { comments(first: $first, after: $after) { ...comments } }
+  }
+}
+` + commentsFragment + actorFragment + reactionsFragment
+
+const moreEventsQuery = `
+query MoreEvents($id: ID!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  node(id: $id) {
+    ... on Issue { issueEvents: timelineItems(first: $first, after: $after, itemTypes: [LABELED_EVENT, CLOSED_EVENT]) { ...events } }
+    ... on PullRequest { pullRequestEvents: timelineItems(first: $first, after: $after, itemTypes: [LABELED_EVENT, CLOSED_EVENT, MERGED_EVENT]) { ...pullRequestEvents } }
+  }
+}
+` + eventsFragment + pullRequestEventsFragment + actorFragment
+
+const moreReviewsQuery = `
+query MoreReviews($id: ID!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  node(id: $id) {
+    ... on PullRequest { reviews(first: $first, after: $after) { ...reviews } }
+  }
+}
+` + reviewsFragment + actorFragment
+
+const stargazersQuery = `
+query Stargazers($owner: String!, $name: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  repository(owner: $owner, name: $name) {
+    stargazers(first: $first, after: $after, orderBy: {field: STARRED_AT, direction: ASC}) {
+      pageInfo { hasNextPage endCursor }
+      edges { starredAt node { __typename login url databaseId } }
+    }
+  }
+}
+`
+
+const releasesQuery = `
+query Releases($owner: String!, $name: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  repository(owner: $owner, name: $name) {
+    releases(first: $first, after: $after, orderBy: {field: CREATED_AT, direction: ASC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId
+        tagName
+        name
+        isDraft
+        isPrerelease
+        createdAt
+        publishedAt
+        url
+        author { __typename login url databaseId }
+        releaseAssets(first: 100) { nodes { name contentType size downloadCount createdAt } }
+      }
+    }
+  }
+}
+`
+
+const watchersQuery = `
+query Watchers($owner: String!, $name: String!, $first: Int!, $after: String) {
+  ` + rateLimitField + `
+  repository(owner: $owner, name: $name) {
+    watchers(first: $first, after: $after) {
+      pageInfo { hasNextPage endCursor }
+      nodes { __typename login url databaseId }
+    }
+  }
+}
+`
@@ -0,0 +1,77 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+func (client *Client) ReleasesForOrganizations(ctx context.Context, orgs []string) (map[string][]*gh.Release, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("releases", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*gh.Release{}
+	for _, repo := range repos {
+		releases, err := client.AllReleasesForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = releases
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// AllReleasesForRepository returns the repository's published releases,
+// oldest first, with the download counts of their first 100 assets.
+func (client *Client) AllReleasesForRepository(ctx context.Context, repo *github.Repository) ([]*gh.Release, error) {
+	u := client.urls(repo)
+	collectedAt := time.Now().UTC()
+
+	var all []*gh.Release
+	var after *string
+
+	for {
+		var result struct {
+			Repository struct {
+				Releases struct {
+					PageInfo pageInfo      `json:"pageInfo"`
+					Nodes    []releaseNode `json:"nodes"`
+				} `json:"releases"`
+			} `json:"repository"`
+		}
+
+		err := client.query(ctx, releasesQuery, client.repositoryVariables(u, after), &result)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := result.Repository.Releases
+
+		for _, n := range connection.Nodes {
+			if !n.IsDraft && n.PublishedAt != nil {
+				all = append(all, &gh.Release{Release: n.release(), CollectedAt: collectedAt})
+			}
+		}
+
+		if !connection.PageInfo.HasNextPage {
+			break
+		}
+
+		after = github.String(connection.PageInfo.EndCursor)
+	}
+
+	gh.SortReleases(all)
+
+	return all, nil
+}
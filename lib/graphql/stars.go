@@ -0,0 +1,147 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func (client *Client) StargazersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.Stargazer, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("stargazers", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*github.Stargazer{}
+	for _, repo := range repos {
+		stargazers, err := client.AllStargazersForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = stargazers
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+func (client *Client) WatchersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.User, error) {
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Progress.Begin("watchers", len(repos))
+	defer client.Progress.End()
+
+	all := map[string][]*github.User{}
+	for _, repo := range repos {
+		watchers, err := client.AllWatchersForRepository(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		all[repo.GetFullName()] = watchers
+
+		client.Progress.RepositoryDone(repo.GetFullName())
+	}
+
+	return all, nil
+}
+
+// AllStargazersForRepository returns everyone who starred the repository,
+// oldest star first, with when they starred it.
+func (client *Client) AllStargazersForRepository(ctx context.Context, repo *github.Repository) ([]*github.Stargazer, error) {
+	u := client.urls(repo)
+
+	var all []*github.Stargazer
+	var after *string
+
+	for {
+		var result struct {
+			Repository struct {
+				Stargazers struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Edges    []struct {
+						StarredAt time.Time `json:"starredAt"`
+						Node      actor     `json:"node"`
+					} `json:"edges"`
+				} `json:"stargazers"`
+			} `json:"repository"`
+		}
+
+		err := client.query(ctx, stargazersQuery, client.repositoryVariables(u, after), &result)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := result.Repository.Stargazers
+
+		for i := range connection.Edges {
+			edge := connection.Edges[i]
+
+			user := edge.Node.user()
+			if !client.excludes(user) {
+				all = append(all, &github.Stargazer{
+					StarredAt: &github.Timestamp{Time: edge.StarredAt},
+					User:      user,
+				})
+			}
+		}
+
+		if !connection.PageInfo.HasNextPage {
+			break
+		}
+
+		after = github.String(connection.PageInfo.EndCursor)
+	}
+
+	return all, nil
+}
+
+// AllWatchersForRepository returns everyone watching the repository.
+func (client *Client) AllWatchersForRepository(ctx context.Context, repo *github.Repository) ([]*github.User, error) {
+	u := client.urls(repo)
+
+	var all []*github.User
+	var after *string
+
+	for {
+		var result struct {
+			Repository struct {
+				Watchers struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []actor  `json:"nodes"`
+				} `json:"watchers"`
+			} `json:"repository"`
+		}
+
+		err := client.query(ctx, watchersQuery, client.repositoryVariables(u, after), &result)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := result.Repository.Watchers
+
+		for i := range connection.Nodes {
+			user := connection.Nodes[i].user()
+			if !client.excludes(user) {
+				all = append(all, user)
+			}
+		}
+
+		if !connection.PageInfo.HasNextPage {
+			break
+		}
+
+		after = github.String(connection.PageInfo.EndCursor)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,150 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/google/go-github/github"
+)
+
+// Reporter tracks how far a crawl has got. It either redraws a single status
+// line on a terminal or, when output is not interactive, emits a lager event
+// at most once per interval.
+type Reporter struct {
+	// Now is the clock the ETA and logging interval are measured by; it
+	// defaults to time.Now.
+	Now func() time.Time
+
+	terminal io.Writer
+	logger   lager.Logger
+	interval time.Duration
+
+	lock     sync.Mutex
+	phase    string
+	total    int
+	done     int
+	pages    int
+	rate     github.Rate
+	started  time.Time
+	reported time.Time
+}
+
+// NewTerminalReporter redraws a live progress line on w, which should be a
+// terminal.
+func NewTerminalReporter(w io.Writer) *Reporter {
+	return &Reporter{
+		terminal: w,
+		Now:      time.Now,
+	}
+}
+
+// NewLoggingReporter logs progress to logger at most once per interval, as
+// well as at the start and end of every phase.
+func NewLoggingReporter(logger lager.Logger, interval time.Duration) *Reporter {
+	return &Reporter{
+		logger:   logger.Session("progress"),
+		interval: interval,
+		Now:      time.Now,
+	}
+}
+
+func (r *Reporter) Begin(phase string, repositories int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.phase = phase
+	r.total = repositories
+	r.done = 0
+	r.pages = 0
+	r.started = r.Now()
+
+	if r.logger != nil {
+		r.logger.Info("begin", r.data())
+		r.reported = r.started
+		return
+	}
+
+	r.report()
+}
+
+func (r *Reporter) RepositoryDone(repository string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.done++
+	r.report()
+}
+
+func (r *Reporter) PageFetched(rate github.Rate) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pages++
+	r.rate = rate
+	r.report()
+}
+
+func (r *Reporter) End() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.logger != nil {
+		r.logger.Info("end", r.data())
+		return
+	}
+
+	r.report()
+	fmt.Fprintln(r.terminal)
+}
+
+// ETA estimates how long the current phase will take to finish based on how
+// long the repositories done so far have taken.
+func (r *Reporter) ETA() time.Duration {
+	if r.done == 0 || r.done >= r.total {
+		return 0
+	}
+
+	perRepository := r.Now().Sub(r.started) / time.Duration(r.done)
+
+	return perRepository * time.Duration(r.total-r.done)
+}
+
+func (r *Reporter) report() {
+	if r.logger != nil {
+		if r.Now().Sub(r.reported) < r.interval {
+			return
+		}
+
+		r.logger.Info("progress", r.data())
+		r.reported = r.Now()
+		return
+	}
+
+	fmt.Fprintf(
+		r.terminal,
+		"\r\033[K%s: %d/%d repositories, %d pages, %d/%d requests left, ETA %s",
+		r.phase,
+		r.done,
+		r.total,
+		r.pages,
+		r.rate.Remaining,
+		r.rate.Limit,
+		r.ETA().Truncate(time.Second),
+	)
+}
+
+func (r *Reporter) data() lager.Data {
+	return lager.Data{
+		"phase":              r.phase,
+		"repositories-done":  r.done,
+		"repositories-total": r.total,
+		"pages-fetched":      r.pages,
+		"rate-remaining":     r.rate.Remaining,
+		"rate-limit":         r.rate.Limit,
+		"rate-reset":         r.rate.Reset.Time,
+		"eta":                r.ETA().String(),
+	}
+}
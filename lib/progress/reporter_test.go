@@ -0,0 +1,159 @@
+package progress_test
+
+import (
+	"bytes"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/progress"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reporter", func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2017, time.June, 1, 9, 0, 0, 0, time.UTC)
+	})
+
+	clock := func() time.Time { return now }
+	advance := func(d time.Duration) { now = now.Add(d) }
+
+	Describe("on a terminal", func() {
+		var (
+			terminal *bytes.Buffer
+			reporter *progress.Reporter
+		)
+
+		BeforeEach(func() {
+			terminal = &bytes.Buffer{}
+			reporter = progress.NewTerminalReporter(terminal)
+			reporter.Now = clock
+		})
+
+		// line is the status line the reporter last drew.
+		line := func() string {
+			lines := bytes.Split(terminal.Bytes(), []byte("\r\033[K"))
+			return string(lines[len(lines)-1])
+		}
+
+		It("redraws the status line after every repository and page", func() {
+			reporter.Begin("issues", 4)
+			Expect(terminal.String()).To(Equal("\r\033[Kissues: 0/4 repositories, 0 pages, 0/0 requests left, ETA 0s"))
+
+			advance(10 * time.Second)
+			reporter.RepositoryDone("acme/widget")
+			Expect(line()).To(Equal("issues: 1/4 repositories, 0 pages, 0/0 requests left, ETA 30s"))
+
+			advance(5 * time.Second)
+			reporter.PageFetched(github.Rate{Limit: 5000, Remaining: 4998})
+			Expect(line()).To(Equal("issues: 1/4 repositories, 1 pages, 4998/5000 requests left, ETA 45s"))
+
+			reporter.End()
+			Expect(terminal.String()).To(HaveSuffix("issues: 1/4 repositories, 1 pages, 4998/5000 requests left, ETA 45s\n"))
+		})
+
+		It("starts each phase afresh", func() {
+			reporter.Begin("issues", 2)
+			reporter.PageFetched(github.Rate{Limit: 5000, Remaining: 4999})
+			reporter.RepositoryDone("acme/widget")
+			reporter.End()
+
+			reporter.Begin("comments", 3)
+			Expect(line()).To(Equal("comments: 0/3 repositories, 0 pages, 4999/5000 requests left, ETA 0s"))
+		})
+	})
+
+	Describe("ETA", func() {
+		var reporter *progress.Reporter
+
+		BeforeEach(func() {
+			reporter = progress.NewTerminalReporter(&bytes.Buffer{})
+			reporter.Now = clock
+		})
+
+		It("projects the time per repository so far over those left", func() {
+			reporter.Begin("issues", 5)
+
+			advance(3 * time.Second)
+			reporter.RepositoryDone("acme/widget")
+			reporter.RepositoryDone("acme/gadget")
+			Expect(reporter.ETA()).To(Equal(4500 * time.Millisecond))
+
+			advance(3 * time.Second)
+			Expect(reporter.ETA()).To(Equal(9 * time.Second))
+		})
+
+		It("is zero before the first repository and after the last", func() {
+			reporter.Begin("issues", 1)
+
+			advance(time.Minute)
+			Expect(reporter.ETA()).To(BeZero())
+
+			reporter.RepositoryDone("acme/widget")
+			Expect(reporter.ETA()).To(BeZero())
+		})
+	})
+
+	Describe("logging", func() {
+		var (
+			logger   *lagertest.TestLogger
+			reporter *progress.Reporter
+		)
+
+		BeforeEach(func() {
+			logger = lagertest.NewTestLogger("test")
+			reporter = progress.NewLoggingReporter(logger, 30*time.Second)
+			reporter.Now = clock
+		})
+
+		It("logs the start and end of a phase and its progress at most once per interval", func() {
+			reporter.Begin("issues", 4)
+
+			advance(10 * time.Second)
+			reporter.RepositoryDone("acme/widget")
+			reporter.PageFetched(github.Rate{Limit: 5000, Remaining: 4999})
+
+			advance(20 * time.Second)
+			reporter.PageFetched(github.Rate{Limit: 5000, Remaining: 4998})
+
+			advance(29 * time.Second)
+			reporter.RepositoryDone("acme/gadget")
+
+			advance(time.Second)
+			reporter.RepositoryDone("acme/sprocket")
+
+			reporter.End()
+
+			Expect(logger.LogMessages()).To(Equal([]string{
+				"test.progress.begin",
+				"test.progress.progress",
+				"test.progress.progress",
+				"test.progress.end",
+			}))
+
+			logs := logger.Logs()
+			Expect(logs[1].Data).To(HaveKeyWithValue("repositories-done", BeNumerically("==", 1)))
+			Expect(logs[1].Data).To(HaveKeyWithValue("pages-fetched", BeNumerically("==", 2)))
+			Expect(logs[1].Data).To(HaveKeyWithValue("rate-remaining", BeNumerically("==", 4998)))
+			Expect(logs[1].Data).To(HaveKeyWithValue("eta", "1m30s"))
+
+			Expect(logs[3].Data).To(HaveKeyWithValue("phase", "issues"))
+			Expect(logs[3].Data).To(HaveKeyWithValue("repositories-done", BeNumerically("==", 3)))
+			Expect(logs[3].Data).To(HaveKeyWithValue("repositories-total", BeNumerically("==", 4)))
+			Expect(logs[3].Data).To(HaveKeyWithValue("rate-limit", BeNumerically("==", 5000)))
+			Expect(logs[3].Data).To(HaveKeyWithValue("eta", "20s"))
+		})
+
+		It("only logs the start and end of a phase shorter than the interval", func() {
+			reporter.Begin("issues", 1)
+			reporter.RepositoryDone("acme/widget")
+			reporter.End()
+
+			Expect(logger.LogMessages()).To(Equal([]string{"test.progress.begin", "test.progress.end"}))
+		})
+	})
+})
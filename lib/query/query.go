@@ -0,0 +1,494 @@
+// Package query parses the filter expressions accepted by pm query.
+//
+// An expression is a list of terms combined with and, or, not and
+// parentheses; terms next to each other must all match:
+//
+//	type:issue-comments (repo:cloudfoundry/cli or repo:cloudfoundry/bosh) date>=2017-05-01 date<2017-06-01
+//
+// Terms are field:value, or field followed by <, <=, > or >= for dates:
+//
+//	user:LOGIN           activity by this user (globs allowed)
+//	repo:NAME            activity in a repository, matched as name or owner/name (globs allowed)
+//	type:KIND            issues, issue-comments, repository-comments, issue-events, reviews or events
+//	date:DAY             activity created on a day, or before/after it with <, <=, > or >=
+//	label:NAME           activity on an issue or pull request with this label (globs allowed)
+//	affiliation:member   activity by organization members, or affiliation:external for everyone else
+//
+// Values containing spaces or parentheses can be quoted: label:"good first issue".
+package query
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/chendrix/pm/lib/store"
+)
+
+// Event is the activity an expression is matched against.
+type Event struct {
+	Kind       string
+	Repository string
+	User       string
+	CreatedAt  time.Time
+	Labels     []string
+	Member     bool
+}
+
+// Expr is a parsed filter expression.
+type Expr interface {
+	Match(e Event) bool
+}
+
+type and []Expr
+
+func (a and) Match(e Event) bool {
+	for _, x := range a {
+		if !x.Match(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type or []Expr
+
+func (o or) Match(e Event) bool {
+	for _, x := range o {
+		if x.Match(e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type not struct {
+	Expr
+}
+
+func (n not) Match(e Event) bool {
+	return !n.Expr.Match(e)
+}
+
+// all matches every event; it is what an empty expression parses to.
+type all struct{}
+
+func (all) Match(e Event) bool {
+	return true
+}
+
+type term struct {
+	field string
+	op    string
+	value string
+
+	// date is the start of the day (or the instant) given for date terms
+	date time.Time
+	// end is the first instant after the day (or the instant) given
+	end time.Time
+}
+
+func (t term) Match(e Event) bool {
+	switch t.field {
+	case "user":
+		matched, _ := path.Match(t.value, e.User)
+		return matched
+	case "repo":
+		return matchRepository(t.value, e.Repository)
+	case "type":
+		return e.Kind == t.value
+	case "label":
+		for _, l := range e.Labels {
+			if matched, _ := path.Match(t.value, l); matched {
+				return true
+			}
+		}
+
+		return false
+	case "affiliation":
+		return e.Member == (t.value == "member")
+	case "date":
+		switch t.op {
+		case "<":
+			return e.CreatedAt.Before(t.date)
+		case "<=":
+			return e.CreatedAt.Before(t.end)
+		case ">":
+			return !e.CreatedAt.Before(t.end)
+		case ">=":
+			return !e.CreatedAt.Before(t.date)
+		default:
+			return !e.CreatedAt.Before(t.date) && e.CreatedAt.Before(t.end)
+		}
+	}
+
+	return false
+}
+
+func matchRepository(pattern string, fullName string) bool {
+	if matched, _ := path.Match(pattern, fullName); matched {
+		return true
+	}
+
+	name := fullName
+	if i := strings.LastIndex(fullName, "/"); i >= 0 {
+		name = fullName[i+1:]
+	}
+
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+// Parse parses an expression. An empty expression matches everything.
+func Parse(expression string) (Expr, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return all{}, nil
+	}
+
+	p := &parser{tokens: tokens}
+
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q", p.peek())
+	}
+
+	return expr, nil
+}
+
+// References reports whether the expression uses the given field, so
+// callers can skip gathering what it does not need.
+func References(expr Expr, field string) bool {
+	switch x := expr.(type) {
+	case and:
+		for _, e := range x {
+			if References(e, field) {
+				return true
+			}
+		}
+	case or:
+		for _, e := range x {
+			if References(e, field) {
+				return true
+			}
+		}
+	case not:
+		return References(x.Expr, field)
+	case term:
+		return x.field == field
+	}
+
+	return false
+}
+
+// Narrow returns the store query covering every event the expression can
+// match, using the terms that all matching events must satisfy.
+func Narrow(expr Expr) store.Query {
+	var q store.Query
+
+	terms := []Expr{expr}
+	if a, ok := expr.(and); ok {
+		terms = a
+	}
+
+	for _, e := range terms {
+		t, ok := e.(term)
+		if !ok {
+			continue
+		}
+
+		switch t.field {
+		case "user":
+			if !hasMeta(t.value) {
+				q.User = t.value
+			}
+		case "repo":
+			if !hasMeta(t.value) && strings.Contains(t.value, "/") {
+				q.Repository = t.value
+			}
+		case "type":
+			q.Kind = t.value
+		case "date":
+			since, until := t.date, t.end
+			switch t.op {
+			case "<":
+				since, until = time.Time{}, t.date
+			case "<=":
+				since = time.Time{}
+			case ">":
+				since, until = t.end, time.Time{}
+			case ">=":
+				until = time.Time{}
+			}
+
+			if since.After(q.Since) {
+				q.Since = since
+			}
+
+			if !until.IsZero() && (q.Until.IsZero() || until.Before(q.Until)) {
+				q.Until = until
+			}
+		}
+	}
+
+	return q
+}
+
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() string {
+	if p.done() {
+		return ""
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *parser) keyword(word string) bool {
+	if strings.EqualFold(p.peek(), word) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) or() (Expr, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := or{first}
+	for p.keyword("or") {
+		next, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, next)
+	}
+
+	if len(exprs) == 1 {
+		return first, nil
+	}
+
+	return exprs, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	first, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := and{first}
+	for {
+		if p.keyword("and") {
+			next, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+
+			exprs = append(exprs, next)
+			continue
+		}
+
+		if p.done() || p.peek() == ")" || strings.EqualFold(p.peek(), "or") {
+			break
+		}
+
+		next, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, next)
+	}
+
+	if len(exprs) == 1 {
+		return first, nil
+	}
+
+	return exprs, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.done() {
+		return nil, fmt.Errorf("expression ends unexpectedly")
+	}
+
+	if p.keyword("not") {
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return not{expr}, nil
+	}
+
+	if p.keyword("(") {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+
+		return expr, nil
+	}
+
+	token := p.peek()
+	if token == ")" || strings.EqualFold(token, "and") || strings.EqualFold(token, "or") {
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+
+	p.pos++
+
+	return parseTerm(token)
+}
+
+var operators = []string{">=", "<=", ":", ">", "<"}
+
+func parseTerm(token string) (Expr, error) {
+	for i, c := range token {
+		if !strings.ContainsRune(":<>", c) {
+			continue
+		}
+
+		t := term{field: token[:i]}
+
+		for _, op := range operators {
+			if strings.HasPrefix(token[i:], op) {
+				t.op = op
+				break
+			}
+		}
+
+		t.value = token[i+len(t.op):]
+		if t.value == "" {
+			return nil, fmt.Errorf("%s has no value", token)
+		}
+
+		if t.op != ":" && t.field != "date" {
+			return nil, fmt.Errorf("%s: only date can be compared with %s", token, t.op)
+		}
+
+		switch t.field {
+		case "user", "repo", "label":
+			if _, err := path.Match(t.value, ""); err != nil {
+				return nil, fmt.Errorf("%s: %s", token, err)
+			}
+		case "type":
+			if !validKind(t.value) {
+				return nil, fmt.Errorf("%s: type must be one of %s", token, strings.Join(store.Kinds, ", "))
+			}
+		case "affiliation":
+			if t.value != "member" && t.value != "external" {
+				return nil, fmt.Errorf("%s: affiliation must be member or external", token)
+			}
+		case "date":
+			var err error
+			t.date, t.end, err = parseDate(t.value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", token, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown field %q", token, t.field)
+		}
+
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("%q is not a field:value term", token)
+}
+
+func validKind(kind string) bool {
+	for _, k := range store.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDate parses a day (YYYY-MM-DD, in UTC), returning its start and the
+// start of the next day, or an RFC 3339 instant, returning it and the
+// instant after it.
+func parseDate(value string) (time.Time, time.Time, error) {
+	day, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+
+	instant, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("dates must be YYYY-MM-DD or RFC 3339")
+	}
+
+	return instant, instant.Add(time.Nanosecond), nil
+}
+
+// lex splits an expression into parentheses and words, keeping quoted
+// sections of a word together.
+func lex(expression string) ([]string, error) {
+	var tokens []string
+	var word []rune
+	quoted := false
+
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = nil
+		}
+	}
+
+	for _, c := range expression {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			word = append(word, c)
+		case c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		default:
+			word = append(word, c)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+
+	flush()
+
+	return tokens, nil
+}
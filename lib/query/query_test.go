@@ -0,0 +1,279 @@
+package query_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chendrix/pm/lib/query"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	Expect(err).NotTo(HaveOccurred())
+
+	return t
+}
+
+var _ = Describe("Query", func() {
+	var event query.Event
+
+	BeforeEach(func() {
+		event = query.Event{
+			Kind:       store.KindIssueComment,
+			Repository: "acme/widget",
+			User:       "alice",
+			CreatedAt:  time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC),
+			Labels:     []string{"bug", "good first issue"},
+			Member:     true,
+		}
+	})
+
+	matches := func(expression string) bool {
+		expr, err := query.Parse(expression)
+		Expect(err).NotTo(HaveOccurred())
+
+		return expr.Match(event)
+	}
+
+	Describe("Parse", func() {
+		DescribeTable("rejecting malformed expressions",
+			func(expression string, message string) {
+				_, err := query.Parse(expression)
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("unterminated quote", `label:"good first`, "unterminated quote"),
+			Entry("missing closing parenthesis", "(user:alice or user:bob", "missing )"),
+			Entry("stray closing parenthesis", "user:alice)", `unexpected ")"`),
+			Entry("dangling or", "user:alice or", "expression ends unexpectedly"),
+			Entry("leading and", "and user:alice", `unexpected "and"`),
+			Entry("dangling not", "not", "expression ends unexpectedly"),
+			Entry("bare word", "alice", "is not a field:value term"),
+			Entry("term without a value", "user:", "has no value"),
+			Entry("unknown field", "author:alice", `unknown field "author"`),
+			Entry("comparing a field other than date", "user>alice", "only date can be compared with >"),
+			Entry("unknown type", "type:pushes", "type must be one of"),
+			Entry("unknown affiliation", "affiliation:staff", "affiliation must be member or external"),
+			Entry("malformed date", "date>=May", "dates must be YYYY-MM-DD or RFC 3339"),
+			Entry("malformed glob", "user:[alice", "syntax error in pattern"),
+		)
+
+		It("matches everything when the expression is empty", func() {
+			Expect(matches("")).To(BeTrue())
+			Expect(matches("  \t\n")).To(BeTrue())
+		})
+
+		It("keeps quoted spaces and parentheses in a value", func() {
+			Expect(matches(`label:"good first issue"`)).To(BeTrue())
+			Expect(matches(`label:"good (first) issue"`)).To(BeFalse())
+
+			_, err := query.Parse(`label:good first issue`)
+			Expect(err).To(MatchError(`"first" is not a field:value term`))
+		})
+
+		It("splits parentheses from the words next to them", func() {
+			Expect(matches("(user:alice)")).To(BeTrue())
+			Expect(matches("not(user:bob)")).To(BeTrue())
+		})
+
+		It("reads keywords in any case", func() {
+			Expect(matches("user:bob OR user:alice")).To(BeTrue())
+			Expect(matches("NOT user:alice")).To(BeFalse())
+			Expect(matches("user:alice AND repo:gadget")).To(BeFalse())
+		})
+
+		It("tells which fields an expression references", func() {
+			expr, err := query.Parse("type:reviews (user:alice or not label:bug)")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(query.References(expr, "label")).To(BeTrue())
+			Expect(query.References(expr, "user")).To(BeTrue())
+			Expect(query.References(expr, "affiliation")).To(BeFalse())
+		})
+	})
+
+	Describe("Match", func() {
+		DescribeTable("terms",
+			func(expression string, matched bool) {
+				Expect(matches(expression)).To(Equal(matched))
+			},
+			Entry("user", "user:alice", true),
+			Entry("another user", "user:bob", false),
+			Entry("user glob", "user:al*", true),
+			Entry("repository by full name", "repo:acme/widget", true),
+			Entry("repository by name", "repo:widget", true),
+			Entry("repository glob", "repo:acme/*", true),
+			Entry("another repository", "repo:gadget", false),
+			Entry("type", "type:issue-comments", true),
+			Entry("another type", "type:issues", false),
+			Entry("label", "label:bug", true),
+			Entry("label glob", "label:good*", true),
+			Entry("missing label", "label:wontfix", false),
+			Entry("member", "affiliation:member", true),
+			Entry("external", "affiliation:external", false),
+			Entry("day", "date:2017-05-01", true),
+			Entry("another day", "date:2017-05-02", false),
+			Entry("instant", "date:2017-05-01T12:00:00Z", true),
+			Entry("another instant", "date:2017-05-01T12:00:01Z", false),
+		)
+
+		DescribeTable("date operators on a day include or exclude the whole day",
+			func(expression string, matched bool) {
+				Expect(matches(expression)).To(Equal(matched))
+			},
+			Entry("< the same day", "date<2017-05-01", false),
+			Entry("< the next day", "date<2017-05-02", true),
+			Entry("<= the same day", "date<=2017-05-01", true),
+			Entry("<= the day before", "date<=2017-04-30", false),
+			Entry("> the same day", "date>2017-05-01", false),
+			Entry("> the day before", "date>2017-04-30", true),
+			Entry(">= the same day", "date>=2017-05-01", true),
+			Entry(">= the next day", "date>=2017-05-02", false),
+		)
+
+		DescribeTable("date operators on an instant compare to it exactly",
+			func(expression string, matched bool) {
+				Expect(matches(expression)).To(Equal(matched))
+			},
+			Entry("< the instant", "date<2017-05-01T12:00:00Z", false),
+			Entry("<= the instant", "date<=2017-05-01T12:00:00Z", true),
+			Entry("> the instant", "date>2017-05-01T12:00:00Z", false),
+			Entry(">= the instant", "date>=2017-05-01T12:00:00Z", true),
+			Entry("> a second before", "date>2017-05-01T11:59:59Z", true),
+		)
+
+		DescribeTable("combining terms",
+			func(expression string, matched bool) {
+				Expect(matches(expression)).To(Equal(matched))
+			},
+			Entry("implicit and, all matching", "user:alice repo:widget", true),
+			Entry("implicit and, one failing", "user:alice repo:gadget", false),
+			Entry("and", "user:alice and repo:gadget", false),
+			Entry("or", "user:bob or repo:widget", true),
+			Entry("not", "not user:bob", true),
+			Entry("not binds tighter than implicit and", "not user:bob user:alice", true),
+			Entry("not binds tighter than or", "not user:alice or user:alice", true),
+			Entry("implicit and binds tighter than or", "user:bob repo:widget or user:alice", true),
+			Entry("implicit and binds tighter than or, failing", "user:bob or user:carol repo:widget", false),
+			Entry("and binds tighter than or", "user:alice or user:bob and repo:gadget", true),
+			Entry("and binds tighter than or, failing", "user:bob and repo:widget or user:carol", false),
+			Entry("parentheses override precedence", "(user:alice or user:bob) and repo:gadget", false),
+			Entry("not applies to a parenthesised group", "not (user:bob or repo:gadget)", true),
+			Entry("double negation", "not not user:alice", true),
+		)
+	})
+
+	Describe("Narrow", func() {
+		narrow := func(expression string) store.Query {
+			expr, err := query.Parse(expression)
+			Expect(err).NotTo(HaveOccurred())
+
+			return query.Narrow(expr)
+		}
+
+		DescribeTable("date bounds, with Until exclusive",
+			func(expression string, since, until string) {
+				expected := store.Query{}
+				if since != "" {
+					expected.Since = day(since)
+				}
+
+				if until != "" {
+					expected.Until = day(until)
+				}
+
+				Expect(narrow(expression)).To(Equal(expected))
+			},
+			Entry("on a day", "date:2017-05-01", "2017-05-01", "2017-05-02"),
+			Entry("before a day", "date<2017-05-01", "", "2017-05-01"),
+			Entry("up to and including a day", "date<=2017-05-01", "", "2017-05-02"),
+			Entry("after a day", "date>2017-05-01", "2017-05-02", ""),
+			Entry("from a day", "date>=2017-05-01", "2017-05-01", ""),
+			Entry("between two days", "date>=2017-05-01 date<2017-06-01", "2017-05-01", "2017-06-01"),
+			Entry("keeping the narrowest bounds", "date>2017-05-01 date>=2017-04-01 date<=2017-06-30 date<2017-08-01", "2017-05-02", "2017-07-01"),
+		)
+
+		It("uses the user, full repository name and type terms", func() {
+			Expect(narrow("user:alice repo:acme/widget type:reviews")).To(Equal(store.Query{
+				User:       "alice",
+				Repository: "acme/widget",
+				Kind:       store.KindReview,
+			}))
+		})
+
+		It("leaves out globs and repository names without an owner", func() {
+			Expect(narrow("user:al* repo:widget")).To(Equal(store.Query{}))
+			Expect(narrow("repo:acme/*")).To(Equal(store.Query{}))
+		})
+
+		It("leaves out terms not every match must satisfy", func() {
+			Expect(narrow("user:alice or user:bob")).To(Equal(store.Query{}))
+			Expect(narrow("not user:alice")).To(Equal(store.Query{}))
+			Expect(narrow("type:issues (user:alice or user:bob)")).To(Equal(store.Query{Kind: store.KindIssue}))
+		})
+
+		Describe("against a store", func() {
+			var (
+				dir string
+				s   *store.Store
+			)
+
+			BeforeEach(func() {
+				var err error
+				dir, err = ioutil.TempDir("", "pm-query")
+				Expect(err).NotTo(HaveOccurred())
+
+				s, err = store.Open(filepath.Join(dir, "pm.db"))
+				Expect(err).NotTo(HaveOccurred())
+
+				issue := func(number int, at time.Time) *github.Issue {
+					return &github.Issue{ID: github.Int(number), Number: github.Int(number), CreatedAt: &at}
+				}
+
+				repo := &github.Repository{FullName: github.String("acme/widget")}
+				err = s.RecordRepository(repo, snapshot.Activity{Issues: []*github.Issue{
+					issue(1, time.Date(2017, time.April, 30, 23, 59, 59, 0, time.UTC)),
+					issue(2, time.Date(2017, time.May, 1, 0, 0, 0, 0, time.UTC)),
+					issue(3, time.Date(2017, time.May, 1, 23, 59, 59, 0, time.UTC)),
+					issue(4, time.Date(2017, time.May, 2, 0, 0, 0, 0, time.UTC)),
+				}})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				s.Close()
+				os.RemoveAll(dir)
+			})
+
+			DescribeTable("reading what the date operators match, up to the edges of the day",
+				func(expression string, ids ...int) {
+					var found []int
+					err := s.Each(narrow(expression), func(e store.Entry, payload []byte) error {
+						found = append(found, e.ID)
+						return nil
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(found).To(ConsistOf(ids))
+				},
+				Entry("on the day", "date:2017-05-01", 2, 3),
+				Entry("before the day", "date<2017-05-01", 1),
+				Entry("up to and including the day", "date<=2017-05-01", 1, 2, 3),
+				Entry("after the day", "date>2017-05-01", 4),
+				Entry("from the day", "date>=2017-05-01", 2, 3, 4),
+			)
+		})
+
+		It("narrows a single term on its own", func() {
+			Expect(narrow("user:alice")).To(Equal(store.Query{User: "alice"}))
+		})
+	})
+})
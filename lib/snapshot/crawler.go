@@ -0,0 +1,224 @@
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+// Crawler serves a snapshot's activity through the same interface as
+// gh.Client, applying the same repository filter and user exclusions.
+type Crawler struct {
+	Snapshot *Snapshot
+
+	RepositoryFilter gh.RepositoryFilter
+	ExcludedUsers    map[string]bool
+}
+
+var _ gh.Crawler = &Crawler{}
+
+func NewCrawler(s *Snapshot) *Crawler {
+	return &Crawler{
+		Snapshot: s,
+	}
+}
+
+func (c *Crawler) AllIssuesForOrganizations(ctx context.Context, orgs []string) ([]*github.Issue, error) {
+	var all []*github.Issue
+
+	allowed := c.repositories(orgs)
+	for _, i := range c.Snapshot.Issues {
+		if allowed[gh.IssueRepository(i)] && i.GetState() == "open" && !c.excludes(i.User) {
+			all = append(all, i)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) IssuesUpdatedSinceForOrganizations(ctx context.Context, orgs []string, since time.Time) ([]*github.Issue, error) {
+	var all []*github.Issue
+
+	allowed := c.repositories(orgs)
+	for _, i := range c.Snapshot.Issues {
+		if allowed[gh.IssueRepository(i)] && !i.GetUpdatedAt().Before(since) && !c.excludes(i.User) {
+			all = append(all, i)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) AllIssueCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueComment, error) {
+	var all []*github.IssueComment
+
+	allowed := c.repositories(orgs)
+	for _, comment := range c.Snapshot.IssueComments {
+		if allowed[gh.RepositoryFullName(comment.GetURL())] && !c.excludes(comment.User) {
+			all = append(all, comment)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) AllRepositoryCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.RepositoryComment, error) {
+	var all []*github.RepositoryComment
+
+	allowed := c.repositories(orgs)
+	for _, comment := range c.Snapshot.RepositoryComments {
+		if allowed[gh.RepositoryFullName(comment.GetURL())] && !c.excludes(comment.User) {
+			all = append(all, comment)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) AllIssueEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueEvent, error) {
+	var all []*github.IssueEvent
+
+	allowed := c.repositories(orgs)
+	for _, event := range c.Snapshot.IssueEvents {
+		if allowed[gh.RepositoryFullName(event.GetURL())] && !c.excludes(event.Actor) {
+			all = append(all, event)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) AllReviewsForIssues(ctx context.Context, issues []*github.Issue) ([]*github.PullRequestReview, error) {
+	pullRequests := gh.PullRequestsAmong(issues)
+
+	var all []*github.PullRequestReview
+	for _, review := range c.Snapshot.Reviews {
+		if pullRequests[gh.ReviewedPullRequest(review)] && !c.excludes(review.User) {
+			all = append(all, review)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) StreamEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.Event, error) {
+	var all []*github.Event
+
+	allowed := c.repositories(orgs)
+	for _, e := range c.Snapshot.Events {
+		if allowed[e.Repo.GetName()] && !c.excludes(e.Actor) {
+			all = append(all, e)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) StargazersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.Stargazer, error) {
+	all := map[string][]*github.Stargazer{}
+
+	for name := range c.repositories(orgs) {
+		var stargazers []*github.Stargazer
+		for _, stargazer := range c.Snapshot.Stargazers[name] {
+			if !c.excludes(stargazer.User) {
+				stargazers = append(stargazers, stargazer)
+			}
+		}
+
+		all[name] = stargazers
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) WatchersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.User, error) {
+	all := map[string][]*github.User{}
+
+	for name := range c.repositories(orgs) {
+		var watchers []*github.User
+		for _, user := range c.Snapshot.Watchers[name] {
+			if !c.excludes(user) {
+				watchers = append(watchers, user)
+			}
+		}
+
+		all[name] = watchers
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) ForksForOrganizations(ctx context.Context, orgs []string) (map[string][]*gh.Fork, error) {
+	all := map[string][]*gh.Fork{}
+
+	for name := range c.repositories(orgs) {
+		var forks []*gh.Fork
+		for _, fork := range c.Snapshot.Forks[name] {
+			if !c.excludes(fork.Repository.Owner) {
+				forks = append(forks, fork)
+			}
+		}
+
+		all[name] = forks
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) ReleasesForOrganizations(ctx context.Context, orgs []string) (map[string][]*gh.Release, error) {
+	all := map[string][]*gh.Release{}
+
+	for name := range c.repositories(orgs) {
+		all[name] = c.Snapshot.Releases[name]
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) TrafficForOrganizations(ctx context.Context, orgs []string) (map[string]gh.TrafficHistory, error) {
+	all := map[string]gh.TrafficHistory{}
+
+	for name := range c.repositories(orgs) {
+		if traffic, found := c.Snapshot.Traffic[name]; found {
+			all[name] = traffic
+		}
+	}
+
+	return all, nil
+}
+
+// Events returns the organizations' activity.
+func (c *Crawler) Events(ctx context.Context, orgs []string) ([]activity.Event, error) {
+	return gh.CrawlEvents(ctx, c, orgs)
+}
+
+func (c *Crawler) AllMembersForOrganizations(ctx context.Context, orgs []string) ([]*github.User, error) {
+	var all []*github.User
+
+	for _, org := range orgs {
+		all = append(all, c.Snapshot.Members[org]...)
+	}
+
+	return all, nil
+}
+
+// repositories returns the full names of the snapshot's repositories that
+// belong to one of the organizations and pass the repository filter.
+func (c *Crawler) repositories(orgs []string) map[string]bool {
+	allowed := map[string]bool{}
+
+	for _, repo := range c.Snapshot.Repositories {
+		if owned(repo, orgs) && c.RepositoryFilter.Allows(repo) {
+			allowed[repo.GetFullName()] = true
+		}
+	}
+
+	return allowed
+}
+
+func (c *Crawler) excludes(user *github.User) bool {
+	return user != nil && c.ExcludedUsers[user.GetLogin()]
+}
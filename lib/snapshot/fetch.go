@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+// Activity is everything crawled from a single repository.
+type Activity struct {
+	Issues             []*github.Issue
+	IssueComments      []*github.IssueComment
+	RepositoryComments []*github.RepositoryComment
+	IssueEvents        []*github.IssueEvent
+	Reviews            []*github.PullRequestReview
+	Stargazers         []*github.Stargazer
+	Watchers           []*github.User
+	Forks              []*gh.Fork
+	Releases           []*gh.Release
+
+	// Traffic is empty if the token may not see the repository's traffic.
+	Traffic gh.TrafficHistory
+}
+
+// Recorder keeps what Fetch crawls. Fetch hands it one repository at a time,
+// so a Recorder that writes through to disk never holds a whole organization
+// in memory.
+type Recorder interface {
+	RecordRepository(repo *github.Repository, activity Activity) error
+	RecordEvents(events []*github.Event) error
+	RecordMembers(org string, members []*github.User) error
+}
+
+// Fetch crawls every repository, issue, comment, issue event, review,
+// stargazer, watcher, fork, release, stream event and member of the given
+// organizations, and the traffic of the repositories the token may see, into
+// r.
+func Fetch(ctx context.Context, logger lager.Logger, client gh.Fetcher, orgs []string, r Recorder) error {
+	logger = logger.Session("fetch")
+
+	logger.Debug("gathering repositories")
+	repos, err := client.PublicRepositoriesForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	progress := client.ProgressReporter()
+	progress.Begin("fetch", len(repos))
+
+	for _, repo := range repos {
+		logger.Debug("gathering repository", lager.Data{"repository": repo.GetFullName()})
+
+		activity, err := fetchRepository(ctx, client, repo)
+		if err == nil {
+			err = r.RecordRepository(repo, activity)
+		}
+
+		if err != nil {
+			progress.End()
+			return err
+		}
+
+		progress.RepositoryDone(repo.GetFullName())
+	}
+
+	progress.End()
+
+	logger.Debug("gathering events")
+	events, err := client.StreamEventsForOrganizations(ctx, orgs)
+	if err != nil {
+		return err
+	}
+
+	err = r.RecordEvents(events)
+	if err != nil {
+		return err
+	}
+
+	for _, org := range orgs {
+		logger.Debug("gathering members", lager.Data{"organization": org})
+
+		members, err := client.OrganizationMembers(ctx, org)
+		if err != nil {
+			return err
+		}
+
+		err = r.RecordMembers(org, members)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fetchRepository(ctx context.Context, client gh.Fetcher, repo *github.Repository) (Activity, error) {
+	var activity Activity
+	var err error
+
+	activity.Issues, err = client.AllIssuesIncludingClosed(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.IssueComments, err = client.AllIssueCommentsForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.RepositoryComments, err = client.AllCommentsForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.IssueEvents, err = client.AllIssueEventsForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.Reviews, err = client.AllReviewsForPullRequests(ctx, repo, activity.Issues)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.Stargazers, err = client.AllStargazersForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.Watchers, err = client.AllWatchersForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.Forks, err = client.AllForksForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.Releases, err = client.AllReleasesForRepository(ctx, repo)
+	if err != nil {
+		return activity, err
+	}
+
+	traffic, err := client.TrafficForRepository(ctx, repo)
+	if traffic != nil {
+		activity.Traffic = gh.TrafficHistory{traffic}
+	}
+
+	return activity, err
+}
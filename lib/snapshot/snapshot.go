@@ -0,0 +1,302 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+// Version is the snapshot format written by this version of pm. Snapshots
+// from older versions are refused rather than read as missing what was added
+// since, as are snapshots from newer versions. It is bumped whenever the
+// format changes:
+//
+//	1: repositories, issues, comments, issue events, reviews and members
+//	2: event streams
+//	3: stargazers and watchers
+//	4: forks
+//	5: traffic
+//	6: releases
+const Version = 6
+
+// Snapshot is everything crawled from one or more organizations, so reports
+// can be run again later without a token or network access.
+type Snapshot struct {
+	Version       int       `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Organizations []string  `json:"organizations"`
+
+	Repositories       []*github.Repository        `json:"repositories"`
+	Issues             []*github.Issue             `json:"issues"`
+	IssueComments      []*github.IssueComment      `json:"issue_comments"`
+	RepositoryComments []*github.RepositoryComment `json:"repository_comments"`
+	IssueEvents        []*github.IssueEvent        `json:"issue_events"`
+	Reviews            []*github.PullRequestReview `json:"reviews"`
+
+	// Stargazers and Watchers map each repository's full name to the users
+	// starring and watching it.
+	Stargazers map[string][]*github.Stargazer `json:"stargazers,omitempty"`
+	Watchers   map[string][]*github.User      `json:"watchers,omitempty"`
+
+	// Forks maps each repository's full name to its forks.
+	Forks map[string][]*gh.Fork `json:"forks,omitempty"`
+
+	// Releases maps each repository's full name to its published releases.
+	Releases map[string][]*gh.Release `json:"releases,omitempty"`
+
+	// Traffic maps the full name of each repository whose traffic was
+	// crawled to its traffic.
+	Traffic map[string]gh.TrafficHistory `json:"traffic,omitempty"`
+
+	// Events are the organizations' event streams, oldest first.
+	Events []*github.Event `json:"events,omitempty"`
+
+	// Members maps each organization to its members.
+	Members map[string][]*github.User `json:"members"`
+}
+
+var _ Recorder = &Snapshot{}
+
+// New returns an empty snapshot of the given organizations.
+func New(orgs []string, now time.Time) *Snapshot {
+	return &Snapshot{
+		Version:       Version,
+		CreatedAt:     now,
+		Organizations: orgs,
+		Stargazers:    map[string][]*github.Stargazer{},
+		Watchers:      map[string][]*github.User{},
+		Forks:         map[string][]*gh.Fork{},
+		Releases:      map[string][]*gh.Release{},
+		Traffic:       map[string]gh.TrafficHistory{},
+		Members:       map[string][]*github.User{},
+	}
+}
+
+func (s *Snapshot) RecordRepository(repo *github.Repository, activity Activity) error {
+	s.Repositories = append(s.Repositories, repo)
+	s.Issues = append(s.Issues, activity.Issues...)
+	s.IssueComments = append(s.IssueComments, activity.IssueComments...)
+	s.RepositoryComments = append(s.RepositoryComments, activity.RepositoryComments...)
+	s.IssueEvents = append(s.IssueEvents, activity.IssueEvents...)
+	s.Reviews = append(s.Reviews, activity.Reviews...)
+	s.Stargazers[repo.GetFullName()] = activity.Stargazers
+	s.Watchers[repo.GetFullName()] = activity.Watchers
+	s.Forks[repo.GetFullName()] = activity.Forks
+	s.Releases[repo.GetFullName()] = activity.Releases
+
+	if len(activity.Traffic) > 0 {
+		s.Traffic[repo.GetFullName()] = activity.Traffic
+	}
+
+	return nil
+}
+
+func (s *Snapshot) RecordMembers(org string, members []*github.User) error {
+	s.Members[org] = members
+
+	return nil
+}
+
+// RecordEvents adds the events the snapshot does not hold yet.
+func (s *Snapshot) RecordEvents(events []*github.Event) error {
+	seen := map[string]bool{}
+	for _, e := range s.Events {
+		seen[e.GetID()] = true
+	}
+
+	for _, e := range events {
+		if !seen[e.GetID()] {
+			seen[e.GetID()] = true
+			s.Events = append(s.Events, e)
+		}
+	}
+
+	gh.SortEvents(s.Events)
+
+	return nil
+}
+
+// Read decodes a gzip-compressed snapshot.
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var s Snapshot
+	err = json.NewDecoder(gz).Decode(&s)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Version < Version {
+		return nil, fmt.Errorf("snapshot version %d is older than this pm reads (%d); run pm fetch again to replace it", s.Version, Version)
+	}
+
+	if s.Version > Version {
+		return nil, fmt.Errorf("snapshot version %d is newer than this pm reads (%d); upgrade pm", s.Version, Version)
+	}
+
+	return &s, nil
+}
+
+// ReadFile reads the snapshot stored at path.
+func ReadFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %s", path, err)
+	}
+
+	return s, nil
+}
+
+// Write encodes the snapshot as gzip-compressed JSON.
+func (s *Snapshot) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+
+	err := json.NewEncoder(gz).Encode(s)
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// WriteFile writes the snapshot to a temporary file next to path and then
+// moves it into place, so an interrupted run never leaves a truncated
+// snapshot behind.
+func (s *Snapshot) WriteFile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+
+	err = s.Write(tmp)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Replay hands r the activity of every repository in the snapshot that
+// belongs to one of the organizations and passes the filter, followed by
+// their events and the organizations' members, as Fetch would have.
+func (s *Snapshot) Replay(orgs []string, filter gh.RepositoryFilter, r Recorder) error {
+	activity := map[string]*Activity{}
+	for _, repo := range s.Repositories {
+		if owned(repo, orgs) && filter.Allows(repo) {
+			activity[repo.GetFullName()] = &Activity{}
+		}
+	}
+
+	for _, i := range s.Issues {
+		if a, found := activity[gh.IssueRepository(i)]; found {
+			a.Issues = append(a.Issues, i)
+		}
+	}
+
+	for _, c := range s.IssueComments {
+		if a, found := activity[gh.RepositoryFullName(c.GetURL())]; found {
+			a.IssueComments = append(a.IssueComments, c)
+		}
+	}
+
+	for _, c := range s.RepositoryComments {
+		if a, found := activity[gh.RepositoryFullName(c.GetURL())]; found {
+			a.RepositoryComments = append(a.RepositoryComments, c)
+		}
+	}
+
+	for _, e := range s.IssueEvents {
+		if a, found := activity[gh.RepositoryFullName(e.GetURL())]; found {
+			a.IssueEvents = append(a.IssueEvents, e)
+		}
+	}
+
+	for _, review := range s.Reviews {
+		if a, found := activity[gh.RepositoryFullName(review.GetPullRequestURL())]; found {
+			a.Reviews = append(a.Reviews, review)
+		}
+	}
+
+	for _, repo := range s.Repositories {
+		a, found := activity[repo.GetFullName()]
+		if !found {
+			continue
+		}
+
+		a.Stargazers = s.Stargazers[repo.GetFullName()]
+		a.Watchers = s.Watchers[repo.GetFullName()]
+		a.Forks = s.Forks[repo.GetFullName()]
+		a.Releases = s.Releases[repo.GetFullName()]
+		a.Traffic = s.Traffic[repo.GetFullName()]
+
+		err := r.RecordRepository(repo, *a)
+		if err != nil {
+			return err
+		}
+	}
+
+	var events []*github.Event
+	for _, e := range s.Events {
+		if _, found := activity[e.Repo.GetName()]; found {
+			events = append(events, e)
+		}
+	}
+
+	err := r.RecordEvents(events)
+	if err != nil {
+		return err
+	}
+
+	for _, org := range orgs {
+		err := r.RecordMembers(org, s.Members[org])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func owned(repo *github.Repository, orgs []string) bool {
+	for _, org := range orgs {
+		if strings.EqualFold(repo.Owner.GetLogin(), org) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// encoded gzips a snapshot's JSON the way Write does.
+func encoded(payload string) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(payload))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(gz.Close()).To(Succeed())
+
+	return &buf
+}
+
+var _ = Describe("Snapshot", func() {
+	var created time.Time
+
+	BeforeEach(func() {
+		created = time.Date(2017, time.July, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	It("reads back what it writes", func() {
+		s := snapshot.New([]string{"acme"}, created)
+
+		repo := &github.Repository{FullName: github.String("acme/widget")}
+		Expect(s.RecordRepository(repo, snapshot.Activity{
+			Issues:     []*github.Issue{{ID: github.Int(1001)}},
+			Stargazers: []*github.Stargazer{{User: &github.User{Login: github.String("erin")}}},
+		})).To(Succeed())
+		Expect(s.RecordEvents([]*github.Event{{ID: github.String("7001")}})).To(Succeed())
+
+		var buf bytes.Buffer
+		Expect(s.Write(&buf)).To(Succeed())
+
+		read, err := snapshot.Read(&buf)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(read.Version).To(Equal(snapshot.Version))
+		Expect(read.CreatedAt).To(Equal(created))
+		Expect(read.Organizations).To(Equal([]string{"acme"}))
+		Expect(read.Issues).To(HaveLen(1))
+		Expect(read.Stargazers["acme/widget"]).To(HaveLen(1))
+		Expect(read.Events).To(HaveLen(1))
+	})
+
+	It("refuses snapshots older than it reads", func() {
+		_, err := snapshot.Read(encoded(fmt.Sprintf(`{"version": %d, "organizations": ["acme"]}`, snapshot.Version-1)))
+		Expect(err).To(MatchError(fmt.Sprintf("snapshot version %d is older than this pm reads (%d); run pm fetch again to replace it", snapshot.Version-1, snapshot.Version)))
+	})
+
+	It("refuses snapshots newer than it reads", func() {
+		_, err := snapshot.Read(encoded(fmt.Sprintf(`{"version": %d}`, snapshot.Version+1)))
+		Expect(err).To(MatchError(fmt.Sprintf("snapshot version %d is newer than this pm reads (%d); upgrade pm", snapshot.Version+1, snapshot.Version)))
+	})
+
+	It("names the file it could not read", func() {
+		dir, err := ioutil.TempDir("", "pm-snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "old.json.gz")
+		Expect(ioutil.WriteFile(path, encoded(`{"version": 1}`).Bytes(), 0644)).To(Succeed())
+
+		_, err = snapshot.ReadFile(path)
+		Expect(err).To(MatchError(HavePrefix("reading snapshot " + path + ": snapshot version 1 is older than this pm reads")))
+	})
+
+	It("writes a file that reads back", func() {
+		dir, err := ioutil.TempDir("", "pm-snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "acme.json.gz")
+		Expect(snapshot.New([]string{"acme"}, created).WriteFile(path)).To(Succeed())
+
+		read, err := snapshot.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read.Organizations).To(Equal([]string{"acme"}))
+	})
+})
@@ -0,0 +1,362 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/chendrix/pm/lib/activity"
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/google/go-github/github"
+)
+
+// Crawler serves a store's activity through the same interface as
+// gh.Client, applying the same repository filter and user exclusions.
+type Crawler struct {
+	Store *Store
+
+	RepositoryFilter gh.RepositoryFilter
+	ExcludedUsers    map[string]bool
+}
+
+var _ gh.Crawler = &Crawler{}
+
+func NewCrawler(s *Store) *Crawler {
+	return &Crawler{
+		Store: s,
+	}
+}
+
+func (c *Crawler) AllIssuesForOrganizations(ctx context.Context, orgs []string) ([]*github.Issue, error) {
+	var all []*github.Issue
+
+	err := c.each(orgs, KindIssue, func(payload []byte) error {
+		var i github.Issue
+		err := json.Unmarshal(payload, &i)
+		if err != nil {
+			return err
+		}
+
+		if i.GetState() == "open" {
+			all = append(all, &i)
+		}
+
+		return nil
+	})
+
+	return all, err
+}
+
+func (c *Crawler) IssuesUpdatedSinceForOrganizations(ctx context.Context, orgs []string, since time.Time) ([]*github.Issue, error) {
+	var all []*github.Issue
+
+	err := c.each(orgs, KindIssue, func(payload []byte) error {
+		var i github.Issue
+		err := json.Unmarshal(payload, &i)
+		if err != nil {
+			return err
+		}
+
+		if !i.GetUpdatedAt().Before(since) {
+			all = append(all, &i)
+		}
+
+		return nil
+	})
+
+	return all, err
+}
+
+func (c *Crawler) AllIssueCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueComment, error) {
+	var all []*github.IssueComment
+
+	err := c.each(orgs, KindIssueComment, func(payload []byte) error {
+		var comment github.IssueComment
+		err := json.Unmarshal(payload, &comment)
+		if err != nil {
+			return err
+		}
+
+		all = append(all, &comment)
+		return nil
+	})
+
+	return all, err
+}
+
+func (c *Crawler) AllRepositoryCommentsForOrganizations(ctx context.Context, orgs []string) ([]*github.RepositoryComment, error) {
+	var all []*github.RepositoryComment
+
+	err := c.each(orgs, KindRepositoryComment, func(payload []byte) error {
+		var comment github.RepositoryComment
+		err := json.Unmarshal(payload, &comment)
+		if err != nil {
+			return err
+		}
+
+		all = append(all, &comment)
+		return nil
+	})
+
+	return all, err
+}
+
+func (c *Crawler) AllIssueEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.IssueEvent, error) {
+	var all []*github.IssueEvent
+
+	err := c.each(orgs, KindIssueEvent, func(payload []byte) error {
+		var event github.IssueEvent
+		err := json.Unmarshal(payload, &event)
+		if err != nil {
+			return err
+		}
+
+		all = append(all, &event)
+		return nil
+	})
+
+	return all, err
+}
+
+func (c *Crawler) AllReviewsForIssues(ctx context.Context, issues []*github.Issue) ([]*github.PullRequestReview, error) {
+	repos, _ := gh.GroupIssuesByRepository(issues)
+	pullRequests := gh.PullRequestsAmong(issues)
+
+	var all []*github.PullRequestReview
+	for _, repo := range repos {
+		err := c.Store.Each(Query{Kind: KindReview, Repository: repo.GetFullName()}, func(e Entry, payload []byte) error {
+			if c.ExcludedUsers[e.User] {
+				return nil
+			}
+
+			var review github.PullRequestReview
+			err := json.Unmarshal(payload, &review)
+			if err != nil {
+				return err
+			}
+
+			if pullRequests[gh.ReviewedPullRequest(&review)] {
+				all = append(all, &review)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) StreamEventsForOrganizations(ctx context.Context, orgs []string) ([]*github.Event, error) {
+	var all []*github.Event
+
+	err := c.each(orgs, KindEvent, func(payload []byte) error {
+		var event github.Event
+		err := json.Unmarshal(payload, &event)
+		if err != nil {
+			return err
+		}
+
+		all = append(all, &event)
+		return nil
+	})
+
+	gh.SortEvents(all)
+
+	return all, err
+}
+
+func (c *Crawler) StargazersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.Stargazer, error) {
+	repos, err := c.repositories(orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string][]*github.Stargazer{}
+	for _, repo := range repos {
+		stargazers, err := c.Store.Stargazers(repo.GetFullName())
+		if err != nil {
+			return nil, err
+		}
+
+		var kept []*github.Stargazer
+		for _, stargazer := range stargazers {
+			if !c.ExcludedUsers[stargazer.User.GetLogin()] {
+				kept = append(kept, stargazer)
+			}
+		}
+
+		all[repo.GetFullName()] = kept
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) WatchersForOrganizations(ctx context.Context, orgs []string) (map[string][]*github.User, error) {
+	repos, err := c.repositories(orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string][]*github.User{}
+	for _, repo := range repos {
+		watchers, err := c.Store.Watchers(repo.GetFullName())
+		if err != nil {
+			return nil, err
+		}
+
+		var kept []*github.User
+		for _, user := range watchers {
+			if !c.ExcludedUsers[user.GetLogin()] {
+				kept = append(kept, user)
+			}
+		}
+
+		all[repo.GetFullName()] = kept
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) ForksForOrganizations(ctx context.Context, orgs []string) (map[string][]*gh.Fork, error) {
+	repos, err := c.repositories(orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string][]*gh.Fork{}
+	for _, repo := range repos {
+		forks, err := c.Store.Forks(repo.GetFullName())
+		if err != nil {
+			return nil, err
+		}
+
+		var kept []*gh.Fork
+		for _, fork := range forks {
+			if !c.ExcludedUsers[fork.Repository.Owner.GetLogin()] {
+				kept = append(kept, fork)
+			}
+		}
+
+		all[repo.GetFullName()] = kept
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) ReleasesForOrganizations(ctx context.Context, orgs []string) (map[string][]*gh.Release, error) {
+	repos, err := c.repositories(orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string][]*gh.Release{}
+	for _, repo := range repos {
+		all[repo.GetFullName()], err = c.Store.Releases(repo.GetFullName())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return all, nil
+}
+
+func (c *Crawler) TrafficForOrganizations(ctx context.Context, orgs []string) (map[string]gh.TrafficHistory, error) {
+	repos, err := c.repositories(orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string]gh.TrafficHistory{}
+	for _, repo := range repos {
+		traffic, err := c.Store.Traffic(repo.GetFullName())
+		if err != nil {
+			return nil, err
+		}
+
+		if len(traffic) > 0 {
+			all[repo.GetFullName()] = traffic
+		}
+	}
+
+	return all, nil
+}
+
+// Events returns the organizations' activity.
+func (c *Crawler) Events(ctx context.Context, orgs []string) ([]activity.Event, error) {
+	return gh.CrawlEvents(ctx, c, orgs)
+}
+
+func (c *Crawler) AllMembersForOrganizations(ctx context.Context, orgs []string) ([]*github.User, error) {
+	var all []*github.User
+
+	for _, org := range orgs {
+		members, err := c.Store.Members(org)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, members...)
+	}
+
+	return all, nil
+}
+
+// each calls fn with every activity of the given kind in the organizations'
+// repositories that pass the repository filter, skipping excluded users.
+func (c *Crawler) each(orgs []string, kind string, fn func([]byte) error) error {
+	return c.Each(orgs, Query{Kind: kind}, func(e Entry, payload []byte) error {
+		return fn(payload)
+	})
+}
+
+// Each calls fn with every entry matching q in the organizations'
+// repositories that pass the repository filter, skipping excluded users.
+func (c *Crawler) Each(orgs []string, q Query, fn func(Entry, []byte) error) error {
+	repos, err := c.repositories(orgs)
+	if err != nil {
+		return err
+	}
+
+	for _, repo := range repos {
+		if q.Repository != "" && q.Repository != repo.GetFullName() {
+			continue
+		}
+
+		repoQuery := q
+		repoQuery.Repository = repo.GetFullName()
+
+		err := c.Store.Each(repoQuery, func(e Entry, payload []byte) error {
+			if c.ExcludedUsers[e.User] {
+				return nil
+			}
+
+			return fn(e, payload)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repositories returns the stored repositories that belong to one of the
+// organizations and pass the repository filter.
+func (c *Crawler) repositories(orgs []string) ([]*github.Repository, error) {
+	repos, err := c.Store.Repositories()
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []*github.Repository
+	for _, repo := range repos {
+		if owned(repo, orgs) && c.RepositoryFilter.Allows(repo) {
+			allowed = append(allowed, repo)
+		}
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,81 @@
+package store_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Crawler", func() {
+	var (
+		dir     string
+		s       *store.Store
+		crawler *store.Crawler
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pm-store-crawler")
+		Expect(err).NotTo(HaveOccurred())
+
+		s, err = store.Open(filepath.Join(dir, "pm.db"))
+		Expect(err).NotTo(HaveOccurred())
+
+		closed := issue(1002, "bob", t0.Add(24*time.Hour))
+		closed.State = github.String("closed")
+
+		Expect(s.RecordRepository(repository("acme", "widget"), snapshot.Activity{
+			Issues: []*github.Issue{issue(1001, "alice", t0), closed, issue(1003, "ci-bot", t0)},
+		})).To(Succeed())
+		Expect(s.RecordRepository(repository("acme", "widget-ci"), snapshot.Activity{
+			Issues: []*github.Issue{issue(1101, "alice", t0)},
+		})).To(Succeed())
+		Expect(s.RecordRepository(repository("umbrella", "tool"), snapshot.Activity{
+			Issues: []*github.Issue{issue(1201, "alice", t0)},
+		})).To(Succeed())
+
+		crawler = store.NewCrawler(s)
+		crawler.RepositoryFilter = gh.RepositoryFilter{Exclude: []string{"*-ci"}}
+		crawler.ExcludedUsers = map[string]bool{"ci-bot": true}
+	})
+
+	AfterEach(func() {
+		s.Close()
+		os.RemoveAll(dir)
+	})
+
+	numbers := func(issues []*github.Issue) []int {
+		var all []int
+		for _, i := range issues {
+			all = append(all, i.GetNumber())
+		}
+
+		return all
+	}
+
+	It("lists the open issues of the organizations' repositories that pass the filter, without excluded users", func() {
+		issues, err := crawler.AllIssuesForOrganizations(context.Background(), []string{"acme"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(numbers(issues)).To(Equal([]int{1001}))
+	})
+
+	It("lists open and closed issues updated since a time", func() {
+		issues, err := crawler.IssuesUpdatedSinceForOrganizations(context.Background(), []string{"acme"}, time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(numbers(issues)).To(Equal([]int{1001, 1002}))
+
+		issues, err = crawler.IssuesUpdatedSinceForOrganizations(context.Background(), []string{"acme"}, t0.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(numbers(issues)).To(Equal([]int{1002}))
+	})
+})
@@ -0,0 +1,693 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/google/go-github/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Kinds of activity kept in the store. Each kind is stored in the bucket of
+// the same name, keyed by its GitHub ID.
+const (
+	KindIssue             = "issues"
+	KindIssueComment      = "issue-comments"
+	KindRepositoryComment = "repository-comments"
+	KindIssueEvent        = "issue-events"
+	KindReview            = "reviews"
+	KindEvent             = "events"
+)
+
+var Kinds = []string{
+	KindIssue,
+	KindIssueComment,
+	KindRepositoryComment,
+	KindIssueEvent,
+	KindReview,
+	KindEvent,
+}
+
+var (
+	repositoriesBucket = []byte("repositories")
+	usersBucket        = []byte("users")
+	membersBucket      = []byte("members")
+	stargazersBucket   = []byte("stargazers")
+	watchersBucket     = []byte("watchers")
+	forksBucket        = []byte("forks")
+	trafficBucket      = []byte("traffic")
+	releasesBucket     = []byte("releases")
+
+	byUserBucket       = []byte("by-user")
+	byRepositoryBucket = []byte("by-repository")
+	byTimeBucket       = []byte("by-time")
+)
+
+// Store is a local database of crawled activity. Records are replaced by ID
+// whenever they are crawled again, so history accumulates across runs, and
+// every activity is indexed by user, by repository and by time.
+type Store struct {
+	db *bolt.DB
+}
+
+var _ snapshot.Recorder = &Store{}
+
+// Entry is an index entry describing one stored activity.
+type Entry struct {
+	Kind       string    `json:"kind"`
+	ID         int       `json:"id"`
+	Repository string    `json:"repository"`
+	User       string    `json:"user,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Query selects index entries. Empty fields match everything; Until is
+// exclusive.
+type Query struct {
+	Kind       string
+	Repository string
+	User       string
+	Since      time.Time
+	Until      time.Time
+}
+
+// Open opens the store at path, creating it if it does not exist.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening store %s: %s", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := [][]byte{
+			repositoriesBucket,
+			usersBucket,
+			membersBucket,
+			stargazersBucket,
+			watchersBucket,
+			forksBucket,
+			trafficBucket,
+			releasesBucket,
+			byUserBucket,
+			byRepositoryBucket,
+			byTimeBucket,
+		}
+
+		for _, kind := range Kinds {
+			buckets = append(buckets, []byte(kind))
+		}
+
+		for _, name := range buckets {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordRepository stores a repository and its activity in a single
+// transaction. Stargazers, watchers and forks replace the ones stored
+// before, as users can unstar, stop watching and delete their forks, while
+// traffic is kept as RecordTraffic does. Releases replace the ones stored
+// before too, each remembering its download count as stored before.
+func (s *Store) RecordRepository(repo *github.Repository, activity snapshot.Activity) error {
+	name := repo.GetFullName()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := put(tx.Bucket(repositoriesBucket), []byte(name), repo)
+		if err != nil {
+			return err
+		}
+
+		err = put(tx.Bucket(stargazersBucket), []byte(name), activity.Stargazers)
+		if err != nil {
+			return err
+		}
+
+		err = put(tx.Bucket(watchersBucket), []byte(name), activity.Watchers)
+		if err != nil {
+			return err
+		}
+
+		err = put(tx.Bucket(forksBucket), []byte(name), activity.Forks)
+		if err != nil {
+			return err
+		}
+
+		err = recordReleases(tx, name, activity.Releases)
+		if err != nil {
+			return err
+		}
+
+		for _, traffic := range activity.Traffic {
+			err := put(tx.Bucket(trafficBucket), trafficKey(name, traffic), traffic)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, i := range activity.Issues {
+			err := record(tx, Entry{Kind: KindIssue, ID: i.GetID(), Repository: name, CreatedAt: i.GetCreatedAt()}, i.User, i)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, c := range activity.IssueComments {
+			err := record(tx, Entry{Kind: KindIssueComment, ID: c.GetID(), Repository: name, CreatedAt: c.GetCreatedAt()}, c.User, c)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, c := range activity.RepositoryComments {
+			err := record(tx, Entry{Kind: KindRepositoryComment, ID: c.GetID(), Repository: name, CreatedAt: c.GetCreatedAt()}, c.User, c)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, e := range activity.IssueEvents {
+			err := record(tx, Entry{Kind: KindIssueEvent, ID: e.GetID(), Repository: name, CreatedAt: e.GetCreatedAt()}, e.Actor, e)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, r := range activity.Reviews {
+			err := record(tx, Entry{Kind: KindReview, ID: r.GetID(), Repository: name, CreatedAt: r.GetSubmittedAt()}, r.User, r)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RecordEvents stores events from the organizations' event streams. Events
+// are keyed by ID, so reading overlapping windows of a stream stores each
+// event once.
+func (s *Store) RecordEvents(events []*github.Event) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range events {
+			// Event actors carry only a few fields, so they are indexed
+			// without replacing the stored copy of the user.
+			entry := Entry{Kind: KindEvent, ID: gh.EventID(e), Repository: e.Repo.GetName(), User: e.Actor.GetLogin(), CreatedAt: e.GetCreatedAt()}
+
+			err := record(tx, entry, nil, e)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RecordMembers replaces the members stored for an organization.
+func (s *Store) RecordMembers(org string, members []*github.User) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(membersBucket), []byte(org), members)
+	})
+}
+
+// Repositories returns every stored repository.
+func (s *Store) Repositories() ([]*github.Repository, error) {
+	var all []*github.Repository
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(repositoriesBucket).ForEach(func(k, v []byte) error {
+			var repo github.Repository
+			err := json.Unmarshal(v, &repo)
+			if err != nil {
+				return err
+			}
+
+			all = append(all, &repo)
+			return nil
+		})
+	})
+
+	return all, err
+}
+
+// Members returns the stored members of an organization.
+func (s *Store) Members(org string) ([]*github.User, error) {
+	var members []*github.User
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(membersBucket).Get([]byte(org))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &members)
+	})
+
+	return members, err
+}
+
+// Stargazers returns the stored stargazers of a repository.
+func (s *Store) Stargazers(repo string) ([]*github.Stargazer, error) {
+	var stargazers []*github.Stargazer
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(stargazersBucket).Get([]byte(repo))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &stargazers)
+	})
+
+	return stargazers, err
+}
+
+// Watchers returns the stored watchers of a repository.
+func (s *Store) Watchers(repo string) ([]*github.User, error) {
+	var watchers []*github.User
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(watchersBucket).Get([]byte(repo))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &watchers)
+	})
+
+	return watchers, err
+}
+
+// Forks returns the stored forks of a repository.
+func (s *Store) Forks(repo string) ([]*gh.Fork, error) {
+	var forks []*gh.Fork
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(forksBucket).Get([]byte(repo))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &forks)
+	})
+
+	return forks, err
+}
+
+func recordReleases(tx *bolt.Tx, repo string, releases []*gh.Release) error {
+	b := tx.Bucket(releasesBucket)
+
+	if v := b.Get([]byte(repo)); v != nil {
+		var stored []*gh.Release
+		err := json.Unmarshal(v, &stored)
+		if err != nil {
+			return err
+		}
+
+		earlier := map[int]*gh.Release{}
+		for _, release := range stored {
+			earlier[release.Release.GetID()] = release
+		}
+
+		for _, release := range releases {
+			if e, found := earlier[release.Release.GetID()]; found {
+				release.Follow(e)
+			}
+		}
+	}
+
+	return put(b, []byte(repo), releases)
+}
+
+// Releases returns the stored releases of a repository, oldest first.
+func (s *Store) Releases(repo string) ([]*gh.Release, error) {
+	var releases []*gh.Release
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(releasesBucket).Get([]byte(repo))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &releases)
+	})
+
+	return releases, err
+}
+
+// RecordTraffic stores a repository and a crawl of its traffic. Crawls are
+// kept one per day, a later crawl replacing an earlier one of the same day,
+// so running pm repeatedly builds up a history longer than the 14 days
+// GitHub keeps.
+func (s *Store) RecordTraffic(repo *github.Repository, traffic *gh.Traffic) error {
+	name := repo.GetFullName()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := put(tx.Bucket(repositoriesBucket), []byte(name), repo)
+		if err != nil {
+			return err
+		}
+
+		return put(tx.Bucket(trafficBucket), trafficKey(name, traffic), traffic)
+	})
+}
+
+// Traffic returns the stored crawls of a repository's traffic, oldest first.
+func (s *Store) Traffic(repo string) (gh.TrafficHistory, error) {
+	var history gh.TrafficHistory
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := field(repo)
+
+		c := tx.Bucket(trafficBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var traffic gh.Traffic
+			err := json.Unmarshal(v, &traffic)
+			if err != nil {
+				return err
+			}
+
+			history = append(history, &traffic)
+		}
+
+		return nil
+	})
+
+	return history, err
+}
+
+// User returns the most recently stored copy of a user, or nil.
+func (s *Store) User(login string) (*github.User, error) {
+	var user *github.User
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(usersBucket).Get([]byte(login))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &user)
+	})
+
+	return user, err
+}
+
+// Entries returns the index entries matching q, oldest first within each
+// kind.
+func (s *Store) Entries(q Query) ([]Entry, error) {
+	var entries []Entry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return scan(tx, q, func(e Entry) error {
+			entries = append(entries, e)
+			return nil
+		})
+	})
+
+	return entries, err
+}
+
+// Each calls fn with every entry matching q and the JSON of the GitHub
+// resource it describes, oldest first within each kind.
+func (s *Store) Each(q Query, fn func(Entry, []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return scan(tx, q, func(e Entry) error {
+			v := tx.Bucket([]byte(e.Kind)).Get(idKey(e.ID))
+			if v == nil {
+				return fmt.Errorf("store is missing %s %d", e.Kind, e.ID)
+			}
+
+			return fn(e, v)
+		})
+	})
+}
+
+// scan walks the narrowest index for q: by user if one is given, otherwise
+// by repository, otherwise by time.
+func scan(tx *bolt.Tx, q Query, fn func(Entry) error) error {
+	bucket := byTimeBucket
+	var prefix []byte
+
+	switch {
+	case q.User != "":
+		bucket = byUserBucket
+		prefix = field(q.User)
+	case q.Repository != "":
+		bucket = byRepositoryBucket
+		prefix = field(q.Repository)
+	}
+
+	kinds := Kinds
+	if q.Kind != "" {
+		kinds = []string{q.Kind}
+	}
+
+	c := tx.Bucket(bucket).Cursor()
+
+	for _, kind := range kinds {
+		kindPrefix := append(append([]byte{}, prefix...), field(kind)...)
+
+		var until []byte
+		if !q.Until.IsZero() {
+			until = append(append([]byte{}, kindPrefix...), timeKey(q.Until)...)
+		}
+
+		for k, v := c.Seek(append(append([]byte{}, kindPrefix...), timeKey(q.Since)...)); k != nil && bytes.HasPrefix(k, kindPrefix); k, v = c.Next() {
+			if until != nil && bytes.Compare(k, until) >= 0 {
+				break
+			}
+
+			var e Entry
+			err := json.Unmarshal(v, &e)
+			if err != nil {
+				return err
+			}
+
+			if q.Repository != "" && e.Repository != q.Repository {
+				continue
+			}
+
+			err = fn(e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// record stores an activity and indexes it. Index keys are derived from
+// fields that never change once created, so storing the same activity again
+// overwrites its entries rather than leaving stale ones behind. Activity that
+// has not happened yet, such as a pending review, is left out until it has,
+// as it has no time to be indexed by.
+func record(tx *bolt.Tx, e Entry, user *github.User, v interface{}) error {
+	if e.CreatedAt.IsZero() {
+		return nil
+	}
+
+	if user != nil && user.GetLogin() != "" {
+		e.User = user.GetLogin()
+
+		err := put(tx.Bucket(usersBucket), []byte(e.User), user)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := put(tx.Bucket([]byte(e.Kind)), idKey(e.ID), v)
+	if err != nil {
+		return err
+	}
+
+	suffix := append(append(field(e.Kind), timeKey(e.CreatedAt)...), idKey(e.ID)...)
+
+	keys := map[string][]byte{
+		string(byTimeBucket):       suffix,
+		string(byRepositoryBucket): append(field(e.Repository), suffix...),
+	}
+
+	if e.User != "" {
+		keys[string(byUserBucket)] = append(field(e.User), suffix...)
+	}
+
+	for bucket, key := range keys {
+		err := put(tx.Bucket([]byte(bucket)), key, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func put(b *bolt.Bucket, key []byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, payload)
+}
+
+// field encodes a string index component, terminated so that one value is
+// never a prefix of another.
+func field(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func trafficKey(repo string, traffic *gh.Traffic) []byte {
+	return append(field(repo), traffic.CollectedAt.UTC().Format("2006-01-02")...)
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	if !t.IsZero() {
+		binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	}
+
+	return key
+}
+
+func idKey(id int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+// Replay hands r the stored activity of every repository that belongs to
+// one of the organizations and passes the filter, followed by their events
+// and the organizations' members, as snapshot.Fetch would have.
+func (s *Store) Replay(orgs []string, filter gh.RepositoryFilter, r snapshot.Recorder) error {
+	repos, err := s.Repositories()
+	if err != nil {
+		return err
+	}
+
+	var events []*github.Event
+
+	for _, repo := range repos {
+		if !owned(repo, orgs) || !filter.Allows(repo) {
+			continue
+		}
+
+		var activity snapshot.Activity
+
+		activity.Stargazers, err = s.Stargazers(repo.GetFullName())
+		if err != nil {
+			return err
+		}
+
+		activity.Watchers, err = s.Watchers(repo.GetFullName())
+		if err != nil {
+			return err
+		}
+
+		activity.Forks, err = s.Forks(repo.GetFullName())
+		if err != nil {
+			return err
+		}
+
+		activity.Releases, err = s.Releases(repo.GetFullName())
+		if err != nil {
+			return err
+		}
+
+		activity.Traffic, err = s.Traffic(repo.GetFullName())
+		if err != nil {
+			return err
+		}
+
+		err = s.Each(Query{Repository: repo.GetFullName()}, func(e Entry, payload []byte) error {
+			switch e.Kind {
+			case KindIssue:
+				var i github.Issue
+				activity.Issues = append(activity.Issues, &i)
+				return json.Unmarshal(payload, &i)
+			case KindIssueComment:
+				var c github.IssueComment
+				activity.IssueComments = append(activity.IssueComments, &c)
+				return json.Unmarshal(payload, &c)
+			case KindRepositoryComment:
+				var c github.RepositoryComment
+				activity.RepositoryComments = append(activity.RepositoryComments, &c)
+				return json.Unmarshal(payload, &c)
+			case KindIssueEvent:
+				var event github.IssueEvent
+				activity.IssueEvents = append(activity.IssueEvents, &event)
+				return json.Unmarshal(payload, &event)
+			case KindReview:
+				var review github.PullRequestReview
+				activity.Reviews = append(activity.Reviews, &review)
+				return json.Unmarshal(payload, &review)
+			case KindEvent:
+				var event github.Event
+				events = append(events, &event)
+				return json.Unmarshal(payload, &event)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = r.RecordRepository(repo, activity)
+		if err != nil {
+			return err
+		}
+	}
+
+	gh.SortEvents(events)
+
+	err = r.RecordEvents(events)
+	if err != nil {
+		return err
+	}
+
+	for _, org := range orgs {
+		members, err := s.Members(org)
+		if err != nil {
+			return err
+		}
+
+		err = r.RecordMembers(org, members)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func owned(repo *github.Repository, orgs []string) bool {
+	for _, org := range orgs {
+		if strings.EqualFold(repo.Owner.GetLogin(), org) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,427 @@
+package store_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chendrix/pm/lib/gh"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/store"
+	"github.com/google/go-github/github"
+	bolt "go.etcd.io/bbolt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// t0 is when the first issue in the store was opened.
+var t0 = time.Date(2017, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+func user(login string) *github.User {
+	return &github.User{Login: github.String(login)}
+}
+
+func repository(owner, name string) *github.Repository {
+	return &github.Repository{
+		FullName: github.String(owner + "/" + name),
+		Name:     github.String(name),
+		Owner:    user(owner),
+	}
+}
+
+func issue(id int, author string, at time.Time) *github.Issue {
+	return &github.Issue{ID: github.Int(id), Number: github.Int(id), User: user(author), State: github.String("open"), CreatedAt: &at, UpdatedAt: &at}
+}
+
+func push(id string, actor string, repo string, at time.Time) *github.Event {
+	return &github.Event{ID: github.String(id), Type: github.String("PushEvent"), Actor: user(actor), Repo: &github.Repository{Name: github.String(repo)}, CreatedAt: &at}
+}
+
+// indexKey is how the store keys an activity in its by-user and
+// by-repository indexes, or in its by-time index when prefix is empty.
+func indexKey(prefix, kind string, at time.Time, id int) []byte {
+	var key []byte
+	if prefix != "" {
+		key = append([]byte(prefix), 0)
+	}
+
+	key = append(append(key, kind...), 0)
+
+	timestamp := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestamp, uint64(at.UnixNano()))
+
+	number := make([]byte, 8)
+	binary.BigEndian.PutUint64(number, uint64(id))
+
+	return append(append(key, timestamp...), number...)
+}
+
+var _ = Describe("Store", func() {
+	var (
+		dir    string
+		path   string
+		s      *store.Store
+		widget *github.Repository
+		gadget *github.Repository
+		tool   *github.Repository
+
+		widgetActivity snapshot.Activity
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pm-store")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(dir, "pm.db")
+
+		s, err = store.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		widget = repository("acme", "widget")
+		gadget = repository("acme", "gadget")
+		tool = repository("umbrella", "tool")
+
+		commented := t0.Add(2 * time.Hour)
+		labelled := t0.Add(24 * time.Hour)
+		reviewed := t0.Add(72 * time.Hour)
+
+		widgetActivity = snapshot.Activity{
+			Issues: []*github.Issue{
+				issue(1002, "bob", t0.Add(24*time.Hour)),
+				issue(1001, "alice", t0),
+			},
+			IssueComments: []*github.IssueComment{
+				{ID: github.Int(2001), User: user("bob"), CreatedAt: &commented},
+			},
+			IssueEvents: []*github.IssueEvent{
+				{ID: github.Int(5001), Actor: user("carol"), Event: github.String("labeled"), CreatedAt: &labelled},
+			},
+			Reviews: []*github.PullRequestReview{
+				{ID: github.Int(6001), User: user("alice"), State: github.String("APPROVED"), SubmittedAt: &reviewed},
+			},
+			Stargazers: []*github.Stargazer{{User: user("erin")}},
+			Watchers:   []*github.User{user("alice")},
+		}
+
+		Expect(s.RecordRepository(widget, widgetActivity)).To(Succeed())
+		Expect(s.RecordRepository(gadget, snapshot.Activity{Issues: []*github.Issue{issue(1101, "alice", t0.Add(48*time.Hour))}})).To(Succeed())
+		Expect(s.RecordRepository(tool, snapshot.Activity{Issues: []*github.Issue{issue(1201, "alice", t0)}})).To(Succeed())
+
+		Expect(s.RecordEvents([]*github.Event{push("7001", "dave", "acme/widget", t0.Add(96*time.Hour))})).To(Succeed())
+
+		Expect(s.RecordMembers("acme", []*github.User{user("alice"), user("bob")})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if s != nil {
+			s.Close()
+		}
+
+		os.RemoveAll(dir)
+	})
+
+	ids := func(q store.Query) []int {
+		entries, err := s.Entries(q)
+		Expect(err).NotTo(HaveOccurred())
+
+		var found []int
+		for _, e := range entries {
+			found = append(found, e.ID)
+		}
+
+		return found
+	}
+
+	// indexes closes the store and reads back the keys of each index.
+	indexes := func() map[string][]string {
+		Expect(s.Close()).To(Succeed())
+		s = nil
+
+		db, err := bolt.Open(path, 0644, &bolt.Options{ReadOnly: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+
+		keys := map[string][]string{}
+		err = db.View(func(tx *bolt.Tx) error {
+			for _, name := range []string{"by-user", "by-repository", "by-time"} {
+				err := tx.Bucket([]byte(name)).ForEach(func(k, v []byte) error {
+					keys[name] = append(keys[name], string(k))
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		return keys
+	}
+
+	It("indexes every activity by its user, its repository and when it happened", func() {
+		keys := indexes()
+
+		Expect(keys["by-user"]).To(ConsistOf(
+			string(indexKey("alice", store.KindIssue, t0, 1001)),
+			string(indexKey("bob", store.KindIssue, t0.Add(24*time.Hour), 1002)),
+			string(indexKey("alice", store.KindIssue, t0.Add(48*time.Hour), 1101)),
+			string(indexKey("alice", store.KindIssue, t0, 1201)),
+			string(indexKey("bob", store.KindIssueComment, t0.Add(2*time.Hour), 2001)),
+			string(indexKey("carol", store.KindIssueEvent, t0.Add(24*time.Hour), 5001)),
+			string(indexKey("alice", store.KindReview, t0.Add(72*time.Hour), 6001)),
+			string(indexKey("dave", store.KindEvent, t0.Add(96*time.Hour), 7001)),
+		))
+
+		Expect(keys["by-repository"]).To(ConsistOf(
+			string(indexKey("acme/widget", store.KindIssue, t0, 1001)),
+			string(indexKey("acme/widget", store.KindIssue, t0.Add(24*time.Hour), 1002)),
+			string(indexKey("acme/gadget", store.KindIssue, t0.Add(48*time.Hour), 1101)),
+			string(indexKey("umbrella/tool", store.KindIssue, t0, 1201)),
+			string(indexKey("acme/widget", store.KindIssueComment, t0.Add(2*time.Hour), 2001)),
+			string(indexKey("acme/widget", store.KindIssueEvent, t0.Add(24*time.Hour), 5001)),
+			string(indexKey("acme/widget", store.KindReview, t0.Add(72*time.Hour), 6001)),
+			string(indexKey("acme/widget", store.KindEvent, t0.Add(96*time.Hour), 7001)),
+		))
+
+		Expect(keys["by-time"]).To(ConsistOf(
+			string(indexKey("", store.KindIssue, t0, 1001)),
+			string(indexKey("", store.KindIssue, t0.Add(24*time.Hour), 1002)),
+			string(indexKey("", store.KindIssue, t0.Add(48*time.Hour), 1101)),
+			string(indexKey("", store.KindIssue, t0, 1201)),
+			string(indexKey("", store.KindIssueComment, t0.Add(2*time.Hour), 2001)),
+			string(indexKey("", store.KindIssueEvent, t0.Add(24*time.Hour), 5001)),
+			string(indexKey("", store.KindReview, t0.Add(72*time.Hour), 6001)),
+			string(indexKey("", store.KindEvent, t0.Add(96*time.Hour), 7001)),
+		))
+	})
+
+	It("replaces activity recorded again by ID without indexing it twice", func() {
+		widgetActivity.Issues[1].Title = github.String("Widgets wobble")
+		Expect(s.RecordRepository(widget, widgetActivity)).To(Succeed())
+
+		Expect(s.RecordEvents([]*github.Event{push("7001", "dave", "acme/widget", t0.Add(96*time.Hour))})).To(Succeed())
+
+		Expect(ids(store.Query{})).To(Equal([]int{1001, 1201, 1002, 1101, 2001, 5001, 6001, 7001}))
+
+		var title string
+		err := s.Each(store.Query{User: "alice", Kind: store.KindIssue, Until: t0.Add(time.Hour)}, func(e store.Entry, payload []byte) error {
+			if e.ID != 1001 {
+				return nil
+			}
+
+			var i github.Issue
+			err := json.Unmarshal(payload, &i)
+			title = i.GetTitle()
+			return err
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(title).To(Equal("Widgets wobble"))
+
+		for name, keys := range indexes() {
+			Expect(keys).To(HaveLen(8), name)
+		}
+	})
+
+	It("leaves out activity that has not happened yet, such as a pending review", func() {
+		pending := &github.PullRequestReview{ID: github.Int(6002), User: user("frank"), State: github.String("PENDING")}
+		Expect(s.RecordRepository(widget, snapshot.Activity{Reviews: []*github.PullRequestReview{pending}})).To(Succeed())
+
+		Expect(ids(store.Query{Kind: store.KindReview})).To(Equal([]int{6001}))
+
+		frank, err := s.User("frank")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(frank).To(BeNil())
+	})
+
+	DescribeTable("querying the index, oldest first within each kind",
+		func(q store.Query, expected ...int) {
+			if len(expected) == 0 {
+				Expect(ids(q)).To(BeEmpty())
+				return
+			}
+
+			Expect(ids(q)).To(Equal(expected))
+		},
+		Entry("everything", store.Query{}, 1001, 1201, 1002, 1101, 2001, 5001, 6001, 7001),
+		Entry("by user", store.Query{User: "alice"}, 1001, 1201, 1101, 6001),
+		Entry("by a user whose login starts another's", store.Query{User: "al"}),
+		Entry("by repository", store.Query{Repository: "acme/widget"}, 1001, 1002, 2001, 5001, 6001, 7001),
+		Entry("by a repository whose name starts another's", store.Query{Repository: "acme/wid"}),
+		Entry("by kind", store.Query{Kind: store.KindReview}, 6001),
+		Entry("since a time, inclusive", store.Query{Since: t0.Add(24 * time.Hour)}, 1002, 1101, 5001, 6001, 7001),
+		Entry("until a time, exclusive", store.Query{Until: t0.Add(24 * time.Hour)}, 1001, 1201, 2001),
+		Entry("between two times", store.Query{Since: t0.Add(time.Hour), Until: t0.Add(72 * time.Hour)}, 1002, 1101, 2001, 5001),
+		Entry("by user and repository", store.Query{User: "alice", Repository: "acme/widget"}, 1001, 6001),
+		Entry("by user, kind and time", store.Query{User: "alice", Kind: store.KindIssue, Since: t0.Add(time.Hour)}, 1101),
+		Entry("by repository, kind and time", store.Query{Repository: "acme/widget", Kind: store.KindIssue, Until: t0.Add(time.Hour)}, 1001),
+	)
+
+	It("keeps the users, members, stargazers and watchers it was given", func() {
+		alice, err := s.User("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alice.GetLogin()).To(Equal("alice"))
+
+		nobody, err := s.User("nobody")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nobody).To(BeNil())
+
+		members, err := s.Members("acme")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(2))
+
+		stargazers, err := s.Stargazers("acme/widget")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stargazers).To(HaveLen(1))
+
+		Expect(s.RecordRepository(widget, snapshot.Activity{})).To(Succeed())
+
+		stargazers, err = s.Stargazers("acme/widget")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stargazers).To(BeEmpty(), "replaced by the latest crawl")
+	})
+
+	Describe("releases", func() {
+		release := func(id int, downloads int, collected time.Time) *gh.Release {
+			return &gh.Release{
+				Release: &github.RepositoryRelease{
+					ID:     github.Int(id),
+					Assets: []github.ReleaseAsset{{DownloadCount: github.Int(downloads)}},
+				},
+				CollectedAt: collected,
+			}
+		}
+
+		It("carries each release's download count from the crawl before forward", func() {
+			first := t0
+			second := t0.AddDate(0, 0, 7)
+			third := t0.AddDate(0, 0, 14)
+
+			Expect(s.RecordRepository(widget, snapshot.Activity{Releases: []*gh.Release{release(9001, 10, first)}})).To(Succeed())
+
+			releases, err := s.Releases("acme/widget")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(1))
+			Expect(releases[0].Previous).To(BeNil())
+
+			Expect(s.RecordRepository(widget, snapshot.Activity{Releases: []*gh.Release{release(9001, 25, second), release(9002, 3, second)}})).To(Succeed())
+
+			releases, err = s.Releases("acme/widget")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(2))
+			Expect(releases[0].Downloads()).To(Equal(25))
+			Expect(releases[0].Previous).To(Equal(&gh.ReleaseDownloads{CollectedAt: first, Downloads: 10}))
+			Expect(releases[1].Previous).To(BeNil())
+
+			Expect(s.RecordRepository(widget, snapshot.Activity{Releases: []*gh.Release{release(9001, 40, third)}})).To(Succeed())
+
+			releases, err = s.Releases("acme/widget")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(1), "deleted releases are dropped")
+			Expect(releases[0].Previous).To(Equal(&gh.ReleaseDownloads{CollectedAt: second, Downloads: 25}))
+		})
+
+		It("keeps the earlier count when the same crawl is recorded again", func() {
+			Expect(s.RecordRepository(widget, snapshot.Activity{Releases: []*gh.Release{release(9001, 10, t0)}})).To(Succeed())
+			Expect(s.RecordRepository(widget, snapshot.Activity{Releases: []*gh.Release{release(9001, 25, t0.AddDate(0, 0, 7))}})).To(Succeed())
+			Expect(s.RecordRepository(widget, snapshot.Activity{Releases: []*gh.Release{release(9001, 25, t0.AddDate(0, 0, 7))}})).To(Succeed())
+
+			releases, err := s.Releases("acme/widget")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases[0].Previous).To(Equal(&gh.ReleaseDownloads{CollectedAt: t0, Downloads: 10}))
+		})
+	})
+
+	Describe("traffic", func() {
+		crawl := func(at time.Time, views int) *gh.Traffic {
+			return &gh.Traffic{CollectedAt: at, Views: []*github.TrafficData{{Count: github.Int(views)}}}
+		}
+
+		views := func(repo string) []int {
+			history, err := s.Traffic(repo)
+			Expect(err).NotTo(HaveOccurred())
+
+			var counts []int
+			for _, traffic := range history {
+				counts = append(counts, traffic.Views[0].GetCount())
+			}
+
+			return counts
+		}
+
+		It("keeps one crawl per day, the latest of the day, oldest day first", func() {
+			Expect(s.RecordTraffic(widget, crawl(t0.AddDate(0, 0, 1), 20))).To(Succeed())
+			Expect(s.RecordTraffic(widget, crawl(t0, 10))).To(Succeed())
+			Expect(s.RecordTraffic(widget, crawl(t0.Add(8*time.Hour), 15))).To(Succeed())
+			Expect(s.RecordRepository(widget, snapshot.Activity{Traffic: gh.TrafficHistory{crawl(t0.AddDate(0, 0, 2), 30)}})).To(Succeed())
+
+			Expect(views("acme/widget")).To(Equal([]int{15, 20, 30}))
+		})
+
+		It("dates crawls in UTC", func() {
+			pacific := time.FixedZone("PDT", -7*60*60)
+
+			Expect(s.RecordTraffic(widget, crawl(time.Date(2017, time.May, 1, 20, 0, 0, 0, pacific), 10))).To(Succeed())
+			Expect(s.RecordTraffic(widget, crawl(time.Date(2017, time.May, 2, 9, 0, 0, 0, time.UTC), 20))).To(Succeed())
+
+			Expect(views("acme/widget")).To(Equal([]int{20}))
+		})
+
+		It("keeps each repository's traffic apart, even when one name starts another", func() {
+			Expect(s.RecordTraffic(widget, crawl(t0, 10))).To(Succeed())
+			Expect(s.RecordTraffic(repository("acme", "widget-docs"), crawl(t0, 99))).To(Succeed())
+
+			Expect(views("acme/widget")).To(Equal([]int{10}))
+			Expect(views("acme/widget-docs")).To(Equal([]int{99}))
+		})
+	})
+
+	Describe("Replay", func() {
+		It("hands over the activity of the organizations' repositories that pass the filter", func() {
+			replayed := snapshot.New(nil, t0)
+
+			err := s.Replay([]string{"acme"}, gh.RepositoryFilter{Exclude: []string{"gadget"}}, replayed)
+			Expect(err).NotTo(HaveOccurred())
+
+			var repos []string
+			for _, repo := range replayed.Repositories {
+				repos = append(repos, repo.GetFullName())
+			}
+			Expect(repos).To(Equal([]string{"acme/widget"}))
+
+			var issues []int
+			for _, i := range replayed.Issues {
+				issues = append(issues, i.GetID())
+			}
+			Expect(issues).To(Equal([]int{1001, 1002}))
+
+			Expect(replayed.IssueComments).To(HaveLen(1))
+			Expect(replayed.IssueEvents).To(HaveLen(1))
+			Expect(replayed.Reviews).To(HaveLen(1))
+			Expect(replayed.Stargazers["acme/widget"]).To(HaveLen(1))
+			Expect(replayed.Watchers["acme/widget"]).To(HaveLen(1))
+
+			Expect(replayed.Events).To(HaveLen(1))
+			Expect(replayed.Events[0].GetID()).To(Equal("7001"))
+
+			Expect(replayed.Members).To(Equal(map[string][]*github.User{"acme": {user("alice"), user("bob")}}))
+		})
+
+		It("hands over nothing for organizations it has not stored", func() {
+			replayed := snapshot.New(nil, t0)
+
+			Expect(s.Replay([]string{"initech"}, gh.RepositoryFilter{}, replayed)).To(Succeed())
+
+			Expect(replayed.Repositories).To(BeEmpty())
+			Expect(replayed.Events).To(BeEmpty())
+			Expect(replayed.Members).To(Equal(map[string][]*github.User{"initech": nil}))
+		})
+	})
+})
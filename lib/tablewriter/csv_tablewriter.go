@@ -27,6 +27,8 @@ func (c *CSVTableWriter) SetFooter(keys []string) {
 	c.footer = keys
 }
 
+func (c *CSVTableWriter) SetSparkline(column int) {}
+
 func (c *CSVTableWriter) Append(row []string) {
 	c.rows = append(c.rows, row)
 }
@@ -0,0 +1,168 @@
+package tablewriter
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("table").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+table { border-collapse: collapse; font-family: sans-serif; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+thead, tfoot { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<table>
+{{- if .Header}}
+<thead>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+{{- end}}
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+{{- if .Footer}}
+<tfoot>
+<tr>{{range .Footer}}<td>{{.}}</td>{{end}}</tr>
+</tfoot>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// HTMLTableWriter renders a standalone HTML page holding the table.
+type HTMLTableWriter struct {
+	io.Writer
+
+	header     []string
+	footer     []string
+	rows       [][]string
+	sparklines map[int]bool
+}
+
+func NewHTMLTableWriter(w io.Writer) *HTMLTableWriter {
+	return &HTMLTableWriter{
+		Writer:     w,
+		sparklines: map[int]bool{},
+	}
+}
+
+func (h *HTMLTableWriter) SetHeader(keys []string) {
+	h.header = keys
+}
+
+func (h *HTMLTableWriter) SetFooter(keys []string) {
+	h.footer = keys
+}
+
+func (h *HTMLTableWriter) SetSparkline(column int) {
+	h.sparklines[column] = true
+}
+
+func (h *HTMLTableWriter) Append(row []string) {
+	h.rows = append(h.rows, row)
+}
+
+func (h *HTMLTableWriter) Render() error {
+	var rows [][]interface{}
+	for _, row := range h.rows {
+		var cells []interface{}
+		for i, cell := range row {
+			var value interface{} = cell
+			if h.sparklines[i] {
+				if line, ok := sparkline(cell); ok {
+					value = line
+				}
+			}
+
+			cells = append(cells, value)
+		}
+
+		rows = append(rows, cells)
+	}
+
+	return htmlTemplate.Execute(h.Writer, struct {
+		Header []string
+		Footer []string
+		Rows   [][]interface{}
+	}{
+		Header: h.header,
+		Footer: h.footer,
+		Rows:   rows,
+	})
+}
+
+const (
+	sparklineWidth  = 100
+	sparklineHeight = 20
+)
+
+// sparkline draws a series of numbers separated by spaces as an inline SVG
+// polyline scaled to fit, or returns false if the cell is not such a series.
+// The SVG is built from the parsed numbers only, so it is safe to render
+// unescaped.
+func sparkline(cell string) (template.HTML, bool) {
+	fields := strings.Fields(cell)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return "", false
+		}
+
+		values[i] = v
+	}
+
+	if len(values) == 1 {
+		values = append(values, values[0])
+	}
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+
+		if v > max {
+			max = v
+		}
+	}
+
+	var points []string
+	for i, v := range values {
+		x := float64(i) * sparklineWidth / float64(len(values)-1)
+
+		y := float64(sparklineHeight) / 2
+		if max > min {
+			y = sparklineHeight - 1 - (v-min)/(max-min)*(sparklineHeight-2)
+		}
+
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+
+	var title []string
+	for _, v := range values[:len(fields)] {
+		title = append(title, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	return template.HTML(fmt.Sprintf(
+		`<svg class="sparkline" style="vertical-align: middle" width="%d" height="%d" viewBox="0 0 %d %d"><title>%s</title><polyline fill="none" stroke="#36c" stroke-width="1.5" points="%s"/></svg>`,
+		sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight,
+		strings.Join(title, " "),
+		strings.Join(points, " "),
+	)), true
+}
@@ -0,0 +1,104 @@
+package tablewriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// JSONTableWriter renders the table as a JSON array with one object per row,
+// keyed by the header in its order. The footer is a summary of the rows and is
+// not included.
+type JSONTableWriter struct {
+	io.Writer
+
+	header []string
+	rows   [][]string
+}
+
+func NewJSONTableWriter(w io.Writer) *JSONTableWriter {
+	return &JSONTableWriter{
+		Writer: w,
+	}
+}
+
+func (j *JSONTableWriter) SetHeader(keys []string) {
+	j.header = keys
+}
+
+func (j *JSONTableWriter) SetFooter(keys []string) {}
+
+func (j *JSONTableWriter) SetSparkline(column int) {}
+
+func (j *JSONTableWriter) Append(row []string) {
+	j.rows = append(j.rows, row)
+}
+
+func (j *JSONTableWriter) Render() error {
+	objects := []jsonObject{}
+
+	for _, r := range j.rows {
+		object := jsonObject{}
+		for i, value := range r {
+			if i < len(j.header) {
+				object.keys = append(object.keys, j.header[i])
+				object.values = append(object.values, value)
+			}
+		}
+
+		objects = append(objects, object)
+	}
+
+	encoder := json.NewEncoder(j.Writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(objects)
+}
+
+// jsonObject is a row whose keys are encoded in the order of the columns,
+// which a map would sort.
+type jsonObject struct {
+	keys   []string
+	values []string
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	writeString := func(s string) error {
+		err := encoder.Encode(s)
+		if err != nil {
+			return err
+		}
+
+		// Encode ends every value with a newline.
+		buf.Truncate(buf.Len() - 1)
+		return nil
+	}
+
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		err := writeString(key)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteByte(':')
+
+		err = writeString(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
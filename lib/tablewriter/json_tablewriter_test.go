@@ -0,0 +1,61 @@
+package tablewriter_test
+
+import (
+	"bytes"
+
+	"github.com/chendrix/pm/lib/tablewriter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSONTableWriter", func() {
+	var (
+		buf bytes.Buffer
+		t   *tablewriter.JSONTableWriter
+	)
+
+	BeforeEach(func() {
+		buf.Reset()
+		t = tablewriter.NewJSONTableWriter(&buf)
+	})
+
+	It("keys each row by the header, keeping the order of the columns", func() {
+		t.SetHeader([]string{"Repository", "Github User", "Another <b>Column</b>", "Alpha"})
+		t.Append([]string{"acme/widget", "alice", `"quoted" & <b>bold</b>`, "1"})
+		t.Append([]string{"acme/gadget", "bob", "", "2", "ignored"})
+		t.SetFooter([]string{"Total", "", "", "3"})
+
+		Expect(t.Render()).To(Succeed())
+		Expect(buf.String()).To(Equal(`[
+  {
+    "Repository": "acme/widget",
+    "Github User": "alice",
+    "Another <b>Column</b>": "\"quoted\" & <b>bold</b>",
+    "Alpha": "1"
+  },
+  {
+    "Repository": "acme/gadget",
+    "Github User": "bob",
+    "Another <b>Column</b>": "",
+    "Alpha": "2"
+  }
+]
+`))
+	})
+
+	It("renders an empty array without rows", func() {
+		t.SetHeader([]string{"Repository"})
+
+		Expect(t.Render()).To(Succeed())
+		Expect(buf.String()).To(Equal("[]\n"))
+	})
+
+	It("renders a row without values as an empty object", func() {
+		t.SetHeader([]string{"Repository"})
+		t.Append([]string{})
+
+		Expect(t.Render()).To(Succeed())
+		Expect(buf.String()).To(Equal("[\n  {}\n]\n"))
+	})
+})
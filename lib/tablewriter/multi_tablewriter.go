@@ -0,0 +1,49 @@
+package tablewriter
+
+// MultiTableWriter duplicates a table to several TableWriters, so one report
+// can be rendered in several formats at once.
+type MultiTableWriter struct {
+	writers []TableWriter
+}
+
+func NewMultiTableWriter(writers ...TableWriter) *MultiTableWriter {
+	return &MultiTableWriter{
+		writers: writers,
+	}
+}
+
+func (m *MultiTableWriter) SetHeader(keys []string) {
+	for _, w := range m.writers {
+		w.SetHeader(keys)
+	}
+}
+
+func (m *MultiTableWriter) SetFooter(keys []string) {
+	for _, w := range m.writers {
+		w.SetFooter(keys)
+	}
+}
+
+func (m *MultiTableWriter) SetSparkline(column int) {
+	for _, w := range m.writers {
+		w.SetSparkline(column)
+	}
+}
+
+func (m *MultiTableWriter) Append(row []string) {
+	for _, w := range m.writers {
+		w.Append(row)
+	}
+}
+
+// Render renders every writer, stopping at the first error.
+func (m *MultiTableWriter) Render() error {
+	for _, w := range m.writers {
+		err := w.Render()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,58 @@
 package tablewriter
 
+import (
+	"fmt"
+	"io"
+)
+
 type TableWriter interface {
 	SetHeader(keys []string)
 	SetFooter(keys []string)
+
+	// SetSparkline marks a column whose cells are series of numbers separated
+	// by spaces. HTML draws them as sparklines; the other formats print the
+	// numbers.
+	SetSparkline(column int)
+
 	Append(row []string)
 	Render() error
 }
+
+// Formats lists the names accepted by New.
+var Formats = []string{"csv", "text", "markdown", "json", "html"}
+
+// ContentTypes maps each format to the media type of what it renders.
+var ContentTypes = map[string]string{
+	"csv":      "text/csv; charset=utf-8",
+	"text":     "text/plain; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+	"json":     "application/json",
+	"html":     "text/html; charset=utf-8",
+}
+
+// Extensions maps each format to the file extension of what it renders.
+var Extensions = map[string]string{
+	"csv":      ".csv",
+	"text":     ".txt",
+	"markdown": ".md",
+	"json":     ".json",
+	"html":     ".html",
+}
+
+// New returns a TableWriter for the named format that renders to w.
+func New(format string, w io.Writer) (TableWriter, error) {
+	switch format {
+	case "csv":
+		return NewCSVTableWriter(w), nil
+	case "text":
+		return NewTextTableWriter(w), nil
+	case "markdown":
+		return NewMarkdownTableWriter(w), nil
+	case "json":
+		return NewJSONTableWriter(w), nil
+	case "html":
+		return NewHTMLTableWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown table format: %s", format)
+	}
+}
@@ -0,0 +1,13 @@
+package tablewriter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTablewriter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tablewriter Suite")
+}
@@ -0,0 +1,101 @@
+package tablewriter
+
+import (
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// TextTableWriter renders an aligned plain-text table for reading in a
+// terminal.
+type TextTableWriter struct {
+	table *tablewriter.Table
+
+	// footerAsRow renders the footer as a last row, for formats without a
+	// footer of their own.
+	footerAsRow bool
+	footer      []string
+
+	// escape, if set, rewrites every cell before it is rendered.
+	escape func(string) string
+}
+
+func NewTextTableWriter(w io.Writer) *TextTableWriter {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+
+	return &TextTableWriter{
+		table: table,
+	}
+}
+
+// NewMarkdownTableWriter renders a GitHub-flavored Markdown table, suitable
+// for pasting into issues or chat.
+func NewMarkdownTableWriter(w io.Writer) *TextTableWriter {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Right: true})
+	table.SetCenterSeparator("|")
+
+	return &TextTableWriter{
+		table:       table,
+		footerAsRow: true,
+		escape:      escapeMarkdownCell,
+	}
+}
+
+// markdownCellEscaper keeps a cell on its table row: a pipe would end the
+// cell and a newline the row.
+var markdownCellEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+func escapeMarkdownCell(cell string) string {
+	return markdownCellEscaper.Replace(cell)
+}
+
+func (t *TextTableWriter) SetHeader(keys []string) {
+	t.table.SetHeader(t.escapeAll(keys))
+}
+
+func (t *TextTableWriter) SetFooter(keys []string) {
+	if t.footerAsRow {
+		t.footer = t.escapeAll(keys)
+		return
+	}
+
+	t.table.SetFooter(t.escapeAll(keys))
+}
+
+func (t *TextTableWriter) SetSparkline(column int) {}
+
+func (t *TextTableWriter) Append(row []string) {
+	t.table.Append(t.escapeAll(row))
+}
+
+func (t *TextTableWriter) Render() error {
+	if t.footer != nil {
+		t.table.Append(t.footer)
+	}
+
+	t.table.Render()
+	return nil
+}
+
+func (t *TextTableWriter) escapeAll(cells []string) []string {
+	if t.escape == nil {
+		return cells
+	}
+
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = t.escape(cell)
+	}
+
+	return escaped
+}
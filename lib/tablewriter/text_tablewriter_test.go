@@ -0,0 +1,43 @@
+package tablewriter_test
+
+import (
+	"bytes"
+
+	"github.com/chendrix/pm/lib/tablewriter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Markdown table writer", func() {
+	It("escapes pipes and newlines so every cell stays in its row", func() {
+		var buf bytes.Buffer
+
+		t := tablewriter.NewMarkdownTableWriter(&buf)
+		t.SetHeader([]string{"Issue", "Title | Summary"})
+		t.Append([]string{"#1", "Widgets | gadgets"})
+		t.Append([]string{"#2", "First line\nsecond line\r\nthird line"})
+		t.SetFooter([]string{"Total", "a|b"})
+
+		Expect(t.Render()).To(Succeed())
+		Expect(buf.String()).To(Equal(`| Issue |            Title \| Summary             |
+|-------|-----------------------------------------|
+| #1    | Widgets \| gadgets                      |
+| #2    | First line<br>second line<br>third line |
+| Total | a\|b                                    |
+`))
+	})
+})
+
+var _ = Describe("Text table writer", func() {
+	It("leaves cells as they are", func() {
+		var buf bytes.Buffer
+
+		t := tablewriter.NewTextTableWriter(&buf)
+		t.SetHeader([]string{"Issue", "Title"})
+		t.Append([]string{"#1", "Widgets | gadgets"})
+
+		Expect(t.Render()).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("| #1    | Widgets | gadgets |"))
+	})
+})
@@ -0,0 +1,351 @@
+// Package warehouse exports crawled activity as newline-delimited JSON
+// tables with BigQuery schema files, and loads them into BigQuery.
+package warehouse
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/google/go-github/github"
+)
+
+// Exporter writes every repository it is handed to the tables in a
+// directory. Rows are keyed by GitHub IDs, so exports of the same
+// organization can be joined and deduplicated across runs.
+type Exporter struct {
+	ExcludedUsers map[string]bool
+
+	files    map[string]*os.File
+	encoders map[string]*json.Encoder
+
+	users map[int]*userRow
+}
+
+var _ snapshot.Recorder = &Exporter{}
+
+// NewExporter creates dir, writes a schema file for every table into it and
+// opens the table files.
+func NewExporter(dir string) (*Exporter, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exporter{
+		files:    map[string]*os.File{},
+		encoders: map[string]*json.Encoder{},
+		users:    map[int]*userRow{},
+	}
+
+	for _, t := range Tables {
+		err := writeSchema(filepath.Join(dir, t.Name+".schema.json"), t)
+		if err != nil {
+			e.close()
+			return nil, err
+		}
+
+		f, err := os.Create(TablePath(dir, t))
+		if err != nil {
+			e.close()
+			return nil, err
+		}
+
+		e.files[t.Name] = f
+		e.encoders[t.Name] = json.NewEncoder(f)
+	}
+
+	return e, nil
+}
+
+// TablePath is where the rows of table t are written in dir.
+func TablePath(dir string, t Table) string {
+	return filepath.Join(dir, t.Name+".json")
+}
+
+func writeSchema(path string, t Table) error {
+	payload, err := json.MarshalIndent(t.Fields, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(payload, '\n'), 0644)
+}
+
+type userRow struct {
+	ID       int      `json:"id"`
+	Login    string   `json:"login"`
+	Type     string   `json:"type,omitempty"`
+	HTMLURL  string   `json:"html_url,omitempty"`
+	MemberOf []string `json:"member_of"`
+}
+
+type repositoryRow struct {
+	ID         int        `json:"id"`
+	FullName   string     `json:"full_name"`
+	Owner      string     `json:"owner"`
+	Name       string     `json:"name"`
+	HTMLURL    string     `json:"html_url,omitempty"`
+	Fork       bool       `json:"fork"`
+	Stargazers int        `json:"stargazers"`
+	Forks      int        `json:"forks"`
+	OpenIssues int        `json:"open_issues"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+type issueRow struct {
+	ID           int        `json:"id"`
+	RepositoryID int        `json:"repository_id"`
+	Repository   string     `json:"repository"`
+	Number       int        `json:"number"`
+	UserID       int        `json:"user_id,omitempty"`
+	UserLogin    string     `json:"user_login,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	State        string     `json:"state,omitempty"`
+	PullRequest  bool       `json:"pull_request"`
+	Labels       []string   `json:"labels"`
+	Comments     int        `json:"comments"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	HTMLURL      string     `json:"html_url,omitempty"`
+}
+
+type commentRow struct {
+	ID           int        `json:"id"`
+	Kind         string     `json:"kind"`
+	RepositoryID int        `json:"repository_id"`
+	Repository   string     `json:"repository"`
+	IssueNumber  int        `json:"issue_number,omitempty"`
+	CommitID     string     `json:"commit_id,omitempty"`
+	UserID       int        `json:"user_id,omitempty"`
+	UserLogin    string     `json:"user_login,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	HTMLURL      string     `json:"html_url,omitempty"`
+}
+
+type reviewRow struct {
+	ID                int        `json:"id"`
+	RepositoryID      int        `json:"repository_id"`
+	Repository        string     `json:"repository"`
+	PullRequestNumber int        `json:"pull_request_number,omitempty"`
+	UserID            int        `json:"user_id,omitempty"`
+	UserLogin         string     `json:"user_login,omitempty"`
+	State             string     `json:"state,omitempty"`
+	SubmittedAt       *time.Time `json:"submitted_at,omitempty"`
+	HTMLURL           string     `json:"html_url,omitempty"`
+}
+
+func (e *Exporter) RecordRepository(repo *github.Repository, activity snapshot.Activity) error {
+	row := repositoryRow{
+		ID:         repo.GetID(),
+		FullName:   repo.GetFullName(),
+		Owner:      repo.Owner.GetLogin(),
+		Name:       repo.GetName(),
+		HTMLURL:    repo.GetHTMLURL(),
+		Fork:       repo.GetFork(),
+		Stargazers: repo.GetStargazersCount(),
+		Forks:      repo.GetForksCount(),
+		OpenIssues: repo.GetOpenIssuesCount(),
+	}
+
+	if repo.CreatedAt != nil {
+		row.CreatedAt = &repo.CreatedAt.Time
+	}
+
+	err := e.encoders[Repositories.Name].Encode(row)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range activity.Issues {
+		if e.excludes(i.User) {
+			continue
+		}
+
+		labels := []string{}
+		for _, l := range i.Labels {
+			labels = append(labels, l.GetName())
+		}
+
+		err := e.encoders[Issues.Name].Encode(issueRow{
+			ID:           i.GetID(),
+			RepositoryID: repo.GetID(),
+			Repository:   repo.GetFullName(),
+			Number:       i.GetNumber(),
+			UserID:       e.user(i.User),
+			UserLogin:    i.User.GetLogin(),
+			Title:        i.GetTitle(),
+			State:        i.GetState(),
+			PullRequest:  i.PullRequestLinks != nil,
+			Labels:       labels,
+			Comments:     i.GetComments(),
+			CreatedAt:    i.CreatedAt,
+			UpdatedAt:    i.UpdatedAt,
+			ClosedAt:     i.ClosedAt,
+			HTMLURL:      i.GetHTMLURL(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, c := range activity.IssueComments {
+		if e.excludes(c.User) {
+			continue
+		}
+
+		err := e.encoders[Comments.Name].Encode(commentRow{
+			ID:           c.GetID(),
+			Kind:         "issue",
+			RepositoryID: repo.GetID(),
+			Repository:   repo.GetFullName(),
+			IssueNumber:  lastNumber(c.GetIssueURL()),
+			UserID:       e.user(c.User),
+			UserLogin:    c.User.GetLogin(),
+			CreatedAt:    c.CreatedAt,
+			UpdatedAt:    c.UpdatedAt,
+			HTMLURL:      c.GetHTMLURL(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, c := range activity.RepositoryComments {
+		if e.excludes(c.User) {
+			continue
+		}
+
+		err := e.encoders[Comments.Name].Encode(commentRow{
+			ID:           c.GetID(),
+			Kind:         "commit",
+			RepositoryID: repo.GetID(),
+			Repository:   repo.GetFullName(),
+			CommitID:     c.GetCommitID(),
+			UserID:       e.user(c.User),
+			UserLogin:    c.User.GetLogin(),
+			CreatedAt:    c.CreatedAt,
+			UpdatedAt:    c.UpdatedAt,
+			HTMLURL:      c.GetHTMLURL(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range activity.Reviews {
+		if e.excludes(r.User) {
+			continue
+		}
+
+		err := e.encoders[Reviews.Name].Encode(reviewRow{
+			ID:                r.GetID(),
+			RepositoryID:      repo.GetID(),
+			Repository:        repo.GetFullName(),
+			PullRequestNumber: lastNumber(r.GetPullRequestURL()),
+			UserID:            e.user(r.User),
+			UserLogin:         r.User.GetLogin(),
+			State:             r.GetState(),
+			SubmittedAt:       r.SubmittedAt,
+			HTMLURL:           r.GetHTMLURL(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordEvents ignores event stream events, which have no table.
+func (e *Exporter) RecordEvents(events []*github.Event) error {
+	return nil
+}
+
+func (e *Exporter) RecordMembers(org string, members []*github.User) error {
+	for _, m := range members {
+		if e.excludes(m) || m.GetID() == 0 {
+			continue
+		}
+
+		e.user(m)
+		e.users[m.GetID()].MemberOf = append(e.users[m.GetID()].MemberOf, org)
+	}
+
+	return nil
+}
+
+// Close writes the users table, which is only complete once every
+// repository and organization has been recorded, and closes every table.
+func (e *Exporter) Close() error {
+	var ids []int
+	for id := range e.users {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		err := e.encoders[Users.Name].Encode(e.users[id])
+		if err != nil {
+			e.close()
+			return err
+		}
+	}
+
+	return e.close()
+}
+
+func (e *Exporter) close() error {
+	var firstErr error
+	for _, f := range e.files {
+		err := f.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// user remembers a user for the users table and returns their ID.
+func (e *Exporter) user(u *github.User) int {
+	if u == nil || u.GetID() == 0 {
+		return 0
+	}
+
+	if _, found := e.users[u.GetID()]; !found {
+		e.users[u.GetID()] = &userRow{
+			ID:       u.GetID(),
+			Login:    u.GetLogin(),
+			Type:     u.GetType(),
+			HTMLURL:  u.GetHTMLURL(),
+			MemberOf: []string{},
+		}
+	}
+
+	return u.GetID()
+}
+
+func (e *Exporter) excludes(user *github.User) bool {
+	return user != nil && e.ExcludedUsers[user.GetLogin()]
+}
+
+// lastNumber returns the issue or pull request number at the end of an API
+// URL, or 0.
+func lastNumber(apiURL string) int {
+	n, err := strconv.Atoi(apiURL[strings.LastIndex(apiURL, "/")+1:])
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
@@ -0,0 +1,203 @@
+package warehouse_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/gh/ghtest"
+	"github.com/chendrix/pm/lib/snapshot"
+	"github.com/chendrix/pm/lib/warehouse"
+	"github.com/google/go-github/github"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// rows reads a table's newline-delimited JSON, one string per row.
+func rows(dir string, t warehouse.Table) []string {
+	f, err := os.Open(warehouse.TablePath(dir, t))
+	Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+
+	var all []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		all = append(all, scanner.Text())
+	}
+	Expect(scanner.Err()).NotTo(HaveOccurred())
+
+	return all
+}
+
+var _ = Describe("Exporter", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pm-export")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("writes a BigQuery schema file for every table", func() {
+		exporter, err := warehouse.NewExporter(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exporter.Close()).To(Succeed())
+
+		for _, t := range warehouse.Tables {
+			payload, err := ioutil.ReadFile(filepath.Join(dir, t.Name+".schema.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var fields []warehouse.Field
+			Expect(json.Unmarshal(payload, &fields)).To(Succeed())
+			Expect(fields).To(Equal(t.Fields))
+
+			Expect(rows(dir, t)).To(BeEmpty())
+		}
+
+		payload, err := ioutil.ReadFile(filepath.Join(dir, "reviews.schema.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(payload)).To(HavePrefix(`[
+  {
+    "name": "id",
+    "type": "INTEGER",
+    "mode": "REQUIRED",
+    "description": "GitHub review ID"
+  },`))
+	})
+
+	It("writes a row per repository, issue, comment, review and user", func() {
+		created := time.Date(2017, time.May, 1, 9, 0, 0, 0, time.UTC)
+		closed := created.Add(48 * time.Hour)
+
+		alice := &github.User{ID: github.Int(1), Login: github.String("alice"), Type: github.String("User"), HTMLURL: github.String("https://github.com/alice")}
+		bob := &github.User{ID: github.Int(2), Login: github.String("bob"), Type: github.String("User")}
+		mallory := &github.User{ID: github.Int(9), Login: github.String("mallory")}
+
+		repo := &github.Repository{
+			ID:              github.Int(100),
+			FullName:        github.String("acme/widget"),
+			Owner:           &github.User{Login: github.String("acme")},
+			Name:            github.String("widget"),
+			StargazersCount: github.Int(3),
+			CreatedAt:       &github.Timestamp{Time: created},
+		}
+
+		activity := snapshot.Activity{
+			Issues: []*github.Issue{
+				{
+					ID: github.Int(1001), Number: github.Int(1), User: alice,
+					Title: github.String("Widgets wobble"), State: github.String("closed"),
+					Labels:    []github.Label{{Name: github.String("bug")}},
+					Comments:  github.Int(1),
+					CreatedAt: &created, UpdatedAt: &closed, ClosedAt: &closed,
+				},
+				{
+					ID: github.Int(1002), Number: github.Int(2), User: nil,
+					PullRequestLinks: &github.PullRequestLinks{},
+					CreatedAt:        &created,
+				},
+				{ID: github.Int(1003), Number: github.Int(3), User: mallory},
+			},
+			IssueComments: []*github.IssueComment{
+				{ID: github.Int(3001), User: bob, IssueURL: github.String("https://api.github.com/repos/acme/widget/issues/1"), CreatedAt: &created},
+				{ID: github.Int(3002), User: mallory},
+			},
+			RepositoryComments: []*github.RepositoryComment{
+				{ID: github.Int(4001), User: alice, CommitID: github.String("abc123"), CreatedAt: &created},
+			},
+			Reviews: []*github.PullRequestReview{
+				{ID: github.Int(6001), User: bob, State: github.String("APPROVED"), PullRequestURL: github.String("https://api.github.com/repos/acme/widget/pulls/2"), SubmittedAt: &closed},
+			},
+		}
+
+		exporter, err := warehouse.NewExporter(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		exporter.ExcludedUsers = map[string]bool{"mallory": true}
+
+		Expect(exporter.RecordRepository(repo, activity)).To(Succeed())
+		Expect(exporter.RecordMembers("acme", []*github.User{alice, mallory, {Login: github.String("ghost")}})).To(Succeed())
+		Expect(exporter.Close()).To(Succeed())
+
+		expected := map[string][]string{
+			warehouse.Repositories.Name: {
+				`{"id":100,"full_name":"acme/widget","owner":"acme","name":"widget","fork":false,"stargazers":3,"forks":0,"open_issues":0,"created_at":"2017-05-01T09:00:00Z"}`,
+			},
+			warehouse.Issues.Name: {
+				`{"id":1001,"repository_id":100,"repository":"acme/widget","number":1,"user_id":1,"user_login":"alice","title":"Widgets wobble","state":"closed","pull_request":false,"labels":["bug"],"comments":1,"created_at":"2017-05-01T09:00:00Z","updated_at":"2017-05-03T09:00:00Z","closed_at":"2017-05-03T09:00:00Z"}`,
+				`{"id":1002,"repository_id":100,"repository":"acme/widget","number":2,"pull_request":true,"labels":[],"comments":0,"created_at":"2017-05-01T09:00:00Z"}`,
+			},
+			warehouse.Comments.Name: {
+				`{"id":3001,"kind":"issue","repository_id":100,"repository":"acme/widget","issue_number":1,"user_id":2,"user_login":"bob","created_at":"2017-05-01T09:00:00Z"}`,
+				`{"id":4001,"kind":"commit","repository_id":100,"repository":"acme/widget","commit_id":"abc123","user_id":1,"user_login":"alice","created_at":"2017-05-01T09:00:00Z"}`,
+			},
+			warehouse.Reviews.Name: {
+				`{"id":6001,"repository_id":100,"repository":"acme/widget","pull_request_number":2,"user_id":2,"user_login":"bob","state":"APPROVED","submitted_at":"2017-05-03T09:00:00Z"}`,
+			},
+			warehouse.Users.Name: {
+				`{"id":1,"login":"alice","type":"User","html_url":"https://github.com/alice","member_of":["acme"]}`,
+				`{"id":2,"login":"bob","type":"User","member_of":[]}`,
+			},
+		}
+
+		for _, t := range warehouse.Tables {
+			actual := rows(dir, t)
+			Expect(actual).To(HaveLen(len(expected[t.Name])), t.Name)
+
+			for i, row := range actual {
+				Expect(row).To(MatchJSON(expected[t.Name][i]), t.Name)
+			}
+		}
+	})
+
+	It("only writes the fields its schema declares, with every required one", func() {
+		server := ghtest.NewServer("../gh/ghtest/testdata/acme")
+		defer server.Close()
+
+		exporter, err := warehouse.NewExporter(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = snapshot.Fetch(context.Background(), lagertest.NewTestLogger("test"), server.GitHubClient(), []string{"acme"}, exporter)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exporter.Close()).To(Succeed())
+
+		for _, t := range warehouse.Tables {
+			declared := map[string]warehouse.Field{}
+			for _, f := range t.Fields {
+				declared[f.Name] = f
+			}
+
+			table := rows(dir, t)
+			Expect(table).NotTo(BeEmpty(), t.Name)
+
+			for _, row := range table {
+				var values map[string]interface{}
+				Expect(json.Unmarshal([]byte(row), &values)).To(Succeed())
+
+				for name := range values {
+					Expect(declared).To(HaveKey(name), t.Name)
+				}
+
+				for name, f := range declared {
+					if f.Mode == "REQUIRED" {
+						Expect(values).To(HaveKey(name), t.Name)
+					}
+
+					if f.Mode == "REPEATED" {
+						Expect(values[name]).To(BeAssignableToTypeOf([]interface{}{}), t.Name+"."+name)
+					}
+				}
+			}
+		}
+	})
+})
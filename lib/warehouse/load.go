@@ -0,0 +1,55 @@
+package warehouse
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"cloud.google.com/go/bigquery"
+	"code.cloudfoundry.org/lager"
+)
+
+// Load replaces the contents of every table in the dataset with the rows
+// exported to dir, creating the tables as needed.
+func Load(ctx context.Context, logger lager.Logger, client *bigquery.Client, dataset string, dir string) error {
+	logger = logger.Session("load", lager.Data{"dataset": dataset})
+
+	for _, t := range Tables {
+		logger.Info("loading", lager.Data{"table": t.Name})
+
+		err := loadTable(ctx, client.Dataset(dataset).Table(t.Name), t, TablePath(dir, t))
+		if err != nil {
+			return fmt.Errorf("loading %s.%s: %s", dataset, t.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func loadTable(ctx context.Context, table *bigquery.Table, t Table, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	source := bigquery.NewReaderSource(f)
+	source.SourceFormat = bigquery.JSON
+	source.Schema = t.Schema()
+
+	loader := table.LoaderFrom(source)
+	loader.CreateDisposition = bigquery.CreateIfNeeded
+	loader.WriteDisposition = bigquery.WriteTruncate
+
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	return status.Err()
+}
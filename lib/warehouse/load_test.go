@@ -0,0 +1,184 @@
+package warehouse_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/chendrix/pm/lib/warehouse"
+	"github.com/google/go-github/github"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeBigQuery accepts load jobs uploaded with their data, records them and
+// reports them done, failing those into the tables listed in failures.
+type fakeBigQuery struct {
+	*httptest.Server
+
+	failures map[string]string
+
+	lock sync.Mutex
+	jobs []loadJob
+}
+
+type loadJob struct {
+	Load *bq.JobConfigurationLoad
+	Data string
+}
+
+func newFakeBigQuery() *fakeBigQuery {
+	f := &fakeBigQuery{failures: map[string]string{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+
+	return f
+}
+
+func (f *fakeBigQuery) Jobs() []loadJob {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return append([]loadJob{}, f.jobs...)
+}
+
+func (f *fakeBigQuery) serve(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/projects/acme-project/jobs":
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		Expect(err).NotTo(HaveOccurred())
+
+		parts := multipart.NewReader(r.Body, params["boundary"])
+
+		part, err := parts.NextPart()
+		Expect(err).NotTo(HaveOccurred())
+
+		var job bq.Job
+		Expect(json.NewDecoder(part).Decode(&job)).To(Succeed())
+
+		part, err = parts.NextPart()
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := ioutil.ReadAll(part)
+		Expect(err).NotTo(HaveOccurred())
+
+		f.lock.Lock()
+		f.jobs = append(f.jobs, loadJob{Load: job.Configuration.Load, Data: string(data)})
+		f.lock.Unlock()
+
+		fmt.Fprintf(w, `{"jobReference": {"projectId": "acme-project", "jobId": %q}, "status": {"state": "RUNNING"}}`,
+			job.Configuration.Load.DestinationTable.TableId)
+
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/projects/acme-project/jobs/"):
+		table := strings.TrimPrefix(r.URL.Path, "/projects/acme-project/jobs/")
+
+		if reason, failed := f.failures[table]; failed {
+			fmt.Fprintf(w, `{"status": {"state": "DONE", "errorResult": {"reason": "invalid", "message": %q}}, "statistics": {}}`, reason)
+			return
+		}
+
+		fmt.Fprint(w, `{"status": {"state": "DONE"}, "statistics": {"load": {}}}`)
+
+	default:
+		Fail(fmt.Sprintf("unexpected BigQuery request %s %s", r.Method, r.URL))
+	}
+}
+
+var _ = Describe("Load", func() {
+	var (
+		dir    string
+		server *fakeBigQuery
+		client *bigquery.Client
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pm-load")
+		Expect(err).NotTo(HaveOccurred())
+
+		exporter, err := warehouse.NewExporter(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exporter.RecordMembers("acme", []*github.User{{ID: github.Int(1), Login: github.String("alice")}})).To(Succeed())
+		Expect(exporter.Close()).To(Succeed())
+
+		server = newFakeBigQuery()
+
+		client, err = bigquery.NewClient(context.Background(), "acme-project",
+			option.WithEndpoint(server.URL+"/"),
+			option.WithHTTPClient(http.DefaultClient),
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("replaces every table with the exported rows and schema", func() {
+		err := warehouse.Load(context.Background(), lagertest.NewTestLogger("test"), client, "github", dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		jobs := server.Jobs()
+		Expect(jobs).To(HaveLen(len(warehouse.Tables)))
+
+		for i, t := range warehouse.Tables {
+			load := jobs[i].Load
+
+			Expect(load.DestinationTable).To(Equal(&bq.TableReference{ProjectId: "acme-project", DatasetId: "github", TableId: t.Name}))
+			Expect(load.SourceFormat).To(Equal("NEWLINE_DELIMITED_JSON"))
+			Expect(load.CreateDisposition).To(Equal("CREATE_IF_NEEDED"))
+			Expect(load.WriteDisposition).To(Equal("WRITE_TRUNCATE"))
+
+			var fields []warehouse.Field
+			for _, f := range load.Schema.Fields {
+				mode := f.Mode
+				if mode == "" {
+					mode = "NULLABLE"
+				}
+
+				fields = append(fields, warehouse.Field{Name: f.Name, Type: f.Type, Mode: mode, Description: f.Description})
+			}
+
+			Expect(fields).To(Equal(t.Fields), t.Name)
+
+			exported, err := ioutil.ReadFile(warehouse.TablePath(dir, t))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs[i].Data).To(Equal(string(exported)), t.Name)
+		}
+
+		Expect(jobs[0].Data).To(MatchJSON(`{"id":1,"login":"alice","member_of":["acme"]}`))
+	})
+
+	It("stops at the first table that fails to load", func() {
+		server.failures["issues"] = "no such field: mystery"
+
+		err := warehouse.Load(context.Background(), lagertest.NewTestLogger("test"), client, "github", dir)
+		Expect(err).To(MatchError(ContainSubstring("loading github.issues: ")))
+		Expect(err).To(MatchError(ContainSubstring("no such field: mystery")))
+
+		var loaded []string
+		for _, job := range server.Jobs() {
+			loaded = append(loaded, job.Load.DestinationTable.TableId)
+		}
+
+		Expect(loaded).To(Equal([]string{"users", "repositories", "issues"}))
+	})
+})
@@ -0,0 +1,120 @@
+package warehouse
+
+import (
+	"cloud.google.com/go/bigquery"
+)
+
+// Field is a column in the BigQuery JSON schema format accepted by
+// `bq load --schema`.
+type Field struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Mode        string `json:"mode"`
+	Description string `json:"description,omitempty"`
+}
+
+// Table is an exported table. Its rows are written to <Name>.json and its
+// schema to <Name>.schema.json.
+type Table struct {
+	Name   string
+	Fields []Field
+}
+
+// Schema converts the table's fields for the bigquery client.
+func (t Table) Schema() bigquery.Schema {
+	var schema bigquery.Schema
+	for _, f := range t.Fields {
+		schema = append(schema, &bigquery.FieldSchema{
+			Name:        f.Name,
+			Description: f.Description,
+			Type:        bigquery.FieldType(f.Type),
+			Required:    f.Mode == "REQUIRED",
+			Repeated:    f.Mode == "REPEATED",
+		})
+	}
+
+	return schema
+}
+
+var Users = Table{
+	Name: "users",
+	Fields: []Field{
+		{"id", "INTEGER", "REQUIRED", "GitHub user ID"},
+		{"login", "STRING", "REQUIRED", ""},
+		{"type", "STRING", "NULLABLE", "User, Bot or Organization"},
+		{"html_url", "STRING", "NULLABLE", ""},
+		{"member_of", "STRING", "REPEATED", "Exported organizations the user is a member of"},
+	},
+}
+
+var Repositories = Table{
+	Name: "repositories",
+	Fields: []Field{
+		{"id", "INTEGER", "REQUIRED", "GitHub repository ID"},
+		{"full_name", "STRING", "REQUIRED", "owner/name"},
+		{"owner", "STRING", "REQUIRED", ""},
+		{"name", "STRING", "REQUIRED", ""},
+		{"html_url", "STRING", "NULLABLE", ""},
+		{"fork", "BOOLEAN", "NULLABLE", ""},
+		{"stargazers", "INTEGER", "NULLABLE", ""},
+		{"forks", "INTEGER", "NULLABLE", ""},
+		{"open_issues", "INTEGER", "NULLABLE", ""},
+		{"created_at", "TIMESTAMP", "NULLABLE", ""},
+	},
+}
+
+var Issues = Table{
+	Name: "issues",
+	Fields: []Field{
+		{"id", "INTEGER", "REQUIRED", "GitHub issue ID"},
+		{"repository_id", "INTEGER", "REQUIRED", ""},
+		{"repository", "STRING", "REQUIRED", "owner/name"},
+		{"number", "INTEGER", "REQUIRED", ""},
+		{"user_id", "INTEGER", "NULLABLE", ""},
+		{"user_login", "STRING", "NULLABLE", ""},
+		{"title", "STRING", "NULLABLE", ""},
+		{"state", "STRING", "NULLABLE", "open or closed"},
+		{"pull_request", "BOOLEAN", "REQUIRED", "Whether the issue is a pull request"},
+		{"labels", "STRING", "REPEATED", ""},
+		{"comments", "INTEGER", "NULLABLE", ""},
+		{"created_at", "TIMESTAMP", "NULLABLE", ""},
+		{"updated_at", "TIMESTAMP", "NULLABLE", ""},
+		{"closed_at", "TIMESTAMP", "NULLABLE", ""},
+		{"html_url", "STRING", "NULLABLE", ""},
+	},
+}
+
+var Comments = Table{
+	Name: "comments",
+	Fields: []Field{
+		{"id", "INTEGER", "REQUIRED", "GitHub comment ID, unique together with kind"},
+		{"kind", "STRING", "REQUIRED", "issue or commit"},
+		{"repository_id", "INTEGER", "REQUIRED", ""},
+		{"repository", "STRING", "REQUIRED", "owner/name"},
+		{"issue_number", "INTEGER", "NULLABLE", "Set for issue comments"},
+		{"commit_id", "STRING", "NULLABLE", "Set for commit comments"},
+		{"user_id", "INTEGER", "NULLABLE", ""},
+		{"user_login", "STRING", "NULLABLE", ""},
+		{"created_at", "TIMESTAMP", "NULLABLE", ""},
+		{"updated_at", "TIMESTAMP", "NULLABLE", ""},
+		{"html_url", "STRING", "NULLABLE", ""},
+	},
+}
+
+var Reviews = Table{
+	Name: "reviews",
+	Fields: []Field{
+		{"id", "INTEGER", "REQUIRED", "GitHub review ID"},
+		{"repository_id", "INTEGER", "REQUIRED", ""},
+		{"repository", "STRING", "REQUIRED", "owner/name"},
+		{"pull_request_number", "INTEGER", "NULLABLE", ""},
+		{"user_id", "INTEGER", "NULLABLE", ""},
+		{"user_login", "STRING", "NULLABLE", ""},
+		{"state", "STRING", "NULLABLE", "APPROVED, CHANGES_REQUESTED, COMMENTED or DISMISSED"},
+		{"submitted_at", "TIMESTAMP", "NULLABLE", ""},
+		{"html_url", "STRING", "NULLABLE", ""},
+	},
+}
+
+// Tables lists every exported table.
+var Tables = []Table{Users, Repositories, Issues, Comments, Reviews}
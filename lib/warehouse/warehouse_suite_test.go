@@ -0,0 +1,13 @@
+package warehouse_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestWarehouse(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Warehouse Suite")
+}